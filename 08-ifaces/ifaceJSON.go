@@ -0,0 +1,225 @@
+/*
+Interfejsi i JSON
+=================
+
+U programu ifaceExt smo dodali tip Freelancer bez ijedne promene u funkciji
+totalExpense. Ali zaposlene smo i dalje pravili u Go kodu, pa za svakog novog
+zaposlenog treba menjati i ponovo prevesti program. Pravi program bi ih čitao
+iz datoteke, na primer JSON dokumenta:
+
+	[
+		{"type": "permanent", "empId": 1, "basicPay": 5000, "pf": 20},
+		{"type": "contract", "empId": 3, "basicPay": 3000},
+		{"type": "freelancer", "empId": 4, "ratePerHour": 70, "totalHours": 120}
+	]
+
+Paket encoding/json ne može da dekodira u interfejs SalaryCalculator, jer ne
+zna koji konkretan tip da napravi. Zato svaki objekat ima polje "type"
+(diskriminator), koje imenuje tip.
+
+Paket "learngo/08-ifaces/poly" čuva registar imena i tipova:
+
+	var salaries poly.Registry[SalaryCalculator]
+
+	poly.Register[SalaryCalculator, Permanent](&salaries, "permanent")
+
+Dekoder zna samo za registar, pa se nova vrsta zaposlenog dodaje jednim
+pozivom Register, bez ikakve promene dekodera, kao što se Freelancer dodao bez
+promene funkcije totalExpense.
+
+Polja tipova Permanent, Contract i Freelancer počinju malim slovom, pa ih
+encoding/json ne vidi. Zato svaki tip ima metodu UnmarshalJSON, koja dekodira
+u pomoćnu strukturu sa izvezenim poljima i JSON imenima. Za to koristi
+poly.Strict, koji kao i registar odbija nepoznata polja.
+*/
+
+package ifaces
+
+import (
+	"fmt"
+	"learngo/08-ifaces/poly"
+	"learngo/11-deferAndError/validation"
+	"os"
+)
+
+var salaries poly.Registry[SalaryCalculator]
+
+func init() {
+	poly.Register[SalaryCalculator, Permanent](&salaries, "permanent")
+	poly.Register[SalaryCalculator, Contract](&salaries, "contract")
+	poly.Register[SalaryCalculator, Freelancer](&salaries, "freelancer")
+}
+
+func (p *Permanent) UnmarshalJSON(data []byte) error {
+	var v struct {
+		EmpID    int `json:"empId"`
+		BasicPay int `json:"basicPay"`
+		PF       int `json:"pf"`
+	}
+	if err := poly.Strict(data, &v); err != nil {
+		return err
+	}
+	*p = Permanent{empId: v.EmpID, basicpay: v.BasicPay, pf: v.PF}
+	return nil
+}
+
+func (c *Contract) UnmarshalJSON(data []byte) error {
+	var v struct {
+		EmpID    int `json:"empId"`
+		BasicPay int `json:"basicPay"`
+	}
+	if err := poly.Strict(data, &v); err != nil {
+		return err
+	}
+	*c = Contract{empId: v.EmpID, basicpay: v.BasicPay}
+	return nil
+}
+
+func (f *Freelancer) UnmarshalJSON(data []byte) error {
+	var v struct {
+		EmpID       int `json:"empId"`
+		RatePerHour int `json:"ratePerHour"`
+		TotalHours  int `json:"totalHours"`
+	}
+	if err := poly.Strict(data, &v); err != nil {
+		return err
+	}
+	*f = Freelancer{empId: v.EmpID, ratePerHour: v.RatePerHour, totalHours: v.TotalHours}
+	return nil
+}
+
+func ifaceJSONDecode() {
+
+	fmt.Println("\n --- ifaceJSONDecode ---")
+
+	doc := []byte(`[
+		{"type": "permanent", "empId": 1, "basicPay": 5000, "pf": 20},
+		{"type": "permanent", "empId": 2, "basicPay": 6000, "pf": 30},
+		{"type": "contract", "empId": 3, "basicPay": 3000},
+		{"type": "freelancer", "empId": 4, "ratePerHour": 70, "totalHours": 120},
+		{"type": "freelancer", "empId": 5, "ratePerHour": 100, "totalHours": 100}
+	]`)
+
+	employees, err := salaries.DecodeList(doc, "")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, e := range employees {
+		fmt.Printf("%T %+v\n", e, e)
+	}
+	totalExpense(employees)
+}
+
+/*
+Program ispisuje:
+
+	>> ifaces.Permanent {empId:1 basicpay:5000 pf:20}
+	>> ifaces.Permanent {empId:2 basicpay:6000 pf:30}
+	>> ifaces.Contract {empId:3 basicpay:3000}
+	>> ifaces.Freelancer {empId:4 ratePerHour:70 totalHours:120}
+	>> ifaces.Freelancer {empId:5 ratePerHour:100 totalHours:100}
+	>> Total Expense Per Month $32450
+
+Isti zaposleni i isti ukupni troškovi kao u programu ifaceExt, ali bez Go
+koda koji ih pravi.
+
+Greške sa JSON putanjama
+------------------------
+Poruka "json: cannot unmarshal string into Go value of type int" ne kaže
+kod kog zaposlenog ni u kom polju je greška. poly prijavljuje sve greške
+odjednom, kao validation.Errors iz lekcije "11-deferAndError/customError.go",
+svaku sa JSON putanjom: "$" je ceo dokument, "[2]" treći element niza, a
+".basicPay" polje objekta. Zaposleni bez grešaka se i dalje vraćaju.
+*/
+
+func ifaceJSONErrors() {
+
+	fmt.Println("\n --- ifaceJSONErrors ---")
+
+	doc := []byte(`[
+		{"type": "permanent", "empId": 1, "basicPay": 5000, "pf": 20},
+		{"type": "intern", "empId": 2, "stipend": 800},
+		{"type": "contract", "empId": "3", "basicPay": 3000, "bonus": 100},
+		{"empId": 4, "ratePerHour": 70, "totalHours": 120},
+		{"type": "freelancer", "empId": 5, "ratePerHour": 100.5, "totalHours": 100},
+		"Pera Perić"
+	]`)
+
+	employees, err := salaries.DecodeList(doc, "$.employees")
+	fmt.Println("decoded", len(employees), "employees")
+	if verr, ok := err.(*validation.Errors); ok {
+		verr.WriteText(os.Stdout)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> decoded 1 employees
+	>> $.employees[1].type [unknown_type]: unknown type "intern", one of contract, freelancer, permanent
+	>> $.employees[2].bonus [unknown_field]: unknown field
+	>> $.employees[2].empId [invalid]: expected int, got string
+	>> $.employees[3].type [missing_type]: missing, one of contract, freelancer, permanent
+	>> $.employees[4].ratePerHour [invalid]: expected int, got number 100.5
+	>> $.employees[5] [not_object]: expected an object, got string
+
+Neispravni "empId" i nepoznato polje "bonus" su u istom objektu, i prijavljena
+su oba, iako encoding/json staje na prvoj grešci.
+
+Nova vrsta zaposlenog
+---------------------
+Kompanija je uvela praksu: praktikant (intern) dobija fiksnu stipendiju. Novi
+tip implementira SalaryCalculator i registruje se pod imenom "intern". Ni
+dekoder, ni totalExpense se ne menjaju. Tip Intern ima izvezena polja, pa mu
+UnmarshalJSON nije potreban.
+*/
+
+type Intern struct {
+	EmpID   int `json:"empId"`
+	Stipend int `json:"stipend"`
+}
+
+func (i Intern) CalculateSalary() int {
+	return i.Stipend
+}
+
+func ifaceJSONPlugin() {
+
+	fmt.Println("\n --- ifaceJSONPlugin ---")
+
+	var staff poly.Registry[SalaryCalculator]
+	poly.Register[SalaryCalculator, Permanent](&staff, "permanent")
+	poly.Register[SalaryCalculator, Intern](&staff, "intern")
+	fmt.Println("types:", staff.Names())
+
+	employees, err := staff.DecodeList([]byte(`[
+		{"type": "permanent", "empId": 1, "basicPay": 5000, "pf": 20},
+		{"type": "intern", "empId": 2, "stipend": 800}
+	]`), "")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	totalExpense(employees)
+}
+
+/*
+Program ispisuje:
+
+	>> types: [intern permanent]
+	>> Total Expense Per Month $5820
+
+Ovde smo napravili novi registar, da ne bismo menjali registar salaries za
+ostale primere. U pravom programu bi se Intern registrovao u funkciji init
+svog paketa, kao Permanent, Contract i Freelancer gore.
+*/
+
+func InterfaceJSONFunc() {
+
+	fmt.Println("\n --- Interfaces and JSON ---")
+
+	ifaceJSONDecode()
+	ifaceJSONErrors()
+	ifaceJSONPlugin()
+}
@@ -0,0 +1,334 @@
+// Package poly decodes JSON objects into interface values. A discriminator
+// field, "type" by default, names the concrete type, and a Registry maps
+// the names to Go types, so new kinds are added by registering them, not by
+// changing the decoder.
+//
+// Problems are reported all at once as *validation.Errors, with the JSON
+// path of each, such as "$.employees[2].basicPay".
+package poly
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learngo/11-deferAndError/validation"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultField is the discriminator field used when Registry.Field is empty.
+const DefaultField = "type"
+
+// Codes of the reported problems.
+const (
+	CodeSyntax       = "syntax"        // the document is not valid JSON
+	CodeNotObject    = "not_object"    // an element is not a JSON object
+	CodeNotList      = "not_list"      // DecodeList got something else than an array
+	CodeMissingType  = "missing_type"  // the discriminator field is missing
+	CodeUnknownType  = "unknown_type"  // the discriminator names no registered type
+	CodeUnknownField = "unknown_field" // the object has a field the type does not have
+	CodeInvalid      = "invalid"       // a field has a value of the wrong type or format
+)
+
+// Registry maps type names to concrete types implementing T. The zero value
+// is empty and ready to use; it is safe for concurrent use.
+type Registry[T any] struct {
+	Field string // discriminator field, default DefaultField
+
+	mu    sync.RWMutex
+	kinds map[string]kind[T]
+}
+
+// kind is a registered type: its decoder and the type V given to Register,
+// which is used to find where in an object a problem is.
+type kind[T any] struct {
+	decode func(data []byte) (T, error)
+	typ    reflect.Type
+}
+
+// Register makes the name decode into a V. V or *V must implement T; the
+// value is used if it does, otherwise the pointer. Register panics if
+// neither does or if name is already registered.
+func Register[T, V any](r *Registry[T], name string) {
+	var decode func(data []byte) (T, error)
+	switch {
+	case implements[T](*new(V)):
+		decode = func(data []byte) (T, error) {
+			v := new(V)
+			err := Strict(data, v)
+			return any(*v).(T), err
+		}
+	case implements[T](new(V)):
+		decode = func(data []byte) (T, error) {
+			v := new(V)
+			err := Strict(data, v)
+			return any(v).(T), err
+		}
+	default:
+		panic(fmt.Sprintf("poly: neither %v nor %v implements %v",
+			reflect.TypeFor[V](), reflect.TypeFor[*V](), reflect.TypeFor[T]()))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.kinds[name]; dup {
+		panic("poly: type " + name + " registered twice")
+	}
+	if r.kinds == nil {
+		r.kinds = make(map[string]kind[T])
+	}
+	r.kinds[name] = kind[T]{decode, reflect.TypeFor[V]()}
+}
+
+func implements[T any](v any) bool {
+	_, ok := v.(T)
+	return ok
+}
+
+// Strict decodes data into v like json.Unmarshal, but rejects fields v does
+// not have. UnmarshalJSON methods of registered types use it to be as
+// strict as the types decoded by the registry directly.
+func Strict(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
+
+// Names returns the registered names, sorted.
+func (r *Registry[T]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.kinds))
+	for name := range r.kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry[T]) field() string {
+	if r.Field == "" {
+		return DefaultField
+	}
+	return r.Field
+}
+
+// root returns the path of the whole document, "$" if path is empty.
+func root(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
+
+// Decode decodes the object in data. path is the JSON path of data in
+// errors, "$" if empty.
+func (r *Registry[T]) Decode(data []byte, path string) (T, error) {
+	var errs validation.Errors
+	v, _ := r.decode(data, root(path), &errs)
+	return v, errs.Err()
+}
+
+// DecodeList decodes the array of objects in data. Elements that fail are
+// left out and their problems reported together; the others are returned
+// even when the error is not nil.
+func (r *Registry[T]) DecodeList(data []byte, path string) ([]T, error) {
+	path = root(path)
+	var errs validation.Errors
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			errs.Addf(path, CodeNotList, "expected an array, got %s", te.Value)
+		} else {
+			errs.Add(path, CodeSyntax, err)
+		}
+		return nil, errs.Err()
+	}
+
+	list := make([]T, 0, len(raw))
+	for i, data := range raw {
+		if v, ok := r.decode(data, validation.Join(path, fmt.Sprintf("[%d]", i)), &errs); ok {
+			list = append(list, v)
+		}
+	}
+	return list, errs.Err()
+}
+
+func (r *Registry[T]) decode(data []byte, path string, errs *validation.Errors) (T, bool) {
+	var zero T
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		var te *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &te):
+			errs.Addf(path, CodeNotObject, "expected an object, got %s", te.Value)
+		case err != nil:
+			errs.Add(path, CodeSyntax, err)
+		default:
+			errs.Addf(path, CodeNotObject, "expected an object, got null")
+		}
+		return zero, false
+	}
+
+	field := r.field()
+	typePath := validation.Join(path, field)
+	rawName, ok := obj[field]
+	if !ok {
+		errs.Addf(typePath, CodeMissingType, "missing, one of %s", strings.Join(r.Names(), ", "))
+		return zero, false
+	}
+	var name string
+	if err := json.Unmarshal(rawName, &name); err != nil {
+		errs.Addf(typePath, CodeInvalid, "expected a string, got %s", rawName)
+		return zero, false
+	}
+	r.mu.RLock()
+	k, ok := r.kinds[name]
+	r.mu.RUnlock()
+	if !ok {
+		errs.Addf(typePath, CodeUnknownType, "unknown type %q, one of %s", name, strings.Join(r.Names(), ", "))
+		return zero, false
+	}
+
+	delete(obj, field)
+	body, _ := json.Marshal(obj)
+	v, err := k.decode(body)
+	if err == nil {
+		return v, true
+	}
+
+	// The decoder stops at the first problem and does not always say
+	// where it is. Decoding one field at a time finds every bad field.
+	n := errs.Len()
+	for _, key := range sortedKeys(obj) {
+		one, _ := json.Marshal(map[string]json.RawMessage{key: obj[key]})
+		if _, err := k.decode(one); err != nil {
+			keyPath := validation.Join(path, key)
+			if f, ok := jsonField(k.typ, key); ok && !unmarshaler(k.typ) {
+				locate(f.Type, obj[key], keyPath, errs)
+			} else {
+				addFieldError(errs, keyPath, err)
+			}
+		}
+	}
+	if errs.Len() == n {
+		errs.Add(path, CodeInvalid, err)
+	}
+	return zero, false
+}
+
+func sortedKeys(obj map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// locate reports the problems of decoding data into a t, at the path of
+// the innermost value that fails: "$.incomes[1].entries[0].hours" rather
+// than "$.incomes[1].entries". It descends into arrays, maps and structs
+// the way encoding/json does, and stops at types with their own
+// UnmarshalJSON, since it cannot know how they read their input.
+func locate(t reflect.Type, data json.RawMessage, path string, errs *validation.Errors) {
+	err := Strict(data, reflect.New(t).Interface())
+	if err == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer && !unmarshaler(t) {
+		t = t.Elem()
+	}
+	if unmarshaler(t) {
+		addFieldError(errs, path, err)
+		return
+	}
+
+	n := errs.Len()
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var list []json.RawMessage
+		if json.Unmarshal(data, &list) != nil {
+			break
+		}
+		for i, elem := range list {
+			locate(t.Elem(), elem, validation.Join(path, fmt.Sprintf("[%d]", i)), errs)
+		}
+	case reflect.Map:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil || obj == nil {
+			break
+		}
+		for _, key := range sortedKeys(obj) {
+			locate(t.Elem(), obj[key], validation.Join(path, key), errs)
+		}
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(data, &obj) != nil || obj == nil {
+			break
+		}
+		for _, key := range sortedKeys(obj) {
+			if f, ok := jsonField(t, key); ok {
+				locate(f.Type, obj[key], validation.Join(path, key), errs)
+			} else {
+				errs.Addf(validation.Join(path, key), CodeUnknownField, "unknown field")
+			}
+		}
+	}
+	if errs.Len() == n {
+		addFieldError(errs, path, err)
+	}
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// unmarshaler reports whether t or *t has an UnmarshalJSON method.
+func unmarshaler(t reflect.Type) bool {
+	return t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType)
+}
+
+// jsonField returns the field of the struct type t that encoding/json
+// decodes the object key into: the one named key, or else the first one
+// whose name matches key ignoring case.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	var fold reflect.StructField
+	found := false
+	for _, f := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() || f.Anonymous && name == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == key {
+			return f, true
+		}
+		if !found && strings.EqualFold(name, key) {
+			fold, found = f, true
+		}
+	}
+	return fold, found
+}
+
+var unknownField = regexp.MustCompile(`^json: unknown field "(.*)"$`)
+
+func addFieldError(errs *validation.Errors, path string, err error) {
+	var te *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &te):
+		errs.Addf(path, CodeInvalid, "expected %s, got %s", te.Type, te.Value)
+	case unknownField.MatchString(err.Error()):
+		errs.Addf(path, CodeUnknownField, "unknown field")
+	default:
+		errs.Add(path, CodeInvalid, err)
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Ograničena konkurentnost
+========================
+
+U programu "conc2WaitGroup" za svaku stavku pokrećemo po jednu gorutinu:
+
+	for i := 0; i < no; i++ {
+		wg.Add(1)
+		go process(i, &wg)
+	}
+
+Za 3 stavke to je sasvim u redu. Ali ako bi "no" bio 100000, a svaka gorutina
+otvarala datoteku ili mrežnu konekciju, vrlo brzo bismo ostali bez deskriptora
+datoteka ili bismo preopteretili server. Isto važi i za "writeConcurently" iz
+poglavlja o datotekama koji odjednom pokreće 100 "produce" gorutina.
+
+Rešenje je da ograničimo broj gorutina koje rade u isto vreme. Za to se
+koristi semafor.
+
+Semafor sa težinom
+------------------
+Semafor je brojač dozvola. Gorutina pre rada uzima dozvolu (Acquire), a kada
+završi vraća je (Release). Ako dozvola nema, gorutina čeka.
+
+Semafor sa težinom dozvoljava da jedan posao uzme više dozvola odjednom. Na
+primer, semafor veličine 10 može istovremeno pustiti 10 malih poslova ili
+jedan veliki posao težine 6 i četiri mala. Gorutine koje čekaju opslužuju se
+po redosledu dolaska (FIFO), tako da veliki posao ne može biti "izgladneo" od
+strane malih.
+
+Paket "learngo/09-conc/bounded" sadrži tip "Weighted":
+
+	sem := bounded.NewWeighted(10)
+	if err := sem.Acquire(ctx, 6); err != nil {
+		return err // ctx je otkazan dok smo čekali
+	}
+	defer sem.Release(6)
+
+Acquire prima context. Ako se context otkaže dok čekamo, Acquire se vraća sa
+greškom i semafor ostaje nepromenjen.
+
+Primer
+------
+*/
+
+package conc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learngo/09-conc/bounded"
+	"sync"
+	"time"
+)
+
+func weightedJob(ctx context.Context, name string, weight int64, sem *bounded.Weighted, wg *sync.WaitGroup) {
+	defer wg.Done()
+	if err := sem.Acquire(ctx, weight); err != nil {
+		fmt.Println(name, "not started:", err)
+		return
+	}
+	defer sem.Release(weight)
+
+	fmt.Printf("%s started, weight %d\n", name, weight)
+	time.Sleep(500 * time.Millisecond)
+	fmt.Printf("%s ended\n", name)
+}
+
+func boundedWeighted() {
+
+	fmt.Println("\n --- boundedWeighted ---")
+
+	ctx := context.Background()
+	sem := bounded.NewWeighted(3)
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go weightedJob(ctx, "small1", 1, sem, &wg)
+	time.Sleep(10 * time.Millisecond)
+	go weightedJob(ctx, "big", 3, sem, &wg)
+	time.Sleep(10 * time.Millisecond)
+	go weightedJob(ctx, "small2", 1, sem, &wg)
+	go weightedJob(ctx, "small3", 1, sem, &wg)
+	wg.Wait()
+}
+
+/*
+Semafor ima veličinu 3. Prvo "small1" uzima jednu dozvolu. Posao "big" traži
+3 dozvole, ali su slobodne samo 2 i on čeka. Iako bi "small2" i "small3" mogli
+da stanu u preostale 2 dozvole, oni moraju da sačekaju iza posla "big" jer se
+čekanje odvija po redu. Program ispisuje:
+
+	>> small1 started, weight 1
+	>> small1 ended
+	>> big started, weight 3
+	>> big ended
+	>> small2 started, weight 1
+	>> small3 started, weight 1
+	>> small3 ended
+	>> small2 ended
+
+ParallelFor
+-----------
+Najčešće nam ne treba semafor direktno, već petlja koja radi paralelno ali sa
+ograničenjem. Funkcija "ParallelFor" poziva fn za svako i iz [0, n), ali
+nikada više od "Limit" poziva u isto vreme:
+
+	err := bounded.ParallelFor(ctx, n, bounded.Options{Limit: 2},
+		func(ctx context.Context, i int) error {
+			...
+		})
+
+Sada možemo "conc2WaitGroup" prepisati tako da se nikada ne izvršavaju više od
+dve "process" gorutine odjednom:
+*/
+
+func processBounded(ctx context.Context, i int) error {
+	fmt.Println("started goroutine ", i)
+	select {
+	case <-time.After(1 * time.Second):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	fmt.Printf("goroutine %d ended\n", i)
+	return nil
+}
+
+func conc2WaitGroupBounded() {
+
+	fmt.Println("\n --- conc2WaitGroupBounded ---")
+
+	no := 5
+	err := bounded.ParallelFor(context.Background(), no, bounded.Options{Limit: 2}, processBounded)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("All go routines finished executing")
+}
+
+/*
+Više nam nisu potrebni ni WaitGroup ni "wg.Done()". ParallelFor sam čeka da
+se sve pokrenute gorutine završe. Izlaz pokazuje da se gorutine pokreću u
+parovima:
+
+	>> started goroutine  1
+	>> started goroutine  0
+	>> goroutine 0 ended
+	>> goroutine 1 ended
+	>> started goroutine  2
+	>> started goroutine  3
+	>> ...
+	>> All go routines finished executing
+
+Greške, panike i otkazivanje
+----------------------------
+Kada neka stavka vrati grešku, postoje dve razumne strategije, koje biramo
+poljem "Mode":
+
+- bounded.FirstError (podrazumevano) - otkazuje se context prosleđen ostalim
+  stavkama, nove stavke se ne pokreću i vraća se prva greška.
+- bounded.AllErrors - izvršavaju se sve stavke i vraćaju se sve greške spojene
+  funkcijom errors.Join, poređane po indeksu stavke.
+
+Svaka greška je omotana u "*bounded.ItemError" koji nosi indeks stavke, pa
+errors.Is i errors.As rade kroz njega.
+
+Ako fn panikuje, panika ne ruši ceo program. Ona se hvata pomoću recover u
+istoj gorutini u kojoj je nastala i pretvara u grešku tipa
+"*bounded.PanicError" koja sadrži vrednost panike i stek.
+
+"ParallelMap" je generička varijanta koja vraća rezultate istim redom kojim su
+zadati ulazi. Pogledajmo kako izgleda zbir cifara iz worker pool-a uz jednu
+grešku i jednu paniku:
+*/
+
+var errNegative = errors.New("negative number")
+
+func digitsChecked(ctx context.Context, number int) (int, error) {
+	if number < 0 {
+		return 0, errNegative
+	}
+	if number == 0 {
+		panic("zero is not allowed")
+	}
+	sum := 0
+	for no := number; no != 0; no /= 10 {
+		sum += no % 10
+	}
+	return sum, nil
+}
+
+func boundedParallelMap() {
+
+	fmt.Println("\n --- boundedParallelMap ---")
+
+	in := []int{234, -5, 871, 0, 99}
+	out, err := bounded.ParallelMap(context.Background(), in, bounded.Options{Limit: 3, Mode: bounded.AllErrors}, digitsChecked)
+	fmt.Println("results:", out)
+	fmt.Println("errors:")
+	fmt.Println(err)
+
+	fmt.Println("is errNegative:", errors.Is(err, errNegative))
+	var pe *bounded.PanicError
+	if errors.As(err, &pe) {
+		fmt.Println("recovered panic:", pe.Value)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> results: [9 0 16 0 18]
+	>> errors:
+	>> item 1: negative number
+	>> item 3: panic: zero is not allowed
+	>> is errNegative: true
+	>> recovered panic: zero is not allowed
+
+Otkazivanje spolja radi preko context-a. U sledećem programu dozvoljavamo
+petlji da radi samo 1.5 sekundu. Stavke koje su već pokrenute dobijaju otkazan
+context, a nove se više ne pokreću:
+*/
+
+func boundedCancel() {
+
+	fmt.Println("\n --- boundedCancel ---")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	err := bounded.ParallelFor(ctx, 10, bounded.Options{Limit: 2, Mode: bounded.AllErrors}, processBounded)
+	fmt.Println("deadline exceeded:", errors.Is(err, context.DeadlineExceeded))
+}
+
+/*
+Pokreću se stavke 0 i 1 i završavaju nakon jedne sekunde. Zatim se pokreću
+stavke 2 i 3, ali nakon pola sekunde ističe rok i one se prekidaju. Stavke od
+4 do 9 se nikada ne pokreću. Poslednji red izlaza je:
+
+	>> deadline exceeded: true
+*/
+
+func BoundedFunc() {
+
+	fmt.Println("\n --- Bounded Func ---")
+
+	boundedWeighted()
+	conc2WaitGroupBounded()
+	boundedParallelMap()
+	boundedCancel()
+}
@@ -0,0 +1,134 @@
+package bounded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// ErrorMode tells ParallelFor what to do when an item fails.
+type ErrorMode int
+
+const (
+	// FirstError cancels the remaining items and returns the first failure.
+	FirstError ErrorMode = iota
+	// AllErrors runs every item and returns all failures joined together.
+	AllErrors
+)
+
+// Options configures ParallelFor and ParallelMap.
+type Options struct {
+	Limit int       // max items running at once, <= 0 means GOMAXPROCS
+	Mode  ErrorMode // FirstError by default
+}
+
+// ItemError is the error of a single item, tagged with its index.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned in place of an item that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// ParallelFor calls fn for every i in [0, n), running at most opt.Limit calls
+// at the same time. A panic in fn is turned into a *PanicError. When ctx is
+// cancelled no new items are started and ctx.Err() is returned if no item
+// failed.
+func ParallelFor(ctx context.Context, n int, opt Options, fn func(ctx context.Context, i int) error) error {
+	limit := opt.Limit
+	if limit <= 0 {
+		limit = runtime.GOMAXPROCS(0)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := NewWeighted(int64(limit))
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []*ItemError
+	)
+
+	for i := 0; i < n; i++ {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			if err := call(ctx, i, fn); err != nil {
+				mu.Lock()
+				errs = append(errs, &ItemError{Index: i, Err: err})
+				mu.Unlock()
+				if opt.Mode == FirstError {
+					cancel()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return parent.Err()
+	}
+	if opt.Mode == FirstError {
+		return errs[0]
+	}
+
+	sort.Slice(errs, func(a, b int) bool { return errs[a].Index < errs[b].Index })
+	all := make([]error, len(errs))
+	for i, e := range errs {
+		all[i] = e
+	}
+	return errors.Join(all...)
+}
+
+// ParallelMap applies fn to every element of in with bounded concurrency and
+// returns the results in input order. Results of failed items are left as
+// zero values.
+func ParallelMap[T, R any](ctx context.Context, in []T, opt Options, fn func(ctx context.Context, v T) (R, error)) ([]R, error) {
+	out := make([]R, len(in))
+	err := ParallelFor(ctx, len(in), opt, func(ctx context.Context, i int) error {
+		r, err := fn(ctx, in[i])
+		if err != nil {
+			return err
+		}
+		out[i] = r
+		return nil
+	})
+	return out, err
+}
+
+func call(ctx context.Context, i int, fn func(ctx context.Context, i int) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx, i)
+}
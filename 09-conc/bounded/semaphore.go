@@ -0,0 +1,123 @@
+package bounded
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTooLarge is returned by Acquire when more weight is requested than the
+// semaphore can ever hold.
+var ErrTooLarge = errors.New("bounded: requested weight exceeds semaphore size")
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // closed when the weight has been granted
+}
+
+// Weighted is a semaphore whose permits have a weight. A caller may take
+// several permits at once, e.g. a large job can take 3 of 10 slots.
+// Waiters are served in FIFO order so a heavy caller is not starved.
+type Weighted struct {
+	size    int64
+	cur     int64
+	mu      sync.Mutex
+	waiters list.List
+}
+
+// NewWeighted returns a semaphore with the given maximum combined weight.
+func NewWeighted(n int64) *Weighted {
+	return &Weighted{size: n}
+}
+
+// Acquire takes weight n, blocking until it is available or ctx is done.
+// On failure it returns ctx.Err() and leaves the semaphore unchanged.
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	done := ctx.Done()
+
+	s.mu.Lock()
+	select {
+	case <-done:
+		s.mu.Unlock()
+		return ctx.Err()
+	default:
+	}
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrTooLarge
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// Granted just as ctx was cancelled; give the weight back.
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// Removing the front waiter may unblock the ones behind it.
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+
+	case <-ready:
+		return nil
+	}
+}
+
+// TryAcquire takes weight n without blocking and reports whether it succeeded.
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	ok := s.size-s.cur >= n && s.waiters.Len() == 0
+	if ok {
+		s.cur += n
+	}
+	s.mu.Unlock()
+	return ok
+}
+
+// Release returns weight n to the semaphore.
+func (s *Weighted) Release(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	if s.cur < 0 {
+		s.mu.Unlock()
+		panic("bounded: released more than held")
+	}
+	s.notifyWaiters()
+	s.mu.Unlock()
+}
+
+// notifyWaiters wakes waiters from the front of the queue while their weight
+// fits. It stops at the first one that does not fit to keep FIFO order.
+func (s *Weighted) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			break
+		}
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			break
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,68 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTimers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	t1 := f.NewTimer(time.Second)
+	t2 := f.NewTimer(3 * time.Second)
+	t3 := f.NewTimer(2 * time.Second)
+	if f.Timers() != 3 {
+		t.Fatalf("Timers = %d, want 3", f.Timers())
+	}
+	if !t3.Stop() || t3.Stop() {
+		t.Error("Stop should report true once")
+	}
+
+	f.Advance(time.Second)
+	select {
+	case at := <-t1.C():
+		if !at.Equal(start.Add(time.Second)) {
+			t.Errorf("t1 fired at %v", at)
+		}
+	default:
+		t.Fatal("t1 did not fire")
+	}
+	select {
+	case <-t2.C():
+		t.Fatal("t2 fired early")
+	default:
+	}
+
+	f.Set(start) // backwards, ignored
+	if !f.Now().Equal(start.Add(time.Second)) {
+		t.Errorf("Now = %v after moving backwards", f.Now())
+	}
+	f.Advance(5 * time.Second)
+	if at := <-t2.C(); !at.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("t2 fired at %v, want its deadline", at)
+	}
+	if f.Timers() != 0 {
+		t.Errorf("Timers = %d, want 0", f.Timers())
+	}
+}
+
+func TestFakeExpiredTimer(t *testing.T) {
+	f := NewFake(time.Now())
+	select {
+	case <-f.NewTimer(0).C():
+	default:
+		t.Error("a timer of 0 should fire at once")
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(time.Now())
+	done := make(chan struct{})
+	go func() {
+		<-f.NewTimer(time.Minute).C()
+		close(done)
+	}()
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	<-done
+}
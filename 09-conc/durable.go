@@ -0,0 +1,209 @@
+/*
+Trajni red poslova
+==================
+
+Worker pool iz "conc2WorkerPool" drži sve poslove u baferovanom kanalu "jobs",
+a rezultate u kanalu "results". Kanali žive samo u memoriji. Ako program padne
+posle 60 obrađenih poslova, izgubljeni su i preostalih 40 poslova i svih 60
+rezultata, pa sve mora iz početka.
+
+Kada su poslovi skupi, želimo da preživimo pad programa. Ideja je stara koliko
+i baze podataka - dnevnik unapred (eng. write-ahead log, WAL):
+
+1. Pre nego što posao uđe u red, zapiše se na kraj datoteke (zapis "enq").
+2. Kada worker završi posao, rezultat se zapiše na kraj iste datoteke (zapis
+   "ack", potvrda).
+3. Nakon restarta, program pročita datoteku od početka. Svaki posao koji ima
+   "enq" ali nema "ack" ponovo se isporučuje workerima. Poslovi koji imaju
+   "ack" se ne rade ponovo, njihov rezultat je već zapisan.
+
+Zapis je "trajan" tek kada je pozvan f.Sync(), koji traži od operativnog
+sistema da podatke zaista upiše na disk, a ne samo u keš.
+
+Datoteka bi rasla zauvek, zato se povremeno sažima (eng. compaction): upiše se
+nova datoteka koja sadrži samo rezultate i nepotvrđene poslove, i atomski se
+preimenuje preko stare.
+
+Paket "learngo/09-conc/walqueue" implementira ovakav red:
+
+	q, err := walqueue.Open(path, walqueue.Options{})
+	q.Enqueue("job-1", data)   // zapisuje "enq"
+	job, err := q.Dequeue(ctx) // uzima posao
+	q.Ack(job.ID, result)      // zapisuje "ack" sa rezultatom
+
+Svaki posao ima ključ. Enqueue ključa koji je već poznat ne radi ništa, pa
+producer posle restarta može jednostavno ponovo dodati svih 100 poslova. Seal
+kaže redu da novih poslova neće biti, posle čega Dequeue na praznom redu vraća
+walqueue.ErrDrained, slično kao što for range završava na zatvorenom kanalu.
+
+Worker pool sa trajnim redom
+----------------------------
+*/
+
+package conc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"learngo/09-conc/walqueue"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func digitsQuick(number int) int {
+	sum := 0
+	for no := number; no != 0; no /= 10 {
+		sum += no % 10
+	}
+	time.Sleep(100 * time.Millisecond)
+	return sum
+}
+
+func durableWorker(q *walqueue.Queue, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		job, err := q.Dequeue(context.Background())
+		if err != nil {
+			return
+		}
+		randomno, _ := strconv.Atoi(string(job.Data))
+		sum := digitsQuick(randomno)
+		if err := q.Ack(job.ID, []byte(strconv.Itoa(sum))); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Job %s, input random no %d , sum of digits %d\n", job.Key, randomno, sum)
+	}
+}
+
+func durableAllocate(q *walqueue.Queue, noOfJobs int) error {
+	for i := 0; i < noOfJobs; i++ {
+		randomno := rand.Intn(999)
+		if _, err := q.Enqueue(fmt.Sprintf("job-%d", i), []byte(strconv.Itoa(randomno))); err != nil {
+			return err
+		}
+	}
+	q.Seal()
+	return nil
+}
+
+// DurableWorkerPool runs the worker pool over the job log at path. It is
+// started as a child process by conc2DurableWorkerPool.
+func DurableWorkerPool(path string) error {
+	q, err := walqueue.Open(path, walqueue.Options{CompactEvery: 25})
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	if err := durableAllocate(q, 100); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go durableWorker(q, &wg)
+	}
+	wg.Wait()
+	return nil
+}
+
+/*
+"durableAllocate" je isti kao "allocate", samo što poslove upisuje u red
+umesto u kanal. "durableWorker" čita poslove dok red ne bude ispražnjen, a
+rezultat potvrđuje pozivom Ack. Poslovi traju po 100 ms, pa 10 workera obradi
+100 poslova za oko jednu sekundu.
+
+Da bismo zaista proverili da red preživljava pad, moramo program ubiti usred
+rada. Zato pokrećemo worker pool kao zaseban proces, istu izvršnu datoteku sa
+komandom "jobqueue-worker", i posle pola sekunde ga ubijamo signalom SIGKILL.
+Proces nema priliku ni da pokrene defer-ove. Zatim ga pokrećemo ponovo i on
+završava samo ono što je preostalo.
+*/
+
+func runDurableChild(exe, path string) (int, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(exe, "jobqueue-worker", path)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	return strings.Count(out.String(), "Job "), err
+}
+
+func conc2DurableWorkerPool() {
+
+	fmt.Println("\n --- conc2DurableWorkerPool ---")
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	path := filepath.Join(os.TempDir(), "learngo-jobs.wal")
+	os.Remove(path)
+	defer os.Remove(path)
+
+	var out bytes.Buffer
+	cmd := exec.Command(exe, "jobqueue-worker", path)
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	time.Sleep(500 * time.Millisecond)
+	cmd.Process.Kill()
+	cmd.Wait()
+	first := strings.Count(out.String(), "Job ")
+
+	q, err := walqueue.Open(path, walqueue.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("killed after %d jobs, results on disk %d, unfinished %d\n", first, len(q.Results()), q.Len())
+	q.Close()
+
+	second, err := runDurableChild(exe, path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	q, err = walqueue.Open(path, walqueue.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer q.Close()
+	fmt.Printf("restarted and finished %d jobs, results on disk %d, unfinished %d\n", second, len(q.Results()), q.Len())
+	r, _ := q.Result("job-42")
+	fmt.Println("job-42 sum of digits:", string(r))
+}
+
+/*
+Program ispisuje nešto slično ovome:
+
+	>> killed after 40 jobs, results on disk 40, unfinished 60
+	>> restarted and finished 60 jobs, results on disk 100, unfinished 0
+	>> job-42 sum of digits: 14
+
+Ukupno je urađeno tačno 100 poslova. Posao se potvrđuje tek kada je rezultat
+sračunat, pa posao koji je bio u toku u trenutku pada (sračunat, ali ne i
+potvrđen) bude urađen ponovo. To se zove isporuka "bar jednom" (eng.
+at-least-once) i zato poslovi treba da budu takvi da ih je bezbedno ponoviti.
+*/
+
+func DurableFunc() {
+
+	fmt.Println("\n --- Durable Func ---")
+
+	conc2DurableWorkerPool()
+}
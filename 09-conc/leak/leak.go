@@ -0,0 +1,362 @@
+package leak
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frame is one line pair of a goroutine stack trace.
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+func (f Frame) String() string {
+	if f.Func == "" {
+		return "?"
+	}
+	return fmt.Sprintf("%s (%s:%d)", f.Func, f.File, f.Line)
+}
+
+// Goroutine is a parsed entry of runtime.Stack(buf, true).
+type Goroutine struct {
+	ID        int
+	State     string // as printed by the runtime, e.g. "chan send"
+	Frames    []Frame
+	CreatedBy Frame
+}
+
+// Blocking states, as returned by Goroutine.Kind.
+const (
+	ChanSend    = "chan send"
+	ChanReceive = "chan receive"
+	Select      = "select"
+	Semaphore   = "semaphore"
+	Sleep       = "sleep"
+	IO          = "io wait"
+	Running     = "running"
+	Other       = "other"
+)
+
+// Kind groups the many runtime wait reasons into a few blocking states.
+func (g Goroutine) Kind() string {
+	s := g.State
+	switch {
+	case strings.HasPrefix(s, "chan send"):
+		return ChanSend
+	case strings.HasPrefix(s, "chan receive"):
+		return ChanReceive
+	case strings.HasPrefix(s, "select"):
+		return Select
+	case strings.HasPrefix(s, "semacquire"), strings.HasPrefix(s, "sync."):
+		return Semaphore
+	case s == "sleep":
+		return Sleep
+	case s == "IO wait":
+		return IO
+	case s == "running", s == "runnable":
+		return Running
+	}
+	return Other
+}
+
+// Responsible returns the function to blame for the goroutine: the user
+// function that started it, or, for goroutines started by Check, the first
+// user function on its stack.
+func (g Goroutine) Responsible() string {
+	if g.CreatedBy.Func != "" && isUser(g.CreatedBy.Func) {
+		return g.CreatedBy.Func
+	}
+	for i := len(g.Frames) - 1; i >= 0; i-- {
+		if isUser(g.Frames[i].Func) {
+			return g.Frames[i].Func
+		}
+	}
+	return g.CreatedBy.Func
+}
+
+// Top returns the innermost user frame, where the goroutine is stuck.
+func (g Goroutine) Top() Frame {
+	for _, f := range g.Frames {
+		if isUser(f.Func) {
+			return f
+		}
+	}
+	if len(g.Frames) > 0 {
+		return g.Frames[0]
+	}
+	return Frame{}
+}
+
+var (
+	mainModule = func() string {
+		if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Path != "" {
+			return bi.Main.Path
+		}
+		return ""
+	}()
+	self = func() string {
+		pc, _, _, _ := runtime.Caller(0)
+		name := runtime.FuncForPC(pc).Name()
+		return name[:strings.LastIndex(name, "/")+1] + "leak."
+	}()
+)
+
+// isUser reports whether fn belongs to the program rather than to the
+// runtime, the standard library or this package.
+func isUser(fn string) bool {
+	if strings.HasPrefix(fn, self) {
+		return false
+	}
+	if strings.HasPrefix(fn, "main.") {
+		return true
+	}
+	if mainModule != "" {
+		return strings.HasPrefix(fn, mainModule+"/") || strings.HasPrefix(fn, mainModule+".")
+	}
+	// Without build info, treat paths whose first element has a dot
+	// (github.com/...) as user code.
+	first, _, _ := strings.Cut(fn, "/")
+	return strings.Contains(first, ".") && strings.Contains(fn, "/")
+}
+
+// Snapshot returns all goroutines that exist right now.
+func Snapshot() []Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return Parse(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Parse parses the output of runtime.Stack(buf, true) or of a crash dump.
+func Parse(dump []byte) []Goroutine {
+	var (
+		gs  []Goroutine
+		cur *Goroutine
+		fn  string
+	)
+	sc := bufio.NewScanner(bytes.NewReader(dump))
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "goroutine "):
+			// goroutine 18 [chan send, 2 minutes]:
+			head := strings.TrimPrefix(line, "goroutine ")
+			idStr, rest, _ := strings.Cut(head, " ")
+			id, _ := strconv.Atoi(idStr)
+			state := strings.TrimSuffix(strings.TrimPrefix(rest, "["), "]:")
+			state, _, _ = strings.Cut(state, ",")
+			gs = append(gs, Goroutine{ID: id, State: state})
+			cur = &gs[len(gs)-1]
+			fn = ""
+
+		case cur == nil || line == "":
+			fn = ""
+
+		case strings.HasPrefix(line, "\t"):
+			// 	/path/file.go:22 +0x1d
+			file, lineNo := parseLocation(strings.TrimSpace(line))
+			if strings.HasPrefix(fn, "created by ") {
+				name := strings.TrimPrefix(fn, "created by ")
+				name, _, _ = strings.Cut(name, " in goroutine")
+				cur.CreatedBy = Frame{Func: name, File: file, Line: lineNo}
+			} else if fn != "" {
+				cur.Frames = append(cur.Frames, Frame{Func: fn, File: file, Line: lineNo})
+			}
+			fn = ""
+
+		default:
+			// main.server1(0xc000...) or created by main.main in goroutine 1
+			fn = line
+			if !strings.HasPrefix(fn, "created by ") {
+				if i := strings.LastIndex(fn, "("); i > 0 {
+					fn = fn[:i]
+				}
+			}
+		}
+	}
+	return gs
+}
+
+func parseLocation(s string) (string, int) {
+	s, _, _ = strings.Cut(s, " +")
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return s, 0
+	}
+	n, _ := strconv.Atoi(s[i+1:])
+	return s[:i], n
+}
+
+// Diff returns the goroutines in after that are not in before.
+func Diff(before, after []Goroutine) []Goroutine {
+	seen := make(map[int]bool, len(before))
+	for _, g := range before {
+		seen[g.ID] = true
+	}
+	var out []Goroutine
+	for _, g := range after {
+		if !seen[g.ID] {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Group is a set of leaked goroutines with the same creation site and
+// blocking state.
+type Group struct {
+	Responsible string
+	Site        Frame // where the goroutines were started
+	Kind        string
+	Where       Frame // where the first of them is blocked
+	Count       int
+}
+
+// Report is the result of Check.
+type Report struct {
+	Name     string
+	TimedOut bool // fn did not return in time, probably a deadlock
+	Panic    any
+	Leaked   []Goroutine
+	Groups   []Group
+}
+
+// Check runs fn in its own goroutine, waits at most timeout for it to
+// return and reports every goroutine that fn left behind. If fn does not
+// return in time, its own goroutine is reported as well, which is how a
+// deadlock shows up: with the main goroutine alive the runtime cannot
+// detect it, so the program would otherwise hang.
+func Check(name string, timeout time.Duration, fn func()) Report {
+	before := Snapshot()
+
+	r := Report{Name: name}
+	done := make(chan any, 1)
+	go func() {
+		defer func() { done <- recover() }()
+		fn()
+	}()
+
+	t := time.NewTimer(timeout)
+	select {
+	case r.Panic = <-done:
+		t.Stop()
+	case <-t.C:
+		r.TimedOut = true
+	}
+
+	r.Leaked = settle(before)
+	r.Groups = group(r.Leaked)
+	return r
+}
+
+// settle gives goroutines that are about to exit a moment to do so, then
+// returns the ones that are still around.
+func settle(before []Goroutine) []Goroutine {
+	var leaked []Goroutine
+	for i := 0; i < 10; i++ {
+		leaked = Diff(before, Snapshot())
+		if len(leaked) == 0 {
+			break
+		}
+		runtime.Gosched()
+		time.Sleep(10 * time.Millisecond)
+	}
+	return leaked
+}
+
+func group(gs []Goroutine) []Group {
+	type key struct {
+		site Frame
+		kind string
+	}
+	idx := make(map[key]int)
+	var groups []Group
+	for _, g := range gs {
+		k := key{g.CreatedBy, g.Kind()}
+		i, ok := idx[k]
+		if !ok {
+			i = len(groups)
+			idx[k] = i
+			groups = append(groups, Group{
+				Responsible: g.Responsible(),
+				Site:        g.CreatedBy,
+				Kind:        g.Kind(),
+				Where:       g.Top(),
+			})
+		}
+		groups[i].Count++
+	}
+	sort.Slice(groups, func(a, b int) bool {
+		if groups[a].Responsible != groups[b].Responsible {
+			return groups[a].Responsible < groups[b].Responsible
+		}
+		return groups[a].Kind < groups[b].Kind
+	})
+	return groups
+}
+
+// OK reports whether fn returned in time without panicking or leaking.
+func (r Report) OK() bool {
+	return !r.TimedOut && r.Panic == nil && len(r.Leaked) == 0
+}
+
+// Print writes a human readable report to w.
+func (r Report) Print(w io.Writer) {
+	switch {
+	case r.OK():
+		fmt.Fprintf(w, "%s: no leaked goroutines\n", r.Name)
+		return
+	case r.TimedOut:
+		fmt.Fprintf(w, "%s: did not return, probably deadlocked\n", r.Name)
+	case r.Panic != nil:
+		fmt.Fprintf(w, "%s: panicked: %v\n", r.Name, r.Panic)
+	}
+	if len(r.Leaked) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s: %d goroutine(s) left behind\n", r.Name, len(r.Leaked))
+	for _, g := range r.Groups {
+		fmt.Fprintf(w, "  %d x [%s] blamed on %s\n", g.Count, g.Kind, short(g.Responsible))
+		fmt.Fprintf(w, "      blocked in %s\n", shortFrame(g.Where))
+		if isUser(g.Site.Func) {
+			fmt.Fprintf(w, "      started at %s\n", shortFrame(g.Site))
+		}
+	}
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	r.Print(&b)
+	return b.String()
+}
+
+// short strips the import path from a function name, so
+// learngo/09-conc.server1 becomes 09-conc.server1.
+func short(fn string) string {
+	return fn[strings.LastIndex(fn, "/")+1:]
+}
+
+func shortFrame(f Frame) string {
+	file := f.File
+	if i := strings.LastIndex(file, "/"); i >= 0 {
+		if j := strings.LastIndex(file[:i], "/"); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	return fmt.Sprintf("%s %s:%d", short(f.Func), file, f.Line)
+}
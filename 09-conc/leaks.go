@@ -0,0 +1,189 @@
+/*
+Curenje gorutina i zastoji
+==========================
+
+Nekoliko programa iz ovog poglavlja namerno pravi grešku:
+
+- "channelPanic" šalje na kanal koji niko ne čita,
+- "selDeadlock" čeka u select-u na kanal u koji niko ne piše,
+- "selExample" vraća rezultat servera koji je brži, a gorutina "server1"
+  ostaje da spava još 3 sekunde i zatim zauvek blokira pokušavajući da pošalje
+  na kanal koji više niko ne čita.
+
+U prva dva slučaja, kada se pokrenu iz main-a, vidimo samo "fatal error: all
+goroutines are asleep - deadlock!". Ali runtime prijavljuje zastoj samo kada
+su baš sve gorutine blokirane. U pravom programu, gde na primer HTTP server
+radi u pozadini, zastoj se ne prijavljuje - program jednostavno stoji. U
+trećem slučaju ne vidimo ništa, a gorutina "server1" ostaje zauvek u memoriji.
+To se zove curenje gorutina (eng. goroutine leak). Svaka takva gorutina drži
+bar 2KB steka i sve na šta pokazuje.
+
+Kako da ih vidimo? Funkcija runtime.Stack(buf, true) upisuje u buf stek svih
+gorutina, u istom obliku koji vidimo kada program padne:
+
+	goroutine 18 [chan send]:
+	learngo/09-conc.server1(0xc000022120)
+		/home/radosav/go/src/learngo/09-conc/select.go:22 +0x45
+	created by learngo/09-conc.selExample in goroutine 1
+		/home/radosav/go/src/learngo/09-conc/select.go:35 +0x9d
+
+Iz ovoga znamo sve što nam treba: u kom stanju je gorutina (chan send), gde
+je zaglavljena (select.go:22) i koja funkcija ju je pokrenula (selExample).
+
+Paket "learngo/09-conc/leak" ima funkciju Check koja:
+
+1. napravi snimak svih gorutina pre pokretanja lekcije,
+2. pokrene lekciju u posebnoj gorutini i čeka da se završi, najduže zadato
+   vreme,
+3. napravi snimak posle, i prijavi sve gorutine koje nisu postojale pre.
+
+Gorutine se grupišu po mestu nastanka i stanju blokiranja (chan send, chan
+receive, select, semaphore za mutekse i WaitGroup, sleep), a za svaku grupu se
+navodi funkcija lekcije koja je odgovorna.
+
+Ako se lekcija ne završi na vreme, to je najverovatnije zastoj. Pošto glavna
+gorutina i dalje radi (čeka tajmer), runtime neće srušiti program, a izveštaj
+pokazuje gde je lekcija zaglavljena.
+*/
+
+package conc
+
+import (
+	"fmt"
+	"learngo/09-conc/leak"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+U lekcijama conc.go i select.go funkcije channelPanic i selDeadlock su u
+komentaru, jer bi srušile ceo program. Ovde ih ponavljamo, iste kao tamo:
+*/
+
+func channelPanic() {
+	ch := make(chan int)
+	ch <- 5
+}
+
+func selDeadlock() {
+	ch := make(chan string)
+	select {
+	case <-ch:
+	}
+}
+
+func leakDeadlocks() {
+
+	fmt.Println("\n --- leakDeadlocks ---")
+
+	leak.Check("channelPanic", 500*time.Millisecond, channelPanic).Print(os.Stdout)
+	leak.Check("selDeadlock", 500*time.Millisecond, selDeadlock).Print(os.Stdout)
+}
+
+/*
+Program ispisuje:
+
+	>> channelPanic: did not return, probably deadlocked
+	>> channelPanic: 1 goroutine(s) left behind
+	>>   1 x [chan send] blamed on 09-conc.channelPanic
+	>>       blocked in 09-conc.channelPanic 09-conc/leaks.go:66
+	>> selDeadlock: did not return, probably deadlocked
+	>> selDeadlock: 1 goroutine(s) left behind
+	>>   1 x [chan receive] blamed on 09-conc.selDeadlock
+	>>       blocked in 09-conc.selDeadlock 09-conc/leaks.go:72
+
+Umesto pada celog programa dobili smo tačno mesto zastoja. Primetite da je
+stanje gorutine iz "selDeadlock" "chan receive", a ne "select". Kompajler
+select sa samo jednim slučajem prevodi u obično čitanje iz kanala. Napomena: te dve
+gorutine su i dalje blokirane, Go nema način da spolja ubije gorutinu.
+
+Curenje u selExample
+--------------------
+*/
+
+func leakSelExample() {
+
+	fmt.Println("\n --- leakSelExample ---")
+
+	leak.Check("selExample", 5*time.Second, selExample).Print(os.Stdout)
+}
+
+/*
+"selExample" se vraća posle 3 sekunde, ali izveštaj pokazuje gorutinu koja je
+ostala za njim:
+
+	>> from server2
+	>> selExample: 1 goroutine(s) left behind
+	>>   1 x [sleep] blamed on 09-conc.selExample
+	>>       blocked in 09-conc.server1 09-conc/select.go:21
+	>>       started at 09-conc.selExample 09-conc/select.go:35
+
+Gorutina "server1" još spava. Kada se probudi, blokiraće na slanju, jer
+"output1" niko ne čita. Popravka je jednostavna - kanal treba da ima bafer
+veličine 1, pa slanje uspe i gorutina se završi, čak i ako niko ne pročita
+rezultat:
+
+	output1 := make(chan string, 1)
+
+Grupisanje
+----------
+Kada lekcija ostavi više istih gorutina, one se prijavljuju kao jedna grupa.
+U sledećem programu 5 gorutina čeka na mutex koji niko ne otključava, a 3 na
+kanal koji niko ne zatvara:
+*/
+
+func leakMany() {
+	var m sync.Mutex
+	m.Lock()
+	ch := make(chan int)
+	for i := 0; i < 5; i++ {
+		go func() {
+			m.Lock()
+			m.Unlock()
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		go func() {
+			for range ch {
+			}
+		}()
+	}
+}
+
+func leakGroups() {
+
+	fmt.Println("\n --- leakGroups ---")
+
+	leak.Check("leakMany", time.Second, leakMany).Print(os.Stdout)
+	leak.Check("conc2WaitGroupBounded", 5*time.Second, conc2WaitGroupBounded).Print(os.Stdout)
+}
+
+/*
+Program ispisuje:
+
+	>> leakMany: 8 goroutine(s) left behind
+	>>   3 x [chan receive] blamed on 09-conc.leakMany
+	>>       blocked in 09-conc.leakMany.func2 09-conc/leaks.go:148
+	>>       started at 09-conc.leakMany 09-conc/leaks.go:147
+	>>   5 x [semaphore] blamed on 09-conc.leakMany
+	>>       blocked in 09-conc.leakMany.func1 09-conc/leaks.go:142
+	>>       started at 09-conc.leakMany 09-conc/leaks.go:141
+	>> ...
+	>> conc2WaitGroupBounded: no leaked goroutines
+
+Anonimne funkcije runtime imenuje kao funkcija.func1, funkcija.func2 i tako
+redom. Gorutina koja čeka na mutex je u stanju "sync.Mutex.Lock", a starije
+verzije Go-a su pisale "semacquire". Obe varijante se prijavljuju kao
+"semaphore". Poslednji red potvrđuje da "ParallelFor" iz lekcije o ograničenoj
+konkurentnosti iza sebe ne ostavlja ništa.
+*/
+
+func LeakFunc() {
+
+	fmt.Println("\n --- Leak Func ---")
+
+	leakDeadlocks()
+	leakSelExample()
+	leakGroups()
+}
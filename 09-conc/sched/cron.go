@@ -0,0 +1,292 @@
+package sched
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a task runs next.
+type Schedule interface {
+	// Next returns the first activation strictly after t, or the zero time
+	// if there is none.
+	Next(t time.Time) time.Time
+}
+
+type every struct {
+	d time.Duration
+}
+
+// Every returns a schedule that fires every d, counted from the moment the
+// previous activation was due.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("sched: non-positive interval")
+	}
+	return every{d}
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(e.d)
+}
+
+// Cron is a parsed cron expression. Each field is a bit set of allowed values.
+type Cron struct {
+	second, minute, hour, dom, month, dow uint64
+
+	domStar, dowStar bool
+	expr             string
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = field{"second", 0, 59, nil}
+	minuteField = field{"minute", 0, 59, nil}
+	hourField   = field{"hour", 0, 23, nil}
+	domField    = field{"day of month", 1, 31, nil}
+	monthField  = field{"month", 1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{"day of week", 0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression with 5 fields
+//
+//	minute hour day-of-month month day-of-week
+//
+// or 6 fields with a leading seconds field. Fields accept "*", numbers,
+// ranges "a-b", lists "a,b", steps "*/n" and "a-b/n", and three letter month
+// and day names. The macros @hourly, @daily, @weekly, @monthly, @yearly and
+// "@every <duration>" are also accepted. As in classic cron, when both day
+// fields are restricted a day matches if either of them does.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("sched: %q: %w", expr, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("sched: %q: interval must be positive", expr)
+		}
+		return Every(d), nil
+	}
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		c, err := parseFields(m)
+		if err != nil {
+			return nil, err
+		}
+		c.expr = expr
+		return c, nil
+	}
+	return parseFields(expr)
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(expr string) Schedule {
+	s, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseFields(expr string) (*Cron, error) {
+	f := strings.Fields(expr)
+	switch len(f) {
+	case 5:
+		f = append([]string{"0"}, f...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("sched: %q: expected 5 or 6 fields, got %d", expr, len(f))
+	}
+
+	c := &Cron{expr: expr, domStar: f[3] == "*" || f[3] == "?", dowStar: f[5] == "*" || f[5] == "?"}
+	var err error
+	set := []struct {
+		dst *uint64
+		fld field
+	}{
+		{&c.second, secondField},
+		{&c.minute, minuteField},
+		{&c.hour, hourField},
+		{&c.dom, domField},
+		{&c.month, monthField},
+		{&c.dow, dowField},
+	}
+	for i, s := range set {
+		if *s.dst, err = parseField(f[i], s.fld); err != nil {
+			return nil, fmt.Errorf("sched: %q: %w", expr, err)
+		}
+	}
+	// 7 is another name for Sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, step := f.min, f.max, 1
+
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q in %s field", stepStr, f.name)
+			}
+			step = n
+		}
+
+		if rng != "*" && rng != "?" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("bad range %q in %s field", rng, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q in %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %d out of range %d-%d", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *Cron) String() string {
+	return c.expr
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first second after t that matches the expression, in
+// t's location. It gives up and returns the zero time after five years,
+// which only happens for expressions such as "0 0 30 2 *".
+//
+// Around daylight saving time changes Next works like classic cron: a time
+// that does not exist because the clock jumps forward is skipped, and a time
+// that happens twice because the clock falls back matches only the first
+// time, unless the hour field is "*".
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	limit := t.AddDate(5, 0, 0)
+
+	// Every step moves t forward in absolute time. time.Date would move a
+	// wall clock time inside a gap backwards, and t would never advance.
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = startOfDay(t, t.Year(), t.Month()+1, 1, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = startOfDay(t, t.Year(), t.Month(), t.Day()+1, loc)
+			continue
+		}
+		if !has(c.hour, t.Hour()) {
+			t = t.Truncate(time.Minute)
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if !has(c.minute, t.Minute()) {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if !has(c.second, t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+		if c.hour != allHours && repeated(t) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+const allHours = 1<<24 - 1
+
+// startOfDay returns the first instant of the given day in loc, which is
+// later than midnight when midnight falls in a daylight saving gap. The
+// result is never before t.
+func startOfDay(t time.Time, year int, month time.Month, day int, loc *time.Location) time.Time {
+	noon := time.Date(year, month, day, 12, 0, 0, 0, loc)
+	d := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	for d.Day() != noon.Day() {
+		d = d.Add(time.Hour)
+	}
+	if !d.After(t) {
+		return t.Add(time.Second)
+	}
+	return d
+}
+
+// repeated reports whether the wall clock time of t already happened
+// earlier, because the clock was set back.
+func repeated(t time.Time) bool {
+	_, before := t.Add(-24 * time.Hour).Zone()
+	_, now := t.Zone()
+	if before <= now {
+		return false
+	}
+	earlier := t.Add(-time.Duration(before-now) * time.Second)
+	return earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute() && earlier.Day() == t.Day()
+}
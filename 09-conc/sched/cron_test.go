@@ -0,0 +1,117 @@
+package sched
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("time zone data not available:", err)
+	}
+	return loc
+}
+
+func TestCronNext(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		expr string
+		zone string // empty for UTC
+		from string
+		want []string // successive activations, in RFC 3339
+	}{
+		{"*/15 * * * *", "", "2024-01-01T10:07:30Z", []string{
+			"2024-01-01T10:15:00Z", "2024-01-01T10:30:00Z", "2024-01-01T10:45:00Z", "2024-01-01T11:00:00Z"}},
+		{"*/20 * * * * *", "", "2024-01-01T10:00:00Z", []string{
+			"2024-01-01T10:00:20Z", "2024-01-01T10:00:40Z", "2024-01-01T10:01:00Z"}},
+		{"0 9 * * mon-fri", "", "2024-03-08T10:00:00Z", []string{
+			"2024-03-11T09:00:00Z", "2024-03-12T09:00:00Z"}},
+		{"0 0 29 2 *", "", "2024-03-01T00:00:00Z", []string{"2028-02-29T00:00:00Z"}},
+		// Either restricted day field matches.
+		{"0 0 13 * 5", "", "2024-09-01T00:00:00Z", []string{
+			"2024-09-06T00:00:00Z", "2024-09-13T00:00:00Z", "2024-09-20T00:00:00Z"}},
+		{"@monthly", "", "2024-01-31T12:00:00Z", []string{"2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"}},
+
+		// 2:30 does not exist on 2024-03-10 in New York.
+		{"30 2 * * *", "America/New_York", "2024-03-09T12:00:00-05:00", []string{
+			"2024-03-11T02:30:00-04:00", "2024-03-12T02:30:00-04:00"}},
+		{"0 * * * *", "America/New_York", "2024-03-10T00:30:00-05:00", []string{
+			"2024-03-10T01:00:00-05:00", "2024-03-10T03:00:00-04:00", "2024-03-10T04:00:00-04:00"}},
+		// 1:30 happens twice on 2024-11-03, a fixed time runs once.
+		{"30 1 * * *", "America/New_York", "2024-11-02T12:00:00-04:00", []string{
+			"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"}},
+		// With "*" in the hour field the repeated hour runs again.
+		{"30 * * * *", "America/New_York", "2024-11-03T00:45:00-04:00", []string{
+			"2024-11-03T01:30:00-04:00", "2024-11-03T01:30:00-05:00", "2024-11-03T02:30:00-05:00"}},
+		// Midnight does not exist on 2018-11-04 in Sao Paulo, the day
+		// starts at 1:00.
+		{"0 0 * * *", "America/Sao_Paulo", "2018-11-03T12:00:00-03:00", []string{
+			"2018-11-05T00:00:00-02:00", "2018-11-06T00:00:00-02:00"}},
+		{"0 * 4 11 *", "America/Sao_Paulo", "2018-11-03T22:30:00-03:00", []string{
+			"2018-11-04T01:00:00-02:00", "2018-11-04T02:00:00-02:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" "+tt.zone, func(t *testing.T) {
+			loc := utc
+			if tt.zone != "" {
+				loc = mustLoad(t, tt.zone)
+			}
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, err := time.Parse(time.RFC3339, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := from.In(loc)
+			for _, w := range tt.want {
+				want, err := time.Parse(time.RFC3339, w)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := s.Next(at)
+				if !got.Equal(want) {
+					t.Fatalf("Next(%v) = %v, want %v", at, got, want.In(loc))
+				}
+				at = got
+			}
+		})
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	s := MustParse("0 0 30 2 *")
+	if got := s.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %v, want the zero time", got)
+	}
+}
+
+// TestCronNextAlwaysAdvances walks every hour of a year in zones with
+// daylight saving time and checks that Next moves forward and terminates.
+func TestCronNextAlwaysAdvances(t *testing.T) {
+	exprs := []string{"30 2 * * *", "0 0 * * *", "30 1 * * *", "0 * * * *", "15 3 * * 0"}
+	for _, zone := range []string{"America/New_York", "America/Sao_Paulo", "Australia/Lord_Howe", "Europe/Belgrade"} {
+		loc := mustLoad(t, zone)
+		for _, expr := range exprs {
+			s := MustParse(expr)
+			start := time.Date(2018, 1, 1, 0, 0, 0, 0, loc)
+			for at := start; at.Before(start.AddDate(1, 0, 0)); at = at.Add(time.Hour) {
+				next := s.Next(at)
+				if !next.After(at) || next.Sub(at) > 8*24*time.Hour {
+					t.Fatalf("%s in %s: Next(%v) = %v", expr, zone, at, next)
+				}
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "@every -1s", "@every x"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package sched
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learngo/09-conc/clock"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Overlap tells what happens when a task is due while its previous run is
+// still going.
+type Overlap int
+
+const (
+	// Skip drops the new run.
+	Skip Overlap = iota
+	// Queue runs it right after the current run finishes. Any number of runs
+	// can be queued.
+	Queue
+	// Allow starts it at once, next to the run in progress.
+	Allow
+)
+
+var (
+	// ErrDuplicate is returned by Add for a task name that is already used.
+	ErrDuplicate = errors.New("sched: duplicate task name")
+	// ErrStopped is returned by Add and Start after Stop.
+	ErrStopped = errors.New("sched: scheduler stopped")
+)
+
+// Task is a function to run on a schedule.
+type Task struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+	Overlap  Overlap
+	// Jitter delays every activation by a random amount in [0, Jitter), so
+	// that many tasks due at the same second do not start together.
+	Jitter time.Duration
+}
+
+// Options configures a Scheduler.
+type Options struct {
+	Clock clock.Clock // clock.Real() by default
+
+	// OnError is called when a run returns an error or panics.
+	OnError func(task string, err error)
+	// OnSkip is called when a run is dropped because of the Skip policy.
+	OnSkip func(task string, due time.Time)
+}
+
+// Scheduler runs tasks on their schedules until it is stopped.
+type Scheduler struct {
+	opt    Options
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	tasks   map[string]*entry
+	started bool
+	stopped bool
+	stop    chan struct{}
+
+	loops sync.WaitGroup // one per task
+	runs  sync.WaitGroup // one per running task function
+}
+
+type entry struct {
+	Task
+	running int
+	queued  int
+}
+
+// New returns a scheduler that is not started yet.
+func New(opt Options) *Scheduler {
+	if opt.Clock == nil {
+		opt.Clock = clock.Real()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		opt:    opt,
+		ctx:    ctx,
+		cancel: cancel,
+		tasks:  make(map[string]*entry),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Add registers a task. Tasks added after Start are scheduled at once.
+func (s *Scheduler) Add(t Task) error {
+	if t.Schedule == nil || t.Run == nil {
+		return fmt.Errorf("sched: task %q needs a schedule and a function", t.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, ok := s.tasks[t.Name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicate, t.Name)
+	}
+	e := &entry{Task: t}
+	s.tasks[t.Name] = e
+	if s.started {
+		s.loops.Add(1)
+		go s.loop(e, s.opt.Clock.Now())
+	}
+	return nil
+}
+
+// Start begins scheduling all registered tasks.
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if s.started {
+		return nil
+	}
+	s.started = true
+	now := s.opt.Clock.Now()
+	for _, e := range s.tasks {
+		s.loops.Add(1)
+		go s.loop(e, now)
+	}
+	return nil
+}
+
+// Stop stops scheduling new runs, drops queued runs and waits for the running
+// ones to finish. If ctx is done first, the context passed to the running
+// tasks is cancelled and ctx.Err() is returned without waiting any longer.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+		for _, e := range s.tasks {
+			e.queued = 0
+		}
+	}
+	s.mu.Unlock()
+
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(e *entry, from time.Time) {
+	defer s.loops.Done()
+
+	for {
+		next := e.Schedule.Next(from)
+		if next.IsZero() {
+			return
+		}
+
+		wait := next.Sub(s.opt.Clock.Now())
+		if e.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(e.Jitter)))
+		}
+		t := s.opt.Clock.NewTimer(wait)
+		select {
+		case <-t.C():
+			if !s.fire(e) && s.opt.OnSkip != nil {
+				s.opt.OnSkip(e.Name, next)
+			}
+		case <-s.stop:
+			t.Stop()
+			return
+		}
+
+		// Count the next activation from the one that just fired, so that
+		// the schedule does not drift. If we are late, skip the missed ones.
+		from = next
+		if now := s.opt.Clock.Now(); now.After(from) && e.Schedule.Next(from).Before(now) {
+			from = now
+		}
+	}
+}
+
+// fire starts or queues a run of e. It returns false if the run was skipped.
+func (s *Scheduler) fire(e *entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return true
+	}
+
+	if e.running > 0 {
+		switch e.Overlap {
+		case Skip:
+			return false
+		case Queue:
+			e.queued++
+			return true
+		}
+	}
+
+	e.running++
+	s.runs.Add(1)
+	go s.run(e)
+	return true
+}
+
+func (s *Scheduler) run(e *entry) {
+	defer s.runs.Done()
+	for {
+		if err := s.call(e); err != nil && s.opt.OnError != nil {
+			s.opt.OnError(e.Name, err)
+		}
+
+		s.mu.Lock()
+		if e.queued > 0 && !s.stopped {
+			e.queued--
+			s.mu.Unlock()
+			continue
+		}
+		e.running--
+		s.mu.Unlock()
+		return
+	}
+}
+
+func (s *Scheduler) call(e *entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("sched: task %q panicked: %v", e.Name, r)
+		}
+	}()
+	return e.Run(s.ctx)
+}
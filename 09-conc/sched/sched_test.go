@@ -0,0 +1,150 @@
+package sched
+
+import (
+	"context"
+	"errors"
+	"learngo/09-conc/clock"
+	"sync"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+
+// tick waits until the scheduler armed its timers and moves the clock by d.
+func tick(f *clock.Fake, timers int, d time.Duration) {
+	f.BlockUntil(timers)
+	f.Advance(d)
+}
+
+func TestSchedulerRunsOnSchedule(t *testing.T) {
+	f := clock.NewFake(start)
+	s := New(Options{Clock: f})
+	runs := make(chan time.Time, 10)
+	s.Add(Task{Name: "minute", Schedule: MustParse("* * * * *"), Run: func(ctx context.Context) error {
+		runs <- f.Now()
+		return nil
+	}})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	for i := 1; i <= 3; i++ {
+		tick(f, 1, time.Minute)
+		if got, want := <-runs, start.Add(time.Duration(i)*time.Minute); !got.Equal(want) {
+			t.Fatalf("run %d at %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	tests := []struct {
+		overlap          Overlap
+		runs, skips      int
+		concurrentAtOnce int
+	}{
+		{Skip, 1, 2, 1},
+		{Queue, 3, 0, 1},
+		{Allow, 3, 0, 3},
+	}
+	for _, tt := range tests {
+		f := clock.NewFake(start)
+		var mu sync.Mutex
+		skips, running, most := 0, 0, 0
+		release := make(chan struct{})
+		started := make(chan struct{}, 10)
+		s := New(Options{Clock: f, OnSkip: func(string, time.Time) {
+			mu.Lock()
+			skips++
+			mu.Unlock()
+		}})
+		s.Add(Task{Name: "slow", Schedule: Every(time.Second), Overlap: tt.overlap, Run: func(ctx context.Context) error {
+			mu.Lock()
+			running++
+			most = max(most, running)
+			mu.Unlock()
+			started <- struct{}{}
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}})
+		s.Start()
+
+		tick(f, 1, time.Second)
+		<-started
+		tick(f, 1, time.Second)
+		tick(f, 1, time.Second)
+		f.BlockUntil(1) // the third activation has been handled
+		for range tt.concurrentAtOnce - 1 {
+			<-started
+		}
+		close(release)
+		for range tt.runs - tt.concurrentAtOnce {
+			<-started
+		}
+		s.Stop(context.Background())
+
+		if len(started) != 0 {
+			t.Errorf("%v: more than %d runs", tt.overlap, tt.runs)
+		}
+		if skips != tt.skips || most != tt.concurrentAtOnce {
+			t.Errorf("%v: skips %d, at once %d; want %d, %d", tt.overlap, skips, most, tt.skips, tt.concurrentAtOnce)
+		}
+	}
+}
+
+func TestSchedulerErrorsAndPanics(t *testing.T) {
+	f := clock.NewFake(start)
+	errs := make(chan error, 2)
+	s := New(Options{Clock: f, OnError: func(task string, err error) { errs <- err }})
+	s.Add(Task{Name: "fails", Schedule: Every(time.Second), Run: func(ctx context.Context) error {
+		return errors.New("boom")
+	}})
+	s.Add(Task{Name: "panics", Schedule: Every(time.Second), Run: func(ctx context.Context) error {
+		panic("oops")
+	}})
+	s.Start()
+	defer s.Stop(context.Background())
+
+	tick(f, 2, time.Second)
+	got := map[string]bool{(<-errs).Error(): true, (<-errs).Error(): true}
+	if !got["boom"] || !got[`sched: task "panics" panicked: oops`] {
+		t.Errorf("errors = %v", got)
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	f := clock.NewFake(start)
+	s := New(Options{Clock: f})
+	ctxDone := make(chan struct{})
+	s.Add(Task{Name: "long", Schedule: Every(time.Second), Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		close(ctxDone)
+		return ctx.Err()
+	}})
+	s.Start()
+	tick(f, 1, time.Second)
+	f.BlockUntil(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stop = %v, want context.Canceled", err)
+	}
+	<-ctxDone
+	if err := s.Add(Task{Name: "late", Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Add after Stop = %v, want ErrStopped", err)
+	}
+}
+
+func TestSchedulerDuplicate(t *testing.T) {
+	s := New(Options{Clock: clock.NewFake(start)})
+	task := Task{Name: "a", Schedule: Every(time.Second), Run: func(context.Context) error { return nil }}
+	if err := s.Add(task); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(task); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second Add = %v, want ErrDuplicate", err)
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Periodični poslovi
+==================
+
+Do sada smo gorutine pokretali jednom. Često nam treba da se nešto radi
+periodično: da se svakog minuta upiše izveštaj, da se svake noći u 2:30
+obrišu stare datoteke, i slično. Na Unix sistemima za to postoji program cron.
+U ovom odeljku pravimo mali cron unutar našeg programa, koristeći samo ono što
+smo već naučili: gorutine, tajmere i naredbu select.
+
+Cron izrazi
+-----------
+Cron izraz ima 5 polja razdvojenih razmakom:
+
+	┌───────────── minut (0 - 59)
+	│ ┌───────────── sat (0 - 23)
+	│ │ ┌───────────── dan u mesecu (1 - 31)
+	│ │ │ ┌───────────── mesec (1 - 12 ili JAN-DEC)
+	│ │ │ │ ┌───────────── dan u nedelji (0 - 6 ili SUN-SAT, 7 je nedelja)
+	│ │ │ │ │
+	* * * * *
+
+Polje može biti "*" (svaka vrednost), broj, opseg "1-5", lista "1,15,30" ili
+korak "0/15" (svakih 15, počevši od 0). Korak se može dodati i opsegu, na
+primer "10-50/20" je isto što i "10,30,50". Ako izraz ima 6 polja, prvo polje
+su sekunde. Podržani su i skraćeni oblici @hourly, @daily, @weekly, @monthly,
+@yearly i "@every 1m30s" za fiksni interval.
+
+Paket "learngo/09-conc/sched" parsira izraze funkcijom sched.Parse. Rezultat
+je vrednost interfejsa "Schedule" sa jednom metodom:
+
+	Next(t time.Time) time.Time
+
+koja vraća prvi trenutak posle t u kome posao treba da se pokrene.
+
+Oko promene letnjeg računanja vremena Next radi kao klasični cron. Kada se
+sat pomeri unapred, vreme koje ne postoji (u Njujorku 10.3.2024. u 2:30) se
+preskače. Kada se sat vrati unazad, vreme koje se desi dvaput (3.11.2024. u
+1:30) pokreće posao samo prvi put, osim ako je polje sati "*".
+*/
+
+package conc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learngo/09-conc/clock"
+	"learngo/09-conc/sched"
+	"time"
+)
+
+func schedCronNext() {
+
+	fmt.Println("\n --- schedCronNext ---")
+
+	start := time.Date(2024, 3, 1, 10, 17, 0, 0, time.UTC)
+	for _, expr := range []string{"0/15 * * * *", "30 2 * * MON-FRI", "0 0 29 2 *", "0/20 * * * * *", "@every 90s"} {
+		s, err := sched.Parse(expr)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		t := start
+		fmt.Printf("%-18s", expr)
+		for i := 0; i < 3; i++ {
+			t = s.Next(t)
+			fmt.Print(" ", t.Format("Mon 2006-01-02 15:04:05"))
+		}
+		fmt.Println()
+	}
+
+	_, err := sched.Parse("61 * * * *")
+	fmt.Println(err)
+}
+
+/*
+Program ispisuje:
+
+	>> 0/15 * * * *       Fri 2024-03-01 10:30:00 Fri 2024-03-01 10:45:00 Fri 2024-03-01 11:00:00
+	>> 30 2 * * MON-FRI   Mon 2024-03-04 02:30:00 Tue 2024-03-05 02:30:00 Wed 2024-03-06 02:30:00
+	>> 0 0 29 2 *         Tue 2028-02-29 00:00:00 Sun 2032-02-29 00:00:00 Fri 2036-02-29 00:00:00
+	>> 0/20 * * * * *     Fri 2024-03-01 10:17:20 Fri 2024-03-01 10:17:40 Fri 2024-03-01 10:18:00
+	>> @every 90s         Fri 2024-03-01 10:18:30 Fri 2024-03-01 10:20:00 Fri 2024-03-01 10:21:30
+	>> sched: "61 * * * *": minute 61 out of range 0-59
+
+Sat koji se može zameniti
+-------------------------
+Kako testirati posao koji se pokreće svakog minuta? Sigurno ne želimo da test
+traje tri minuta. Zato planer ne poziva direktno time.Now i time.NewTimer, već
+koristi interfejs "clock.Clock" iz paketa "learngo/09-conc/clock":
+
+	type Clock interface {
+		Now() time.Time
+		NewTimer(d time.Duration) Timer
+	}
+
+U pravom programu to je clock.Real(). U testu koristimo clock.NewFake(t), sat
+čije vreme stoji dok ga ručno ne pomerimo metodom Advance. Tajmeri lažnog
+sata okidaju unutar poziva Advance. Metoda BlockUntil(n) čeka da gorutine
+naviju bar n tajmera, tako da ne moramo da spavamo da bismo bili sigurni da je
+planer spreman.
+
+Planer
+------
+Planer se pravi sa sched.New, poslovi se dodaju sa Add, a Start pokreće po
+jednu gorutinu za svaki posao. Ta gorutina u petlji računa sledeći trenutak,
+navije tajmer i čeka u naredbi select na tajmer ili na signal za
+zaustavljanje:
+
+	select {
+	case <-t.C():
+		// pokreni posao
+	case <-s.stop:
+		t.Stop()
+		return
+	}
+*/
+
+func schedFakeClock() {
+
+	fmt.Println("\n --- schedFakeClock ---")
+
+	fake := clock.NewFake(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	s := sched.New(sched.Options{Clock: fake})
+
+	ran := make(chan time.Time)
+	s.Add(sched.Task{
+		Name:     "report",
+		Schedule: sched.MustParse("* * * * *"),
+		Run: func(ctx context.Context) error {
+			ran <- fake.Now()
+			return nil
+		},
+	})
+	s.Start()
+
+	for i := 0; i < 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+		fmt.Println("report ran at", (<-ran).Format("15:04:05"))
+	}
+	s.Stop(context.Background())
+}
+
+/*
+Tri minuta lažnog vremena prođu za nekoliko mikrosekundi:
+
+	>> report ran at 10:01:00
+	>> report ran at 10:02:00
+	>> report ran at 10:03:00
+
+Preklapanje
+-----------
+Šta ako se posao pokreće svakog minuta, a jednom potraje dva minuta? Polje
+"Overlap" bira ponašanje:
+
+- sched.Skip (podrazumevano) - novo pokretanje se preskače i poziva se
+  Options.OnSkip
+- sched.Queue - novo pokretanje čeka i izvršava se odmah po završetku
+  tekućeg
+- sched.Allow - novo pokretanje kreće odmah, paralelno sa tekućim
+
+U sledećem programu posao se zaglavi dok ne zatvorimo kanal "release", a mi
+u međuvremenu pomerimo sat za tri minuta.
+*/
+
+func schedOverlap(policy sched.Overlap, name string, expect int) {
+	fake := clock.NewFake(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	s := sched.New(sched.Options{
+		Clock: fake,
+		OnSkip: func(task string, due time.Time) {
+			fmt.Printf("%s: skipped run due at %s\n", task, due.Format("15:04"))
+		},
+	})
+
+	release := make(chan struct{})
+	started := make(chan int, 10)
+	runs := 0
+	s.Add(sched.Task{
+		Name:     name,
+		Schedule: sched.Every(time.Minute),
+		Overlap:  policy,
+		Run: func(ctx context.Context) error {
+			runs++
+			started <- runs
+			<-release
+			return nil
+		},
+	})
+	s.Start()
+
+	for i := 0; i < 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+	}
+	fake.BlockUntil(1)
+	close(release)
+	for i := 0; i < expect; i++ {
+		<-started
+	}
+	s.Stop(context.Background())
+	fmt.Printf("%s: %d runs\n", name, runs)
+}
+
+func schedOverlaps() {
+
+	fmt.Println("\n --- schedOverlaps ---")
+
+	schedOverlap(sched.Skip, "skip", 1)
+	schedOverlap(sched.Queue, "queue", 3)
+}
+
+/*
+Sa politikom Skip, drugo i treće pokretanje se preskaču. Sa politikom Queue,
+ona čekaju i izvršavaju se jedno za drugim, čim se prvo završi:
+
+	>> skip: skipped run due at 10:02
+	>> skip: skipped run due at 10:03
+	>> skip: 1 runs
+	>> queue: 3 runs
+
+Raspršivanje (jitter)
+---------------------
+Ako sto poslova ima izraz "0 * * * *", svi kreću u istoj sekundi. Polje
+"Jitter" odlaže svako pokretanje za slučajno vreme iz [0, Jitter) i tako ih
+raspoređuje.
+
+Zaustavljanje
+-------------
+Stop(ctx) prestaje da zakazuje nova pokretanja i čeka da se završe ona koja su
+u toku. Ako ctx istekne pre toga, context koji su poslovi dobili se otkazuje i
+Stop vraća ctx.Err(). Ovo je isti obrazac koji koristi http.Server.Shutdown.
+*/
+
+func schedGracefulStop() {
+
+	fmt.Println("\n --- schedGracefulStop ---")
+
+	s := sched.New(sched.Options{})
+	s.Add(sched.Task{
+		Name:     "slow",
+		Schedule: sched.Every(100 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			fmt.Println("slow started")
+			select {
+			case <-time.After(300 * time.Millisecond):
+				fmt.Println("slow finished")
+			case <-ctx.Done():
+				fmt.Println("slow cancelled")
+			}
+			return nil
+		},
+	})
+	s.Start()
+	time.Sleep(150 * time.Millisecond)
+
+	start := time.Now()
+	err := s.Stop(context.Background())
+	fmt.Printf("stopped after %v, error %v\n", time.Since(start).Round(100*time.Millisecond), err)
+
+	s = sched.New(sched.Options{})
+	s.Add(sched.Task{
+		Name:     "stuck",
+		Schedule: sched.Every(100 * time.Millisecond),
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			fmt.Println("stuck cancelled")
+			return ctx.Err()
+		},
+	})
+	s.Start()
+	time.Sleep(150 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = s.Stop(ctx)
+	fmt.Println("deadline exceeded:", errors.Is(err, context.DeadlineExceeded))
+	time.Sleep(10 * time.Millisecond)
+}
+
+/*
+Program ispisuje:
+
+	>> slow started
+	>> slow finished
+	>> stopped after 300ms, error <nil>
+	>> deadline exceeded: true
+	>> stuck cancelled
+*/
+
+func SchedFunc() {
+
+	fmt.Println("\n --- Sched Func ---")
+
+	schedCronNext()
+	schedFakeClock()
+	schedOverlaps()
+	schedGracefulStop()
+}
@@ -0,0 +1,448 @@
+package walqueue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrClosed is returned by operations on a closed queue.
+	ErrClosed = errors.New("walqueue: queue closed")
+	// ErrDrained is returned by Dequeue once the queue is sealed and empty.
+	ErrDrained = errors.New("walqueue: queue drained")
+	// ErrUnknownJob is returned by Ack for a job that is not in flight.
+	ErrUnknownJob = errors.New("walqueue: unknown job")
+	// ErrCorrupt is returned by Open for a log with a bad record that is
+	// followed by good ones, which a crash cannot leave behind.
+	ErrCorrupt = errors.New("walqueue: corrupt log")
+)
+
+const (
+	opEnqueue = "enq"
+	opAck     = "ack"
+)
+
+// record is one line of the write-ahead log.
+type record struct {
+	Op     string `json:"op"`
+	ID     uint64 `json:"id"`
+	Key    string `json:"key"`
+	Data   []byte `json:"data,omitempty"`
+	Result []byte `json:"result,omitempty"`
+}
+
+// Job is a unit of work handed out by Dequeue.
+type Job struct {
+	ID   uint64
+	Key  string
+	Data []byte
+}
+
+// Options configures a Queue.
+type Options struct {
+	NoSync       bool // skip fsync after each record, faster but not crash safe
+	CompactEvery int  // compact the log after this many acks, 0 means 100
+
+	// OnCompactError is called when the compaction that Ack starts every
+	// CompactEvery acks fails. The ack itself is already applied, so Ack
+	// does not return the error; compaction is tried again after the next
+	// ack. By default the error is logged.
+	OnCompactError func(error)
+}
+
+// logFile is the part of *os.File the queue writes the log through.
+type logFile interface {
+	io.WriteSeeker
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// Queue is a FIFO job queue backed by an append-only log file. Every enqueue
+// and ack is written to the log before it takes effect, so after a crash
+// Open re-delivers every job that was not acknowledged.
+type Queue struct {
+	mu     sync.Mutex
+	path   string
+	f      logFile
+	size   int64 // length of the log up to the last whole record
+	broken error // set when a torn record could not be cut off
+	opt    Options
+	nextID uint64
+
+	pending  []Job
+	inflight map[uint64]Job
+	keys     map[string]bool
+	results  map[string][]byte
+
+	acks   int
+	sealed bool
+	closed bool
+	notify chan struct{} // closed and replaced whenever state changes
+}
+
+// Open opens or creates the queue log at path and replays it.
+func Open(path string, opt Options) (*Queue, error) {
+	if opt.CompactEvery <= 0 {
+		opt.CompactEvery = 100
+	}
+	if opt.OnCompactError == nil {
+		opt.OnCompactError = func(err error) { log.Printf("walqueue: compaction failed: %v", err) }
+	}
+	q := &Queue{
+		path:     path,
+		opt:      opt,
+		nextID:   1,
+		inflight: make(map[uint64]Job),
+		keys:     make(map[string]bool),
+		results:  make(map[string][]byte),
+		notify:   make(chan struct{}),
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.replay(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	q.f = f
+	return q, nil
+}
+
+// replay rebuilds the in-memory state from the log. A torn last record, left
+// by a crash in the middle of a write, is cut off. A bad record anywhere
+// else means the log was damaged some other way; cutting it off there would
+// lose the jobs after it, so replay fails with ErrCorrupt instead.
+func (q *Queue) replay(f *os.File) error {
+	r := bufio.NewReader(f)
+	var good int64
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break // a final line without '\n' is torn
+		}
+		if err != nil {
+			return err
+		}
+		var rec record
+		if json.Unmarshal(bytes.TrimSpace(line), &rec) != nil {
+			if _, err := r.Peek(1); err != io.EOF {
+				return fmt.Errorf("%w: %s line %d", ErrCorrupt, q.path, n)
+			}
+			break
+		}
+		q.apply(rec)
+		good += int64(len(line))
+	}
+	if err := f.Truncate(good); err != nil {
+		return err
+	}
+	q.size = good
+	_, err := f.Seek(good, io.SeekStart)
+	return err
+}
+
+func (q *Queue) apply(rec record) {
+	if rec.ID >= q.nextID {
+		q.nextID = rec.ID + 1
+	}
+	switch rec.Op {
+	case opEnqueue:
+		q.keys[rec.Key] = true
+		q.pending = append(q.pending, Job{ID: rec.ID, Key: rec.Key, Data: rec.Data})
+	case opAck:
+		if rec.Key == "" {
+			return // id marker written by compact
+		}
+		q.keys[rec.Key] = true
+		q.results[rec.Key] = rec.Result
+		for i, j := range q.pending {
+			if j.ID == rec.ID {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// write appends rec to the log. If that fails, the part of the record that
+// may have been written is cut off again, because later records after a
+// torn one would make the log corrupt.
+func (q *Queue) write(rec record) error {
+	if q.broken != nil {
+		return q.broken
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = q.f.Write(b)
+	if err == nil && !q.opt.NoSync {
+		err = q.f.Sync()
+	}
+	if err != nil {
+		if terr := q.truncate(); terr != nil {
+			q.broken = fmt.Errorf("walqueue: log has a torn record: %w", terr)
+			return errors.Join(err, q.broken)
+		}
+		return err
+	}
+	q.size += int64(len(b))
+	return nil
+}
+
+// truncate cuts the log back to the last whole record.
+func (q *Queue) truncate() error {
+	if err := q.f.Truncate(q.size); err != nil {
+		return err
+	}
+	_, err := q.f.Seek(q.size, io.SeekStart)
+	return err
+}
+
+// wake must be called with q.mu held.
+func (q *Queue) wake() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// Enqueue adds a job identified by key. Keys are remembered forever, so
+// enqueuing a key that is pending, in flight or already done is a no-op and
+// returns false. This lets a restarted producer simply enqueue everything
+// again.
+func (q *Queue) Enqueue(key string, data []byte) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false, ErrClosed
+	}
+	if q.keys[key] {
+		return false, nil
+	}
+	rec := record{Op: opEnqueue, ID: q.nextID, Key: key, Data: data}
+	if err := q.write(rec); err != nil {
+		return false, err
+	}
+	q.nextID++
+	q.keys[key] = true
+	q.pending = append(q.pending, Job{ID: rec.ID, Key: key, Data: data})
+	q.wake()
+	return true, nil
+}
+
+// Seal tells the queue that no more jobs will be enqueued. Once every
+// pending job has been handed out, Dequeue returns ErrDrained.
+func (q *Queue) Seal() {
+	q.mu.Lock()
+	q.sealed = true
+	q.wake()
+	q.mu.Unlock()
+}
+
+// Dequeue blocks until a job is available, the queue is sealed and empty,
+// or ctx is done.
+func (q *Queue) Dequeue(ctx context.Context) (Job, error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return Job{}, ErrClosed
+		}
+		if len(q.pending) > 0 {
+			j := q.pending[0]
+			q.pending = q.pending[1:]
+			q.inflight[j.ID] = j
+			q.mu.Unlock()
+			return j, nil
+		}
+		if q.sealed {
+			q.mu.Unlock()
+			return Job{}, ErrDrained
+		}
+		notify := q.notify
+		q.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return Job{}, ctx.Err()
+		}
+	}
+}
+
+// Ack marks an in-flight job as done and records its result. Once Ack
+// returns nil the ack is in the log; an error from the compaction that may
+// follow goes to Options.OnCompactError.
+func (q *Queue) Ack(id uint64, result []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	j, ok := q.inflight[id]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownJob, id)
+	}
+	if err := q.write(record{Op: opAck, ID: id, Key: j.Key, Result: result}); err != nil {
+		return err
+	}
+	delete(q.inflight, id)
+	q.results[j.Key] = result
+
+	q.acks++
+	if q.acks >= q.opt.CompactEvery {
+		if err := q.compact(); err != nil {
+			q.opt.OnCompactError(err)
+		}
+	}
+	return nil
+}
+
+// Result returns the recorded result of a finished job.
+func (q *Queue) Result(key string) ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, ok := q.results[key]
+	return r, ok
+}
+
+// Results returns a copy of all recorded results by job key.
+func (q *Queue) Results() map[string][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	m := make(map[string][]byte, len(q.results))
+	for k, v := range q.results {
+		m[k] = v
+	}
+	return m
+}
+
+// Len returns the number of jobs that are pending or in flight.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending) + len(q.inflight)
+}
+
+// Compact rewrites the log so it holds only the results of finished jobs and
+// the jobs that are still unacknowledged.
+func (q *Queue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	return q.compact()
+}
+
+func (q *Queue) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	keys := make([]string, 0, len(q.results))
+	for key := range q.results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := enc.Encode(record{Op: opAck, Key: key, Result: q.results[key]}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	// In-flight jobs are written as pending: if we crash now they must be
+	// delivered again. Ids grow with every enqueue, so sorting by id keeps
+	// the jobs in the order they were enqueued.
+	unacked := make([]Job, 0, len(q.inflight)+len(q.pending))
+	for _, j := range q.inflight {
+		unacked = append(unacked, j)
+	}
+	unacked = append(unacked, q.pending...)
+	sort.Slice(unacked, func(i, j int) bool { return unacked[i].ID < unacked[j].ID })
+	for _, j := range unacked {
+		if err := enc.Encode(record{Op: opEnqueue, ID: j.ID, Key: j.Key, Data: j.Data}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	// Keep the id counter moving forward even when nothing is pending.
+	if err := enc.Encode(record{Op: opAck, ID: q.nextID - 1}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(q.path)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	q.f.Close()
+	q.f = f
+	q.size = st.Size()
+	q.broken = nil
+	q.acks = 0
+	return nil
+}
+
+// Close closes the log. Unacknowledged jobs stay in the log and are
+// delivered again by the next Open.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.wake()
+	return q.f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
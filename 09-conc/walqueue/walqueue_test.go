@@ -0,0 +1,308 @@
+package walqueue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const crashEnv = "WALQUEUE_CRASH_LOG"
+
+// crashWorker runs in the child process: it works through the queue at
+// path, printing every ack, until the parent kills it.
+func crashWorker(path string) {
+	q, err := Open(path, Options{CompactEvery: 7})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	q.Seal()
+	for {
+		j, err := q.Dequeue(context.Background())
+		if errors.Is(err, ErrDrained) {
+			os.Exit(0)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		time.Sleep(time.Millisecond)
+		if err := q.Ack(j.ID, []byte("done "+j.Key)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Println("ack", j.Key)
+	}
+}
+
+// TestCrashReplay kills a worker process several times in the middle of
+// its work and checks that after every restart each job is either done or
+// still queued, exactly once, and that the queued ones keep their order.
+func TestCrashReplay(t *testing.T) {
+	if path := os.Getenv(crashEnv); path != "" {
+		crashWorker(path)
+		return
+	}
+	if testing.Short() {
+		t.Skip("starts child processes")
+	}
+
+	const jobs = 200
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	q, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range jobs {
+		if _, err := q.Enqueue(fmt.Sprintf("job-%03d", i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Close()
+
+	for round := 0; ; round++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCrashReplay$")
+		cmd.Env = append(os.Environ(), crashEnv+"="+path)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		// Let the child ack a few jobs, then kill it while it works.
+		s := bufio.NewScanner(out)
+		for acks := 0; acks < 15+round*7 && s.Scan(); {
+			if strings.HasPrefix(s.Text(), "ack ") {
+				acks++
+			}
+		}
+		cmd.Process.Kill()
+		cmd.Wait()
+
+		q, err := Open(path, Options{})
+		if err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+		results := q.Results()
+		var queued []string
+		q.Seal()
+		for {
+			j, err := q.Dequeue(context.Background())
+			if errors.Is(err, ErrDrained) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			queued = append(queued, j.Key)
+		}
+		q.Close()
+
+		seen := make(map[string]int)
+		for key, res := range results {
+			seen[key]++
+			if string(res) != "done "+key {
+				t.Errorf("round %d: result of %s = %q", round, key, res)
+			}
+		}
+		for i, key := range queued {
+			seen[key]++
+			if i > 0 && queued[i-1] > key {
+				t.Errorf("round %d: %s queued after %s", round, key, queued[i-1])
+			}
+		}
+		for i := range jobs {
+			key := fmt.Sprintf("job-%03d", i)
+			if seen[key] != 1 {
+				t.Errorf("round %d: %s seen %d times", round, key, seen[key])
+			}
+		}
+		if t.Failed() || len(queued) == 0 {
+			return
+		}
+	}
+}
+
+func TestReplayTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	log := `{"op":"enq","id":1,"key":"a"}
+{"op":"enq","id":2,"key":"b"}
+{"op":"ack","id":1,"key":"a","result":"eA=="}
+{"op":"enq","id":3,"ke`
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+	q, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if q.Len() != 1 {
+		t.Errorf("Len = %d, want 1", q.Len())
+	}
+	if _, err := q.Enqueue("c", nil); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	if want := log[:strings.LastIndex(log, "\n")+1] + `{"op":"enq","id":3,"key":"c"}` + "\n"; string(b) != want {
+		t.Errorf("log after enqueue:\n%s\nwant:\n%s", b, want)
+	}
+}
+
+func TestReplayCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	log := `{"op":"enq","id":1,"key":"a"}
+garbage
+{"op":"enq","id":2,"key":"b"}
+`
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, Options{}); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open = %v, want ErrCorrupt", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != log {
+		t.Errorf("corrupt log was changed to:\n%s", b)
+	}
+}
+
+func TestCompactKeepsOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	q, err := Open(path, Options{NoSync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 20 {
+		q.Enqueue(fmt.Sprintf("job-%02d", i), nil)
+	}
+	// Leave a few jobs in flight, so that compaction has to merge them
+	// back with the pending ones.
+	for range 5 {
+		if _, err := q.Dequeue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q, err = Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	for i := range 20 {
+		j, err := q.Dequeue(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("job-%02d", i); j.Key != want {
+			t.Fatalf("job %d = %s, want %s", i, j.Key, want)
+		}
+	}
+}
+
+// tornFile writes only half of the next record and then fails, like a
+// write interrupted by a full disk.
+type tornFile struct {
+	logFile
+	fail bool
+}
+
+func (f *tornFile) Write(b []byte) (int, error) {
+	if !f.fail {
+		return f.logFile.Write(b)
+	}
+	f.fail = false
+	n, _ := f.logFile.Write(b[:len(b)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestWriteErrorCutsTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+	q, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &tornFile{logFile: q.f}
+	q.f = f
+
+	q.Enqueue("a", nil)
+	f.fail = true
+	if _, err := q.Enqueue("b", nil); err == nil {
+		t.Fatal("Enqueue with a failing write succeeded")
+	}
+	if _, err := q.Enqueue("c", nil); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q, err = Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open after a failed write: %v", err)
+	}
+	defer q.Close()
+	if q.Len() != 2 {
+		t.Fatalf("Len after reopening = %d, want 2", q.Len())
+	}
+	var keys []string
+	for range 2 {
+		j, err := q.Dequeue(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, j.Key)
+	}
+	if strings.Join(keys, " ") != "a c" {
+		t.Errorf("jobs after reopening = %v, want [a c]", keys)
+	}
+}
+
+func TestAckIgnoresCompactError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "queue")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var compactErrs []error
+	q, err := Open(filepath.Join(dir, "jobs.wal"), Options{
+		CompactEvery:   1,
+		OnCompactError: func(err error) { compactErrs = append(compactErrs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.Enqueue("a", nil)
+	j, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The open log keeps working, but compaction cannot create its
+	// temporary file any more.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(j.ID, []byte("ok")); err != nil {
+		t.Fatalf("Ack = %v, want nil when only compaction fails", err)
+	}
+	if len(compactErrs) != 1 {
+		t.Errorf("OnCompactError called %d times, want 1", len(compactErrs))
+	}
+	if r, ok := q.Result("a"); !ok || string(r) != "ok" {
+		t.Errorf("Result = %q, %v", r, ok)
+	}
+	if err := q.Ack(j.ID, nil); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("second Ack = %v, want ErrUnknownJob", err)
+	}
+}
@@ -0,0 +1,130 @@
+package billing
+
+import (
+	"learngo/08-ifaces/poly"
+	"time"
+)
+
+// Income is a source of income, like the Income interface of the OOP
+// lesson, but instead of a single number it gives the line items to bill
+// for a period.
+type Income interface {
+	Source() string
+	LineItems(p Period) []LineItem
+}
+
+// LineItem is one line of an invoice.
+type LineItem struct {
+	Source      string
+	Description string
+	Quantity    Quantity
+	Unit        string // such as "h" or "click", empty for a single item
+	UnitPrice   Money
+}
+
+// Amount returns UnitPrice multiplied by Quantity.
+func (li LineItem) Amount() Money {
+	return li.UnitPrice.Mul(li.Quantity)
+}
+
+// FixedBilling is a project billed with an agreed amount, due on Date.
+type FixedBilling struct {
+	Project string
+	Amount  Money
+	Date    time.Time
+}
+
+func (fb FixedBilling) Source() string { return fb.Project }
+
+func (fb FixedBilling) LineItems(p Period) []LineItem {
+	if !p.Contains(fb.Date) {
+		return nil
+	}
+	return []LineItem{{
+		Source:      fb.Project,
+		Description: "fixed price",
+		Quantity:    Unit,
+		UnitPrice:   fb.Amount,
+	}}
+}
+
+// TimeEntry is work logged on a day.
+type TimeEntry struct {
+	Date  time.Time
+	Hours Quantity
+}
+
+// TimeAndMaterial is a project billed by the hour.
+type TimeAndMaterial struct {
+	Project    string
+	HourlyRate Money
+	Entries    []TimeEntry
+}
+
+func (tm TimeAndMaterial) Source() string { return tm.Project }
+
+// LineItems returns one item with the hours logged in p.
+func (tm TimeAndMaterial) LineItems(p Period) []LineItem {
+	var hours Quantity
+	for _, e := range tm.Entries {
+		if p.Contains(e.Date) {
+			hours += e.Hours
+		}
+	}
+	if hours == 0 {
+		return nil
+	}
+	return []LineItem{{
+		Source:      tm.Project,
+		Description: "hours worked",
+		Quantity:    hours,
+		Unit:        "h",
+		UnitPrice:   tm.HourlyRate,
+	}}
+}
+
+// Clicks is the number of clicks on an ad on a day.
+type Clicks struct {
+	Date  time.Time
+	Count int64
+}
+
+// Advertisement is an ad paid per click.
+type Advertisement struct {
+	Name   string
+	CPC    Money // cost per click
+	Clicks []Clicks
+}
+
+func (a Advertisement) Source() string { return a.Name }
+
+// LineItems returns one item with the clicks in p.
+func (a Advertisement) LineItems(p Period) []LineItem {
+	var clicks int64
+	for _, c := range a.Clicks {
+		if p.Contains(c.Date) {
+			clicks += c.Count
+		}
+	}
+	if clicks == 0 {
+		return nil
+	}
+	return []LineItem{{
+		Source:      a.Name,
+		Description: "clicks",
+		Quantity:    Qty(clicks),
+		Unit:        "click",
+		UnitPrice:   a.CPC,
+	}}
+}
+
+// Incomes decodes the income sources of this package from JSON objects
+// whose "type" is "fixed", "time-and-material" or "advertisement". Other
+// packages register their own sources with poly.Register.
+var Incomes poly.Registry[Income]
+
+func init() {
+	poly.Register[Income, FixedBilling](&Incomes, "fixed")
+	poly.Register[Income, TimeAndMaterial](&Incomes, "time-and-material")
+	poly.Register[Income, Advertisement](&Incomes, "advertisement")
+}
@@ -0,0 +1,220 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// ErrDiscount is returned by Discount.Validate for a discount that could
+// raise the amount or is ambiguous.
+var ErrDiscount = errors.New("billing: invalid discount")
+
+// Discount lowers the amount an invoice is taxed and paid on. It is either
+// a percentage (Rate) or a fixed amount (Amount), and applies to the items
+// of one source, or to all items if Source is empty.
+type Discount struct {
+	Name   string
+	Source string
+	Rate   Rate
+	Amount Money
+}
+
+// Validate returns ErrDiscount if d has a negative rate or amount, or both a
+// rate and an amount, and ErrCurrencyMismatch if its amount is not in c. A
+// discount with neither takes off nothing and is valid.
+func (d Discount) Validate(c Currency) error {
+	switch {
+	case d.Rate < 0:
+		return fmt.Errorf("%w: negative rate %s", ErrDiscount, d.Rate)
+	case d.Amount.Amount < 0:
+		return fmt.Errorf("%w: negative amount %s", ErrDiscount, d.Amount)
+	case d.Rate != 0 && !d.Amount.IsZero():
+		return fmt.Errorf("%w: both a rate and an amount", ErrDiscount)
+	case !d.Amount.IsZero() && d.Amount.Currency != c:
+		return fmt.Errorf("%w: %s discount on a %s invoice", ErrCurrencyMismatch, d.Amount.Currency, c)
+	}
+	return nil
+}
+
+// Tax is a tax charged on the amount after discounts.
+type Tax struct {
+	Name string
+	Rate Rate
+}
+
+// Options configures Generate.
+type Options struct {
+	Number    string
+	Currency  Currency
+	Discounts []Discount // applied in order, each to what is left after the earlier ones
+	Taxes     []Tax      // each computed on the discounted amount, not on other taxes
+}
+
+// AppliedDiscount is a discount with the amount it took off.
+type AppliedDiscount struct {
+	Discount
+	Off Money
+}
+
+// AppliedTax is a tax with the amount it added.
+type AppliedTax struct {
+	Tax
+	Base   Money
+	Amount Money
+}
+
+// Invoice is the bill for a period.
+type Invoice struct {
+	Number    string
+	Period    Period
+	Items     []LineItem
+	Discounts []AppliedDiscount
+	Taxes     []AppliedTax
+
+	Subtotal Money // sum of the items
+	Discount Money // sum of the discounts
+	Net      Money // Subtotal - Discount
+	Tax      Money // sum of the taxes
+	Total    Money // Net + Tax
+}
+
+// ErrUnknownSource is returned for a discount on a source that is not one
+// of the sources being billed.
+var ErrUnknownSource = errors.New("billing: discount on unknown source")
+
+// Generate bills sources for p. Every amount must be in opt.Currency,
+// otherwise the error wraps ErrCurrencyMismatch. Discounts are checked with
+// Discount.Validate before anything is computed. A discount on a source is
+// computed on what earlier discounts on that source left of its items; a
+// discount never takes off more than is left of the whole invoice. A
+// discount on a source without items in p takes off nothing, and one on a
+// source that is not in sources is an error wrapping ErrUnknownSource.
+func Generate(sources []Income, p Period, opt Options) (*Invoice, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	for _, d := range opt.Discounts {
+		if err := d.Validate(opt.Currency); err != nil {
+			return nil, fmt.Errorf("discount %s: %w", d.Name, err)
+		}
+	}
+	zero := Money{Currency: opt.Currency}
+	inv := &Invoice{Number: opt.Number, Period: p, Subtotal: zero, Discount: zero, Tax: zero}
+
+	left := make(map[string]Money) // amount of each source not yet discounted
+	for _, src := range sources {
+		if _, ok := left[src.Source()]; !ok {
+			left[src.Source()] = zero
+		}
+		for _, li := range src.LineItems(p) {
+			subtotal, err := inv.Subtotal.Add(li.Amount())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", li.Source, err)
+			}
+			sum, ok := left[li.Source]
+			if !ok {
+				sum = zero
+			}
+			if left[li.Source], err = sum.Add(li.Amount()); err != nil {
+				return nil, fmt.Errorf("%s: %w", li.Source, err)
+			}
+			inv.Subtotal = subtotal
+			inv.Items = append(inv.Items, li)
+		}
+	}
+
+	total := inv.Subtotal
+	for _, d := range opt.Discounts {
+		base := total
+		if d.Source != "" {
+			src, ok := left[d.Source]
+			if !ok {
+				return nil, fmt.Errorf("discount %s: %w %q", d.Name, ErrUnknownSource, d.Source)
+			}
+			if src.Amount < base.Amount {
+				base = src
+			}
+		}
+		off := base.Apply(d.Rate)
+		if !d.Amount.IsZero() {
+			off = d.Amount
+		}
+		if off.Amount > base.Amount {
+			off = base
+		}
+
+		var err error
+		if d.Source != "" {
+			if left[d.Source], err = left[d.Source].Sub(off); err != nil {
+				return nil, fmt.Errorf("discount %s: %w", d.Name, err)
+			}
+		}
+		if total, err = total.Sub(off); err != nil {
+			return nil, fmt.Errorf("discount %s: %w", d.Name, err)
+		}
+		if inv.Discount, err = inv.Discount.Add(off); err != nil {
+			return nil, fmt.Errorf("discount %s: %w", d.Name, err)
+		}
+		inv.Discounts = append(inv.Discounts, AppliedDiscount{d, off})
+	}
+	inv.Net = total
+
+	for _, t := range opt.Taxes {
+		amount := inv.Net.Apply(t.Rate)
+		var err error
+		if inv.Tax, err = inv.Tax.Add(amount); err != nil {
+			return nil, fmt.Errorf("tax %s: %w", t.Name, err)
+		}
+		inv.Taxes = append(inv.Taxes, AppliedTax{t, inv.Net, amount})
+	}
+	var err error
+	if inv.Total, err = inv.Net.Add(inv.Tax); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// WriteText prints the invoice as a table.
+func (inv *Invoice) WriteText(w io.Writer) error {
+	type row struct{ label, qty, price, amount string }
+	var rows []row
+	for _, li := range inv.Items {
+		qty := strings.TrimSpace(li.Quantity.String() + " " + li.Unit)
+		rows = append(rows, row{li.Source + ", " + li.Description, qty, li.UnitPrice.String(), li.Amount().String()})
+	}
+	rows = append(rows, row{label: "Subtotal", amount: inv.Subtotal.String()})
+	for _, d := range inv.Discounts {
+		rows = append(rows, row{label: d.label(), amount: d.Off.Neg().String()})
+	}
+	for _, t := range inv.Taxes {
+		rows = append(rows, row{label: t.Name + " " + t.Rate.String(), amount: t.Amount.String()})
+	}
+	rows = append(rows, row{label: "Total", amount: inv.Total.String()})
+
+	// Numbers are aligned right; labels are padded to the same width so
+	// that they stay aligned left.
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r.label))
+	}
+	fmt.Fprintf(w, "Invoice %s, %s\n", inv.Number, inv.Period)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%-*s\t%s\t%s\t%s\t\n", width, r.label, r.qty, r.price, r.amount)
+	}
+	return tw.Flush()
+}
+
+func (d AppliedDiscount) label() string {
+	s := d.Name
+	if d.Rate != 0 {
+		s += " " + d.Rate.String()
+	}
+	if d.Source != "" {
+		s += " on " + d.Source
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGenerateDiscounts(t *testing.T) {
+	march := Month(2024, time.March, time.UTC)
+	sources := []Income{
+		FixedBilling{Project: "A", Amount: Major(100, USD), Date: march.Start},
+		FixedBilling{Project: "B", Amount: Major(50, USD), Date: march.Start},
+	}
+	tests := []struct {
+		name     string
+		discount Discount
+		net      Money
+		err      error
+	}{
+		{"rate", Discount{Rate: Percent(10)}, Major(135, USD), nil},
+		{"rate on source", Discount{Source: "B", Rate: Percent(10)}, Major(145, USD), nil},
+		{"amount", Discount{Amount: Major(20, USD)}, Major(130, USD), nil},
+		{"amount capped by source", Discount{Source: "B", Amount: Major(80, USD)}, Major(100, USD), nil},
+		{"zero amount", Discount{Name: "none"}, Major(150, USD), nil},
+		{"negative rate", Discount{Rate: -Percent(10)}, Money{}, ErrDiscount},
+		{"negative amount", Discount{Amount: Major(-20, USD)}, Money{}, ErrDiscount},
+		{"rate and amount", Discount{Rate: Percent(10), Amount: Major(20, USD)}, Money{}, ErrDiscount},
+		{"other currency", Discount{Amount: Major(20, EUR)}, Money{}, ErrCurrencyMismatch},
+		{"unknown source", Discount{Source: "C", Rate: Percent(10)}, Money{}, ErrUnknownSource},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := Generate(sources, march, Options{Currency: USD, Discounts: []Discount{tt.discount}})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Generate = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inv.Net != tt.net {
+				t.Errorf("Net = %s, want %s", inv.Net, tt.net)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package billing turns income sources into invoices. Amounts are integers
+// in the minor unit of their currency, such as cents, so that no money is
+// lost to floating point rounding.
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency with the number of digits of its minor
+// unit.
+type Currency struct {
+	Code   string
+	Digits int
+}
+
+var (
+	USD = Currency{"USD", 2}
+	EUR = Currency{"EUR", 2}
+	RSD = Currency{"RSD", 2}
+	JPY = Currency{"JPY", 0}
+)
+
+func (c Currency) String() string { return c.Code }
+
+var currencies = map[string]Currency{USD.Code: USD, EUR.Code: EUR, RSD.Code: RSD, JPY.Code: JPY}
+
+// LookupCurrency returns the currency with the given code.
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencies[code]
+	return c, ok
+}
+
+// scale returns the number of minor units in a major unit.
+func (c Currency) scale() int64 {
+	s := int64(1)
+	for i := 0; i < c.Digits; i++ {
+		s *= 10
+	}
+	return s
+}
+
+// ErrCurrencyMismatch is returned when amounts in different currencies are
+// combined.
+var ErrCurrencyMismatch = errors.New("billing: currency mismatch")
+
+// Money is an amount in the minor unit of its currency.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// New returns amount minor units of c, so New(1999, USD) is 19.99 USD.
+func New(amount int64, c Currency) Money {
+	return Money{Amount: amount, Currency: c}
+}
+
+// Major returns amount major units of c, so Major(20, USD) is 20.00 USD.
+func Major(amount int64, c Currency) Money {
+	return Money{Amount: amount * c.scale(), Currency: c}
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{m.Amount + o.Amount, m.Currency}, nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{-m.Amount, m.Currency}
+}
+
+// IsZero reports whether the amount is 0.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Mul returns m multiplied by q, rounded to the minor unit.
+func (m Money) Mul(q Quantity) Money {
+	return Money{mulDiv(m.Amount, int64(q), int64(Unit)), m.Currency}
+}
+
+// Apply returns the part of m given by r, rounded to the minor unit.
+func (m Money) Apply(r Rate) Money {
+	return Money{mulDiv(m.Amount, int64(r), 100*100), m.Currency}
+}
+
+// String formats m as "USD 1234.50".
+func (m Money) String() string {
+	sign := ""
+	a := m.Amount
+	if a < 0 {
+		sign, a = "-", -a
+	}
+	s := m.Currency.scale()
+	if m.Currency.Digits == 0 {
+		return fmt.Sprintf("%s %s%d", m.Currency, sign, a)
+	}
+	return fmt.Sprintf("%s %s%d.%0*d", m.Currency, sign, a/s, m.Currency.Digits, a%s)
+}
+
+// ParseMoney parses the format of Money.String, "USD 1234.50". The amount
+// may have fewer digits than the currency, but not more, since that would
+// need rounding.
+func ParseMoney(s string) (Money, error) {
+	code, amount, ok := strings.Cut(s, " ")
+	if !ok {
+		return Money{}, fmt.Errorf("billing: invalid money %q, want e.g. \"USD 12.50\"", s)
+	}
+	c, ok := LookupCurrency(code)
+	if !ok {
+		return Money{}, fmt.Errorf("billing: unknown currency %q", code)
+	}
+	n, err := parseDecimal(amount, c.Digits)
+	if err != nil {
+		return Money{}, fmt.Errorf("billing: invalid amount %q for %s", amount, c)
+	}
+	return Money{n, c}, nil
+}
+
+// MarshalJSON encodes m as a string, "USD 1234.50".
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// parseDecimal parses s, which has at most digits decimal places, as an
+// integer count of 10^-digits.
+func parseDecimal(s string, digits int) (int64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > digits || strings.HasPrefix(whole, "+") {
+		return 0, strconv.ErrSyntax
+	}
+	n, err := strconv.ParseInt(whole+frac+strings.Repeat("0", digits-len(frac)), 10, 64)
+	if err != nil || whole == "" || whole == "-" {
+		return 0, strconv.ErrSyntax
+	}
+	return n, nil
+}
+
+// mulDiv returns a*b/c rounded half away from zero.
+func mulDiv(a, b, c int64) int64 {
+	n := a * b
+	q, r := n/c, n%c
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= c {
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+// Quantity is a number of units in thousandths, so that 7.5 hours can be
+// billed. Use Qty for whole numbers.
+type Quantity int64
+
+// Unit is a quantity of one.
+const Unit Quantity = 1000
+
+// Qty returns a quantity of n whole units.
+func Qty(n int64) Quantity {
+	return Quantity(n) * Unit
+}
+
+// String formats q without trailing zeros, as "7.5".
+func (q Quantity) String() string {
+	s := strconv.FormatFloat(float64(q)/float64(Unit), 'f', 3, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// MarshalJSON encodes q as a number, 7.5.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON decodes a number with at most three decimal places.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	n, err := parseDecimal(string(data), 3)
+	if err != nil {
+		return fmt.Errorf("billing: invalid quantity %s, want a number with at most 3 decimals", data)
+	}
+	*q = Quantity(n)
+	return nil
+}
+
+// Rate is a percentage in basis points, hundredths of a percent, so 20% is
+// 2000 and 8.25% is 825. Use Percent for whole percentages.
+type Rate int64
+
+// Percent returns a rate of p percent.
+func Percent(p int64) Rate {
+	return Rate(p * 100)
+}
+
+// String formats r as "20%" or "8.25%".
+func (r Rate) String() string {
+	s := strconv.FormatFloat(float64(r)/100, 'f', 2, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".") + "%"
+}
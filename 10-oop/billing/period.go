@@ -0,0 +1,47 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPeriod is returned for a period that ends before it starts.
+var ErrPeriod = errors.New("billing: invalid period")
+
+// Period is a billing period, from Start up to but not including End.
+type Period struct {
+	Start, End time.Time
+}
+
+// Month returns the calendar month of year in loc.
+func Month(year int, month time.Month, loc *time.Location) Period {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return Period{start, start.AddDate(0, 1, 0)}
+}
+
+// Validate returns ErrPeriod if p is empty or ends before it starts.
+func (p Period) Validate() error {
+	if !p.Start.Before(p.End) {
+		return fmt.Errorf("%w: %s is not before %s", ErrPeriod,
+			p.Start.Format(time.DateOnly), p.End.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// Contains reports whether t is in p.
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// Next returns the period of the same calendar length that follows p, so
+// the month after January is February.
+func (p Period) Next() Period {
+	y, m, d := p.End.Date()
+	y0, m0, d0 := p.Start.Date()
+	return Period{p.End, p.End.AddDate(y-y0, int(m-m0), d-d0)}
+}
+
+func (p Period) String() string {
+	return p.Start.Format(time.DateOnly) + " - " + p.End.AddDate(0, 0, -1).Format(time.DateOnly)
+}
@@ -0,0 +1,338 @@
+/*
+Obračun prihoda
+===============
+
+Interfejs Income iz lekcije "oop.go" pokazuje polimorfizam, ali za pravi
+obračun nije dovoljan. calculate() vraća jedan int bez valute, ne zna za koji
+period se računa, a iz rezultata se ne vidi od čega se iznos sastoji, pa se iz
+njega ne može napisati faktura.
+
+Paket "learngo/10-oop/billing" ima isti interfejs, samo bogatiji:
+
+	type Income interface {
+		Source() string
+		LineItems(p Period) []LineItem
+	}
+
+Svaki izvor prihoda za zadati period vraća stavke fakture (LineItem), sa
+količinom, jedinicom mere i cenom po jedinici. Tipovi FixedBilling,
+TimeAndMaterial i Advertisement su oni iz lekcije "oop.go":
+
+- FixedBilling se naplaćuje jednom, u periodu u kome je datum plaćanja,
+- TimeAndMaterial se naplaćuje po satu, za sate upisane u periodu,
+- Advertisement se naplaćuje po kliku, za klikove u periodu.
+
+Novac (billing.Money) je celi broj najmanjih jedinica valute, na primer
+centi, uz valutu. Zbog float64 bi 0.1 + 0.2 bilo 0.30000000000000004, a u
+celim brojevima centi se ništa ne gubi. Zaokružuje se samo na jednom mestu,
+kada se cena pomnoži količinom ili procentom, i to uvek na isti način (pola
+se zaokružuje dalje od nule). Količina se čuva u hiljaditim delovima, pa se
+može naplatiti i 7.5 sati, a stope (billing.Rate) u stotim delovima procenta.
+*/
+
+package oop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learngo/08-ifaces/poly"
+	"learngo/10-oop/billing"
+	"learngo/11-deferAndError/validation"
+	"os"
+	"strings"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func incomeSources() []billing.Income {
+	return []billing.Income{
+		billing.FixedBilling{Project: "Project 1", Amount: billing.Major(5000, billing.USD), Date: day(15)},
+		billing.FixedBilling{Project: "Project 2", Amount: billing.Major(10000, billing.USD), Date: day(31).AddDate(0, 0, 1)},
+		billing.TimeAndMaterial{
+			Project:    "Project 3",
+			HourlyRate: billing.Major(25, billing.USD),
+			Entries: []billing.TimeEntry{
+				{Date: day(4), Hours: billing.Qty(8)},
+				{Date: day(5), Hours: 7*billing.Unit + billing.Unit/2},
+				{Date: day(6), Hours: billing.Qty(6)},
+			},
+		},
+		billing.Advertisement{
+			Name: "Banner Ad",
+			CPC:  billing.New(35, billing.USD),
+			Clicks: []billing.Clicks{
+				{Date: day(1), Count: 480},
+				{Date: day(20), Count: 333},
+			},
+		},
+	}
+}
+
+func billingLineItems() {
+
+	fmt.Println("\n --- billingLineItems ---")
+
+	march := billing.Month(2024, time.March, time.UTC)
+	for _, income := range incomeSources() {
+		items := income.LineItems(march)
+		if len(items) == 0 {
+			fmt.Printf("%s: nothing to bill in %s\n", income.Source(), march)
+		}
+		for _, li := range items {
+			qty := strings.TrimSpace(li.Quantity.String() + " " + li.Unit)
+			fmt.Printf("%s: %s x %s = %s\n", li.Source, qty, li.UnitPrice, li.Amount())
+		}
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> Project 1: 1 x USD 5000.00 = USD 5000.00
+	>> Project 2: nothing to bill in 2024-03-01 - 2024-03-31
+	>> Project 3: 21.5 h x USD 25.00 = USD 537.50
+	>> Banner Ad: 813 click x USD 0.35 = USD 284.55
+
+Project 2 se plaća 1. aprila, pa ga nema u martu. Kao i kod calculateNetIncome,
+kod koji poziva LineItems ne zna kog je tipa izvor prihoda.
+
+Faktura
+-------
+billing.Generate od izvora prihoda pravi fakturu za period. Popusti
+(billing.Discount) su procenat ili fiksni iznos, na celu fakturu ili samo na
+stavke jednog izvora, i primenjuju se redom. Porezi (billing.Tax) se računaju na
+iznos posle popusta. Faktura sadrži međuzbir (Subtotal), popust, osnovicu
+(Net), porez i ukupan iznos (Total).
+*/
+
+func billingInvoice() {
+
+	fmt.Println("\n --- billingInvoice ---")
+
+	inv, err := billing.Generate(incomeSources(), billing.Month(2024, time.March, time.UTC), billing.Options{
+		Number:   "2024-03-001",
+		Currency: billing.USD,
+		Discounts: []billing.Discount{
+			{Name: "Loyalty", Source: "Project 3", Rate: billing.Percent(10)},
+			{Name: "Voucher", Amount: billing.Major(100, billing.USD)},
+		},
+		Taxes: []billing.Tax{
+			{Name: "VAT", Rate: billing.Percent(20)},
+			{Name: "City tax", Rate: 125},
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	inv.WriteText(os.Stdout)
+
+	fmt.Println("net", inv.Net, "tax", inv.Tax, "total", inv.Total)
+}
+
+/*
+Program ispisuje:
+
+	>> Invoice 2024-03-001, 2024-03-01 - 2024-03-31
+	>>   Project 1, fixed price            1  USD 5000.00  USD 5000.00
+	>>   Project 3, hours worked      21.5 h    USD 25.00   USD 537.50
+	>>   Banner Ad, clicks         813 click     USD 0.35   USD 284.55
+	>>   Subtotal                                          USD 5822.05
+	>>   Loyalty 10% on Project 3                           USD -53.75
+	>>   Voucher                                           USD -100.00
+	>>   VAT 20%                                           USD 1133.66
+	>>   City tax 1.25%                                      USD 70.85
+	>>   Total                                             USD 6872.81
+	>> net USD 5668.30 tax USD 1204.51 total USD 6872.81
+
+Popust za lojalnost je 10% od 537.50, zaokruženo na 53.75, a vaučer još 100.00.
+Oba poreza se računaju na 5668.30.
+
+Mešanje valuta
+--------------
+Iznosi u različitim valutama se ne mogu sabrati. Generate tada vraća grešku
+koja omotava billing.ErrCurrencyMismatch, umesto da tiho sabere dolare i evre.
+*/
+
+func billingCurrency() {
+
+	fmt.Println("\n --- billingCurrency ---")
+
+	sources := append(incomeSources(), billing.FixedBilling{
+		Project: "Project 4",
+		Amount:  billing.Major(1200, billing.EUR),
+		Date:    day(10),
+	})
+	_, err := billing.Generate(sources, billing.Month(2024, time.March, time.UTC), billing.Options{Currency: billing.USD})
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, billing.ErrCurrencyMismatch))
+
+	_, err = billing.Generate(sources, billing.Period{Start: day(10), End: day(1)}, billing.Options{Currency: billing.USD})
+	fmt.Println(err)
+}
+
+/*
+Program ispisuje:
+
+	>> Project 4: billing: currency mismatch: USD + EUR
+	>> true
+	>> billing: invalid period: 2024-03-10 is not before 2024-03-01
+*/
+
+/*
+Izvori prihoda iz JSON-a
+------------------------
+Kao i u lekciji "08-ifaces/ifaceJSON.go", izvori prihoda ne moraju da se
+prave u Go kodu. billing.Incomes je registar (poly.Registry[billing.Income])
+u kome su FixedBilling, TimeAndMaterial i Advertisement registrovani pod
+imenima "fixed", "time-and-material" i "advertisement". Novac se u JSON-u
+piše kao "USD 5000.00", a količina kao broj sa najviše tri decimale.
+
+Dokument ima i druga polja, pa ga prvo dekodiramo u strukturu u kojoj je
+isečak prihoda json.RawMessage, a njega dekodiramo registrom, sa putanjom
+"$.incomes" za poruke o greškama.
+*/
+
+// Subscription je mesečna pretplata. Tip nije deo paketa billing, nego se
+// registruje u registar billing.Incomes u funkciji init, bez izmene paketa.
+type Subscription struct {
+	Plan    string
+	Monthly billing.Money
+	Since   time.Time
+}
+
+func (s Subscription) Source() string { return s.Plan }
+
+func (s Subscription) LineItems(p billing.Period) []billing.LineItem {
+	if !s.Since.Before(p.End) {
+		return nil
+	}
+	return []billing.LineItem{{
+		Source:      s.Plan,
+		Description: "monthly fee",
+		Quantity:    billing.Unit,
+		UnitPrice:   s.Monthly,
+	}}
+}
+
+func init() {
+	poly.Register[billing.Income, Subscription](&billing.Incomes, "subscription")
+}
+
+type billingDocument struct {
+	Number   string
+	Currency string
+	Incomes  json.RawMessage
+}
+
+func loadInvoice(data []byte, p billing.Period) (*billing.Invoice, error) {
+	var doc billingDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	c, ok := billing.LookupCurrency(doc.Currency)
+	if !ok {
+		return nil, fmt.Errorf("$.currency: unknown currency %q", doc.Currency)
+	}
+	incomes, err := billing.Incomes.DecodeList(doc.Incomes, "$.incomes")
+	if err != nil {
+		return nil, err
+	}
+	return billing.Generate(incomes, p, billing.Options{Number: doc.Number, Currency: c})
+}
+
+func billingFromJSON() {
+
+	fmt.Println("\n --- billingFromJSON ---")
+
+	fmt.Println("types:", billing.Incomes.Names())
+
+	inv, err := loadInvoice([]byte(`{
+		"number": "2024-03-002",
+		"currency": "USD",
+		"incomes": [
+			{"type": "fixed", "project": "Project 1", "amount": "USD 5000", "date": "2024-03-15T00:00:00Z"},
+			{"type": "time-and-material", "project": "Project 3", "hourlyRate": "USD 25.00",
+				"entries": [{"date": "2024-03-04T00:00:00Z", "hours": 8}, {"date": "2024-03-05T00:00:00Z", "hours": 7.5}]},
+			{"type": "advertisement", "name": "Popup Ad", "cpc": "USD 0.05", "clicks": [{"date": "2024-03-20T00:00:00Z", "count": 750}]},
+			{"type": "subscription", "plan": "Support Gold", "monthly": "USD 199.99", "since": "2024-01-01T00:00:00Z"}
+		]
+	}`), billing.Month(2024, time.March, time.UTC))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	inv.WriteText(os.Stdout)
+}
+
+/*
+Program ispisuje:
+
+	>> types: [advertisement fixed subscription time-and-material]
+	>> Invoice 2024-03-002, 2024-03-01 - 2024-03-31
+	>>   Project 1, fixed price             1  USD 5000.00  USD 5000.00
+	>>   Project 3, hours worked       15.5 h    USD 25.00   USD 387.50
+	>>   Popup Ad, clicks           750 click     USD 0.05    USD 37.50
+	>>   Support Gold, monthly fee          1   USD 199.99   USD 199.99
+	>>   Subtotal                                           USD 5624.99
+	>>   Total                                              USD 5624.99
+
+Tip Subscription je dodat u registar iz ovog paketa, a dekoder i
+billing.Generate ga koriste kao i ugrađene tipove.
+
+Greške se prijavljuju sve odjednom, sa JSON putanjom svakog neispravnog
+polja:
+*/
+
+func billingJSONErrors() {
+
+	fmt.Println("\n --- billingJSONErrors ---")
+
+	_, err := loadInvoice([]byte(`{
+		"number": "2024-03-003",
+		"currency": "USD",
+		"incomes": [
+			{"type": "fixed", "project": "Project 1", "amount": "5000 USD", "date": "2024-03-15T00:00:00Z"},
+			{"type": "time-and-material", "project": "Project 3", "hourlyRate": "USD 25.005",
+				"entries": [{"date": "2024-03-04T00:00:00Z", "hours": 7.5555}]},
+			{"type": "advertisement", "name": "Popup Ad", "cpc": "XYZ 0.05", "clicks": 750},
+			{"type": "donation", "amount": "USD 100"}
+		]
+	}`), billing.Month(2024, time.March, time.UTC))
+
+	var verr *validation.Errors
+	if errors.As(err, &verr) {
+		verr.WriteText(os.Stdout)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> $.incomes[0].amount [invalid]: billing: unknown currency "5000"
+	>> $.incomes[1].entries[0].hours [invalid]: billing: invalid quantity 7.5555, want a number with at most 3 decimals
+	>> $.incomes[1].hourlyRate [invalid]: billing: invalid amount "25.005" for USD
+	>> $.incomes[2].clicks [invalid]: expected []billing.Clicks, got number
+	>> $.incomes[2].cpc [invalid]: billing: unknown currency "XYZ"
+	>> $.incomes[3].type [unknown_type]: unknown type "donation", one of advertisement, fixed, subscription, time-and-material
+
+Putanja vodi do same neispravne vrednosti, i kada je duboko u objektu: sati
+prvog unosa su "entries[0].hours", a ne ceo niz "entries". Pošto registar
+zna tip TimeAndMaterial, poly prati polja i elemente nizova tog tipa sve dok
+ne stigne do vrednosti koja ne može da se dekodira.
+*/
+
+func BillingFunc() {
+
+	fmt.Println("\n --- Billing Func ---")
+
+	billingLineItems()
+	billingInvoice()
+	billingCurrency()
+	billingFromJSON()
+	billingJSONErrors()
+}
@@ -720,6 +720,9 @@ novi "Advertisement" tip takođe implementirao "Income" interfejs, mogli smo da
 ga dodamo u "incomeStreams" isečak. "calculateNetIncome" funkcija takođe
 radi bez ikakvih izmena jer može da poziva metode tipa "Advertisment",
 calculate() i source().
+
+Obračun sa valutom, periodima, popustima i porezima, na istom principu, je u
+lekciji "invoice.go".
 */
 
 func OOPFunc() {
@@ -0,0 +1,89 @@
+// Package cleanup collects the cleanup steps of a function, such as closing
+// files, and runs them in reverse order, like deferred calls. Unlike a
+// plain defer, their errors are not lost: they are joined into the error
+// the function returns.
+package cleanup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Group is a list of cleanup functions. The zero value is empty and ready
+// to use. A Group is meant to be used by a single goroutine.
+//
+// The usual pattern is
+//
+//	func f() (err error) {
+//		var cleanups cleanup.Group
+//		defer cleanups.RunInto(&err)
+//
+//		r, err := os.Open(name)
+//		if err != nil {
+//			return err
+//		}
+//		cleanups.Add(r.Close)
+//		...
+//	}
+type Group struct {
+	fns []func() error
+}
+
+// Add registers fn to be called by Run. Functions run in the reverse order
+// of registration.
+func (g *Group) Add(fn func() error) {
+	g.fns = append(g.fns, fn)
+}
+
+// AddCloser registers c.Close.
+func (g *Group) AddCloser(c io.Closer) {
+	g.Add(c.Close)
+}
+
+// AddFunc registers fn, which cannot fail.
+func (g *Group) AddFunc(fn func()) {
+	g.Add(func() error {
+		fn()
+		return nil
+	})
+}
+
+// Cancel forgets every registered function. It is called on the success
+// path of a function that hands the resources over to its caller, so that
+// they are cleaned up only when the function fails.
+func (g *Group) Cancel() {
+	g.fns = nil
+}
+
+// Run calls the registered functions, last registered first, and returns
+// their errors joined. Every function is called even if an earlier one
+// fails or panics; a panic is returned as an error. The group is empty
+// afterwards.
+func (g *Group) Run() error {
+	var errs []error
+	for i := len(g.fns) - 1; i >= 0; i-- {
+		if err := call(g.fns[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	g.fns = nil
+	return errors.Join(errs...)
+}
+
+// RunInto calls Run and joins its error into *errp, after the error already
+// there. It is meant to be deferred in a function with a named error result.
+func (g *Group) RunInto(errp *error) {
+	if err := g.Run(); err != nil {
+		*errp = errors.Join(*errp, err)
+	}
+}
+
+func call(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cleanup: panic: %v", r)
+		}
+	}()
+	return fn()
+}
@@ -46,9 +46,12 @@ ako je poluprečnik negativan.
 package de
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"learngo/11-deferAndError/validation"
 	"math"
+	"os"
 )
 
 func circleArea(radius float64) (float64, error) {
@@ -411,6 +414,168 @@ vam to kao vežbu da shvatite kako da koristite ovu strategiju da biste pružili
 više informacija o našim prilagođenim greškama.
 */
 
+/*
+Više grešaka odjednom
+---------------------
+rectArea iz prethodnog programa pronalazi oba problema, ali ih spaja u jedan
+string i vraća jednu grešku. Pozivalac zato mora da zna za metode
+lengthNegative i widthNegative da bi saznao šta je pogrešno, a ne može ni da
+pošalje grešku dalje u obliku koji druga strana (na primer web klijent) može
+da obradi.
+
+Paket "learngo/11-deferAndError/validation" ima tip Errors koji skuplja sve
+probleme. Svaki unos (FieldError) ima:
+
+- Field - putanju do polja, na primer "length" ili "shapes[2].radius",
+- Code - kod namenjen programima, na primer "negative",
+- Err - samu grešku, bilo kog tipa.
+
+	var errs validation.Errors
+	errs.Add("length", "negative", err)
+	return errs.Err()
+
+Errors.Err vraća nil ako nema unosa. Vraćanje samog &errs bi bila greška:
+pokazivač na prazan Errors u interfejsu error nije nil.
+
+Errors ima metodu "Unwrap() []error", istu kao greška koju vraća errors.Join,
+pa errors.Is i errors.As pregledaju svaki unos. Errors se ispisuje kao jedan
+red (Error), kao tekst sa jednim unosom po redu (WriteText) ili kao JSON
+(json.Marshal).
+*/
+
+func rectArea2(length, width float64) (float64, error) {
+	var errs validation.Errors
+	if length < 0 {
+		errs.Add("length", "negative", &areaError2{err: "length is negative", length: length})
+	}
+	if width < 0 {
+		errs.Add("width", "negative", &areaError2{err: "width is negative", width: width})
+	}
+	if err := errs.Err(); err != nil {
+		return 0, err
+	}
+	return length * width, nil
+}
+
+func errCustomErrorMulti() {
+
+	fmt.Println("\n --- errCustomErrorMulti ---")
+	area, err := rectArea2(-5.0, -9.0)
+	if err == nil {
+		fmt.Println("area of rect", area)
+		return
+	}
+	fmt.Println(err)
+
+	var verr *validation.Errors
+	if errors.As(err, &verr) {
+		for _, fe := range verr.Entries() {
+			var areaError *areaError2
+			if errors.As(fe, &areaError) {
+				switch {
+				case areaError.lengthNegative():
+					fmt.Printf("%s: length %0.2f is less than zero\n", fe.Field, areaError.length)
+				case areaError.widthNegative():
+					fmt.Printf("%s: width %0.2f is less than zero\n", fe.Field, areaError.width)
+				}
+			}
+		}
+		verr.WriteText(os.Stdout)
+		b, _ := json.Marshal(verr)
+		fmt.Println(string(b))
+	}
+
+	_, err = rectArea2(5, 9)
+	fmt.Println("no problems:", err == nil)
+}
+
+/*
+Program ispisuje:
+
+	>> length: length is negative; width: width is negative
+	>> length: length -5.00 is less than zero
+	>> width: width -9.00 is less than zero
+	>> length [negative]: length is negative
+	>> width [negative]: width is negative
+	>> [{"field":"length","code":"negative","message":"length is negative"},{"field":"width","code":"negative","message":"width is negative"}]
+	>> no problems: true
+
+Ugnježdene putanje
+------------------
+Kada proveravamo niz oblika, svaki oblik proverava svoja polja, a pozivalac
+njegove greške dodaje metodom AddError, sa putanjom do oblika ispred. AddError
+prihvata bilo koju grešku: *Errors i *FieldError zadržavaju kodove, rezultat
+errors.Join se rastavlja na delove, a svaka druga greška postaje jedan unos
+bez koda.
+*/
+
+type shape struct {
+	radius        float64 // krug ako je length == 0
+	length, width float64
+}
+
+var errNoSize = errors.New("shape has no size")
+
+func checkShapes(shapes []shape) error {
+	var errs validation.Errors
+	for i, s := range shapes {
+		path := fmt.Sprintf("shapes[%d]", i)
+		switch {
+		case s.length != 0 || s.width != 0:
+			_, err := rectArea2(s.length, s.width)
+			errs.AddError(path, err)
+		case s.radius != 0:
+			_, err := circleArea3(s.radius)
+			errs.AddError(validation.Join(path, "radius"), err)
+		default:
+			errs.Add(path, "empty", errNoSize)
+		}
+	}
+	return errs.Err()
+}
+
+func errCustomErrorNested() {
+
+	fmt.Println("\n --- errCustomErrorNested ---")
+	err := checkShapes([]shape{
+		{radius: 2},
+		{radius: -20},
+		{},
+		{length: 3, width: -1},
+	})
+
+	// errors.Join dodaje još jednu grešku, a Is i As i dalje nalaze sve.
+	err = errors.Join(err, errors.New("input was truncated"))
+
+	var verr *validation.Errors
+	if errors.As(err, &verr) {
+		verr.WriteText(os.Stdout)
+	}
+	var circleErr *areaError
+	if errors.As(err, &circleErr) {
+		fmt.Println("circle error, radius:", circleErr.radius)
+	}
+	fmt.Println("has empty shape:", errors.Is(err, errNoSize))
+
+	var all validation.Errors
+	all.AddError("request", err)
+	fmt.Println(all.Len(), "problems:", all.Error())
+}
+
+/*
+Program ispisuje:
+
+	>> shapes[1].radius: radius -20.00: radius is negative
+	>> shapes[2] [empty]: shape has no size
+	>> shapes[3].width [negative]: width is negative
+	>> circle error, radius: -20
+	>> has empty shape: true
+	>> 4 problems: request.shapes[1].radius: radius -20.00: radius is negative; request.shapes[2]: shape has no size; request.shapes[3].width: width is negative; request: input was truncated
+
+circleArea3 vraća *areaError, koji ne zna ništa o paketu validation, pa je
+njegov unos bez koda, ali ga errors.As i dalje pronalazi.
+*/
+
 func CustomError() {
 	fmt.Println("\n --- Custom Error ---")
 
@@ -418,4 +583,6 @@ func CustomError() {
 	errCustomErrorf()
 	errCustomErrorStruct()
 	errCustomErrorStructMethod()
+	errCustomErrorMulti()
+	errCustomErrorNested()
 }
@@ -11,7 +11,9 @@ primera.
 package de
 
 import (
+	"errors"
 	"fmt"
+	"learngo/11-deferAndError/cleanup"
 	"sync"
 	"time"
 )
@@ -291,6 +293,161 @@ da dodamo još jednu putanju povratka metodi area koristeći novi if uslov. Ako
 poziv metode wg.Done() nije bio odložen, moramo biti oprezni i osigurati da
 pozovemo wg.Done() ovu novu putanju povratka. Ali pošto je poziv metode wg.Done()
 odložen, ne moramo da brinemo o dodavanju novih putanja povratka ovoj metodi.
+
+Greške odloženih poziva
+-----------------------
+Odložen poziv ne može da vrati vrednost. Zato se "defer f.Close()" često piše
+iako Close može da vrati grešku (na primer, kod pisanja, kada se tek pri
+zatvaranju podaci upisuju na disk), i ta greška se tiho gubi. Drugi čest
+pogrešan izbor je log.Fatal u odloženoj funkciji, koji prekida ceo program.
+
+Paket "learngo/11-deferAndError/cleanup" čuva korake čišćenja u grupi i
+izvršava ih kao stek defer, LIFO, ali njihove greške spaja (errors.Join) u
+imenovani povratni rezultat funkcije:
+
+	func f() (err error) {
+		var cleanups cleanup.Group
+		defer cleanups.RunInto(&err)
+		...
+		cleanups.Add(r.Close)
+		...
+	}
+
+Izvršavaju se svi koraci, i kada neki od njih vrati grešku ili izazove
+paniku. Funkcije sa zatvaranjem datoteka u lekciji "14-files/readFiles.go"
+pisane su ovako.
+*/
+
+type resource struct {
+	name string
+	fail bool
+}
+
+func openResource(name string, fail bool) (*resource, error) {
+	if name == "" {
+		return nil, errors.New("open: empty name")
+	}
+	fmt.Println("open", name)
+	return &resource{name: name, fail: fail}, nil
+}
+
+func (r *resource) Close() error {
+	fmt.Println("close", r.name)
+	if r.fail {
+		return fmt.Errorf("close %s: disk full", r.name)
+	}
+	return nil
+}
+
+func copyResources() (err error) {
+
+	var cleanups cleanup.Group
+	defer cleanups.RunInto(&err)
+
+	src, err := openResource("source", false)
+	if err != nil {
+		return err
+	}
+	cleanups.AddCloser(src)
+
+	dst, err := openResource("destination", true)
+	if err != nil {
+		return err
+	}
+	cleanups.AddCloser(dst)
+
+	cleanups.AddFunc(func() { fmt.Println("flush log") })
+
+	fmt.Println("copy", src.name, "to", dst.name)
+	return errors.New("copy: short write")
+}
+
+func deferCleanup() {
+
+	fmt.Println("\n --- deferCleanup ---")
+
+	err := copyResources()
+	fmt.Println("error:")
+	fmt.Println(err)
+}
+
+/*
+Program ispisuje:
+
+	>> open source
+	>> open destination
+	>> copy source to destination
+	>> flush log
+	>> close destination
+	>> close source
+	>> error:
+	>> copy: short write
+	>> close destination: disk full
+
+Koraci su izvršeni obrnutim redosledom od prijavljivanja, a err sadrži i
+grešku kopiranja i grešku zatvaranja, jednu ispod druge.
+
+Otkazivanje na uspešnoj putanji
+...............................
+Funkcija koja otvara više resursa i vraća ih pozivaocu treba da ih zatvori
+samo ako ne uspe. Ako druga datoteka ne može da se otvori, prva mora da se
+zatvori, ali ako su obe otvorene, zatvaranje je posao pozivaoca. Metoda Cancel
+briše sve prijavljene korake, pa se poziva neposredno pre uspešnog povratka.
+*/
+
+func openPair(a, b string) (ra, rb *resource, err error) {
+
+	var cleanups cleanup.Group
+	defer cleanups.RunInto(&err)
+
+	ra, err = openResource(a, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanups.AddCloser(ra)
+
+	rb, err = openResource(b, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanups.AddCloser(rb)
+
+	cleanups.Cancel() // uspeh, resurse zatvara pozivalac
+	return ra, rb, nil
+}
+
+func deferCleanupCancel() {
+
+	fmt.Println("\n --- deferCleanupCancel ---")
+
+	if _, _, err := openPair("input", ""); err != nil {
+		fmt.Println("error:", err)
+	}
+
+	ra, rb, err := openPair("input", "output")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	fmt.Println("using", ra.name, "and", rb.name)
+	rb.Close()
+	ra.Close()
+}
+
+/*
+Program ispisuje:
+
+	>> open input
+	>> close input
+	>> error: open: empty name
+	>> open input
+	>> open output
+	>> using input and output
+	>> close output
+	>> close input
+
+Kada otvaranje "output" ne uspe, "input" je zatvoren. Kada uspe, oba resursa
+zatvara deferCleanupCancel.
 */
 
 func DeferFunc() {
@@ -302,4 +459,6 @@ func DeferFunc() {
 	deferStack()
 	deferWithout()
 	deferWith()
+	deferCleanup()
+	deferCleanupCancel()
 }
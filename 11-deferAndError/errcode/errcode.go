@@ -0,0 +1,262 @@
+// Package errcode is a registry of error kinds. Every kind has a stable code,
+// a severity, message templates in several languages and a link to its
+// documentation, so that programs can react to the code while people read a
+// message in their own language.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Severity tells how serious an error is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	SeverityFatal
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "fatal"
+}
+
+// Languages used by the message templates.
+const (
+	English = "en"
+	Serbian = "sr"
+)
+
+// DefaultLanguage is used by Error and when a kind has no template in the
+// requested language.
+const DefaultLanguage = English
+
+// Definition describes a kind of error.
+type Definition struct {
+	Code     string // stable identifier, upper case, e.g. "GEOM_NEGATIVE_RADIUS"
+	Severity Severity
+	Doc      string // where the error is documented
+
+	// Messages maps a language to a template. "{name}" in a template is
+	// replaced by the parameter name, "{name:%.2f}" formats it with the
+	// given verb. A template in DefaultLanguage is required.
+	Messages map[string]string
+}
+
+// Kind is a registered kind of error. It is itself an error, so that
+// errors.Is(err, kind) reports whether err is of that kind.
+type Kind struct {
+	def    Definition
+	params map[string][]string // language -> parameter names
+}
+
+func (k *Kind) Code() string       { return k.def.Code }
+func (k *Kind) Severity() Severity { return k.def.Severity }
+func (k *Kind) Doc() string        { return k.def.Doc }
+
+// Template returns the template of lang, or of DefaultLanguage if the kind
+// has none in lang.
+func (k *Kind) Template(lang string) string {
+	if t, ok := k.def.Messages[lang]; ok {
+		return t
+	}
+	return k.def.Messages[DefaultLanguage]
+}
+
+// Languages returns the languages the kind has templates for, sorted.
+func (k *Kind) Languages() []string {
+	langs := make([]string, 0, len(k.def.Messages))
+	for l := range k.def.Messages {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Params returns the names of the parameters used by the templates, in
+// the order they first appear in the DefaultLanguage template and then in
+// the templates of the other languages, taken in sorted order.
+func (k *Kind) Params() []string {
+	var names []string
+	seen := map[string]bool{}
+	langs := append([]string{DefaultLanguage}, k.Languages()...)
+	for _, l := range langs {
+		for _, n := range k.params[l] {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	return names
+}
+
+func (k *Kind) Error() string { return k.def.Code }
+
+// P holds the parameters of an error.
+type P map[string]any
+
+// New returns an error of kind k with the given parameters. It panics if
+// params does not have exactly the names returned by Params, because a
+// misspelled name is a bug that would otherwise only show up as "{name?}"
+// in a message.
+func (k *Kind) New(params P) *Error {
+	k.checkParams(params)
+	return &Error{Kind: k, Params: params}
+}
+
+// Wrap is New with a cause, which errors.Is and errors.As also see.
+func (k *Kind) Wrap(cause error, params P) *Error {
+	k.checkParams(params)
+	return &Error{Kind: k, Params: params, Cause: cause}
+}
+
+func (k *Kind) checkParams(params P) {
+	want := k.Params()
+	for _, n := range want {
+		if _, ok := params[n]; !ok {
+			panic(fmt.Sprintf("errcode: %s: missing parameter %q", k.def.Code, n))
+		}
+	}
+	if len(params) == len(want) {
+		return
+	}
+	for n := range params {
+		if !slices.Contains(want, n) {
+			panic(fmt.Sprintf("errcode: %s: unknown parameter %q", k.def.Code, n))
+		}
+	}
+}
+
+// Error is an error of a registered kind.
+type Error struct {
+	Kind   *Kind
+	Params P
+	Cause  error
+}
+
+// Error returns the code and the message in DefaultLanguage.
+func (e *Error) Error() string {
+	msg := e.Kind.def.Code + ": " + e.Message(DefaultLanguage)
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+// Message renders the template of lang with the parameters of e.
+func (e *Error) Message(lang string) string {
+	return placeholder.ReplaceAllStringFunc(e.Kind.Template(lang), func(m string) string {
+		name, verb := parsePlaceholder(m)
+		v, ok := e.Params[name]
+		if !ok {
+			return "{" + name + "?}"
+		}
+		return fmt.Sprintf(verb, v)
+	})
+}
+
+// Is reports whether target is the kind of e, or an *Error of the same kind.
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case *Kind:
+		return t == e.Kind
+	case *Error:
+		return t.Kind == e.Kind
+	}
+	return false
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or "".
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind.def.Code
+	}
+	return ""
+}
+
+var placeholder = regexp.MustCompile(`\{[a-zA-Z_][a-zA-Z0-9_]*(:%[^{}]+)?\}`)
+var validCode = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+
+func parsePlaceholder(m string) (name, verb string) {
+	name, verb, ok := strings.Cut(m[1:len(m)-1], ":")
+	if !ok {
+		verb = "%v"
+	}
+	return name, verb
+}
+
+var (
+	mu    sync.RWMutex
+	kinds = map[string]*Kind{}
+)
+
+// Register adds a kind to the registry and returns it. It is meant to be
+// called when initializing package variables and panics if the definition
+// is invalid or the code is already taken.
+func Register(def Definition) *Kind {
+	if !validCode.MatchString(def.Code) {
+		panic(fmt.Sprintf("errcode: invalid code %q", def.Code))
+	}
+	if _, ok := def.Messages[DefaultLanguage]; !ok {
+		panic(fmt.Sprintf("errcode: %s has no %q message", def.Code, DefaultLanguage))
+	}
+
+	k := &Kind{def: def, params: map[string][]string{}}
+	k.def.Messages = make(map[string]string, len(def.Messages))
+	for lang, t := range def.Messages {
+		k.def.Messages[lang] = t
+		for _, m := range placeholder.FindAllString(t, -1) {
+			name, _ := parsePlaceholder(m)
+			k.params[lang] = append(k.params[lang], name)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := kinds[def.Code]; ok {
+		panic(fmt.Sprintf("errcode: %s registered twice", def.Code))
+	}
+	kinds[def.Code] = k
+	return k
+}
+
+// Lookup returns the kind registered under code.
+func Lookup(code string) (*Kind, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	k, ok := kinds[code]
+	return k, ok
+}
+
+// All returns every registered kind, sorted by code.
+func All() []*Kind {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]*Kind, 0, len(kinds))
+	for _, k := range kinds {
+		all = append(all, k)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].def.Code < all[j].def.Code })
+	return all
+}
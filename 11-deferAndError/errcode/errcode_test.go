@@ -0,0 +1,87 @@
+package errcode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testKind = Register(Definition{
+	Code:     "TEST_RANGE",
+	Severity: SeverityError,
+	Messages: map[string]string{
+		Serbian: "{value} nije između {max} i {min}, jedinica {unit}",
+		English: "{value:%.1f} is not between {min} and {max}",
+		"de":    "{unit}: {value} nicht zwischen {min} und {max}",
+	},
+})
+
+func TestParams(t *testing.T) {
+	want := []string{"value", "min", "max", "unit"}
+	if got := testKind.Params(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Params = %v, want %v", got, want)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	cause := errors.New("cause")
+	err := testKind.Wrap(cause, P{"value": 7.25, "min": 1, "max": 5, "unit": "m"})
+	if got, want := err.Error(), "TEST_RANGE: 7.2 is not between 1 and 5: cause"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	if got, want := err.Message(Serbian), "7.25 nije između 5 i 1, jedinica m"; got != want {
+		t.Errorf("Message(sr) = %q, want %q", got, want)
+	}
+	if got := err.Message("fr"); !strings.HasPrefix(got, "7.2 is not") {
+		t.Errorf("Message(fr) = %q, want the English message", got)
+	}
+	if !errors.Is(err, testKind) || !errors.Is(err, cause) || CodeOf(err) != "TEST_RANGE" {
+		t.Errorf("err does not match its kind, its cause or its code")
+	}
+}
+
+func TestNewChecksParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params P
+		panic  string
+	}{
+		{"misspelled", P{"value": 1, "min": 1, "max": 2, "units": "m"}, `missing parameter "unit"`},
+		{"missing", P{"value": 1, "min": 1, "max": 2}, `missing parameter "unit"`},
+		{"unknown", P{"value": 1, "min": 1, "max": 2, "unit": "m", "step": 1}, `unknown parameter "step"`},
+		{"nil", nil, `missing parameter "value"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r, _ := recover().(string)
+				if !strings.Contains(r, tt.panic) {
+					t.Errorf("New panicked with %q, want %q", r, tt.panic)
+				}
+			}()
+			testKind.New(tt.params)
+		})
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		def  Definition
+	}{
+		{"bad code", Definition{Code: "bad-code", Messages: map[string]string{English: "x"}}},
+		{"no english", Definition{Code: "TEST_NO_EN", Messages: map[string]string{Serbian: "x"}}},
+		{"twice", Definition{Code: "TEST_RANGE", Messages: map[string]string{English: "x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register did not panic")
+				}
+			}()
+			Register(tt.def)
+		})
+	}
+}
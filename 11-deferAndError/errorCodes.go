@@ -0,0 +1,322 @@
+/*
+Kodovi grešaka
+==============
+
+Do sada su naše greške bile tekst na engleskom, na primer
+
+	>> Area calculation failed, radius -20.00 is less than zero
+	>> sum error: a can't be eq (-b)
+
+Tekst je namenjen ljudima, ali se lako menja: ako ispravimo slovnu grešku ili
+prevedemo poruku na srpski, pokvariće se svaki program koji je poruku
+poredio. Zato veći programi i servisi svakoj vrsti greške daju i stalni kod,
+na primer GEOM_NEGATIVE_RADIUS. Kod se nikada ne menja, programi (i ljudi u
+pretrazi dokumentacije) se oslanjaju na njega, a poruka može da se prevodi i
+popravlja.
+
+Paket "learngo/11-deferAndError/errcode" je registar takvih vrsta grešaka.
+Svaka vrsta (Kind) ima:
+
+- Code - stalni kod, velikim slovima, reči razdvojene sa "_",
+- Severity - ozbiljnost: info, warning, error ili fatal,
+- Messages - šablon poruke za svaki jezik, sa imenovanim parametrima,
+- Doc - gde je greška opisana.
+
+Vrste se registruju pri inicijalizaciji paketa:
+
+	var errNegativeRadius = errcode.Register(errcode.Definition{
+		Code:     "GEOM_NEGATIVE_RADIUS",
+		Severity: errcode.SeverityError,
+		Messages: map[string]string{
+			errcode.English: "radius {radius:%0.2f} is less than zero",
+			errcode.Serbian: "poluprečnik {radius:%0.2f} je manji od nule",
+		},
+	})
+
+U šablonu "{radius}" se zamenjuje parametrom radius, a "{radius:%0.2f}" ga
+formatira kao fmt.Sprintf("%0.2f", radius). Register paniči ako je kod već
+zauzet ili nema engleske poruke. To je greška programera, koja se otkriva
+čim se program pokrene, isto kao kod regexp.MustCompile.
+
+Grešku pravi metoda New vrste:
+
+	return 0, errNegativeRadius.New(errcode.P{"radius": radius})
+
+Vrsta je i sama greška, pa errors.Is(err, errNegativeRadius) proverava da li
+je err te vrste, čak i kada je omotana pomoću fmt.Errorf i %w.
+*/
+
+package de
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"learngo/11-deferAndError/errcode"
+	"learngo/11-deferAndError/validation"
+	"math"
+	"os"
+	"text/tabwriter"
+)
+
+var (
+	errNegativeRadius = errcode.Register(errcode.Definition{
+		Code:     "GEOM_NEGATIVE_RADIUS",
+		Severity: errcode.SeverityError,
+		Doc:      "11-deferAndError/customError.go",
+		Messages: map[string]string{
+			errcode.English: "area calculation failed, radius {radius:%0.2f} is less than zero",
+			errcode.Serbian: "računanje površine nije uspelo, poluprečnik {radius:%0.2f} je manji od nule",
+		},
+	})
+	errNegativeLength = errcode.Register(errcode.Definition{
+		Code:     "GEOM_NEGATIVE_LENGTH",
+		Severity: errcode.SeverityError,
+		Doc:      "11-deferAndError/customError.go",
+		Messages: map[string]string{
+			errcode.English: "length {length:%0.2f} is less than zero",
+			errcode.Serbian: "dužina {length:%0.2f} je manja od nule",
+		},
+	})
+	errNegativeWidth = errcode.Register(errcode.Definition{
+		Code:     "GEOM_NEGATIVE_WIDTH",
+		Severity: errcode.SeverityError,
+		Doc:      "11-deferAndError/customError.go",
+		Messages: map[string]string{
+			errcode.English: "width {width:%0.2f} is less than zero",
+			errcode.Serbian: "širina {width:%0.2f} je manja od nule",
+		},
+	})
+	errSumZero = errcode.Register(errcode.Definition{
+		Code:     "MATH_SUM_ZERO",
+		Severity: errcode.SeverityFatal,
+		Doc:      "11-deferAndError/panicRecover.go",
+		Messages: map[string]string{
+			errcode.English: "sum error: a ({a}) can't be equal to -b ({b})",
+			errcode.Serbian: "greška sabiranja: a ({a}) ne sme biti jednako -b ({b})",
+		},
+	})
+	errDivideByZero = errcode.Register(errcode.Definition{
+		Code:     "MATH_DIVIDE_BY_ZERO",
+		Severity: errcode.SeverityFatal,
+		Doc:      "11-deferAndError/panicRecover.go",
+		Messages: map[string]string{
+			errcode.English: "div error: divide {a} by 0",
+			errcode.Serbian: "greška deljenja: deljenje {a} nulom",
+		},
+	})
+	errNoRecord = errcode.Register(errcode.Definition{
+		Code:     "DB_NO_ROWS",
+		Severity: errcode.SeverityWarning,
+		Doc:      "11-deferAndError/wrapError.go",
+		Messages: map[string]string{
+			errcode.English: "no rows found in {table}",
+			errcode.Serbian: "u tabeli {table} nema redova",
+		},
+	})
+)
+
+func circleArea4(radius float64) (float64, error) {
+	if radius < 0 {
+		return 0, errNegativeRadius.New(errcode.P{"radius": radius})
+	}
+	return math.Pi * radius * radius, nil
+}
+
+func errCodeNew() {
+
+	fmt.Println("\n --- errCodeNew ---")
+
+	_, err := circleArea4(-20)
+	fmt.Println(err)
+
+	err = fmt.Errorf("shape 2: %w", err)
+	fmt.Println(err)
+	fmt.Println("is negative radius:", errors.Is(err, errNegativeRadius))
+	fmt.Println("is negative length:", errors.Is(err, errNegativeLength))
+
+	var cerr *errcode.Error
+	if errors.As(err, &cerr) {
+		fmt.Println("code:", cerr.Kind.Code())
+		fmt.Println("severity:", cerr.Kind.Severity())
+		fmt.Println("radius:", cerr.Params["radius"])
+		fmt.Println("en:", cerr.Message(errcode.English))
+		fmt.Println("sr:", cerr.Message(errcode.Serbian))
+		fmt.Println("docs:", cerr.Kind.Doc())
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> GEOM_NEGATIVE_RADIUS: area calculation failed, radius -20.00 is less than zero
+	>> shape 2: GEOM_NEGATIVE_RADIUS: area calculation failed, radius -20.00 is less than zero
+	>> is negative radius: true
+	>> is negative length: false
+	>> code: GEOM_NEGATIVE_RADIUS
+	>> severity: error
+	>> radius: -20
+	>> en: area calculation failed, radius -20.00 is less than zero
+	>> sr: računanje površine nije uspelo, poluprečnik -20.00 je manji od nule
+	>> docs: 11-deferAndError/customError.go
+
+Error() uvek vraća englesku poruku sa kodom ispred, jer je namenjena
+logovima, a Message(jezik) poruku za korisnika.
+
+Kodovi i validation.Errors
+--------------------------
+Kod iz registra je dobar i kao Code unosa u validation.Errors, pa i JSON
+koji šaljemo klijentu nosi stalne kodove. Greška sa kodom može da ima i
+uzrok (Wrap), koji errors.Is i errors.As takođe vide.
+*/
+
+func rectArea3(length, width float64) (float64, error) {
+	var errs validation.Errors
+	if length < 0 {
+		errs.Add("length", errNegativeLength.Code(), errNegativeLength.New(errcode.P{"length": length}))
+	}
+	if width < 0 {
+		errs.Add("width", errNegativeWidth.Code(), errNegativeWidth.New(errcode.P{"width": width}))
+	}
+	if err := errs.Err(); err != nil {
+		return 0, err
+	}
+	return length * width, nil
+}
+
+func errCodeWithValidation() {
+
+	fmt.Println("\n --- errCodeWithValidation ---")
+
+	_, err := rectArea3(-5, -9)
+	var verr *validation.Errors
+	if errors.As(err, &verr) {
+		verr.WriteText(os.Stdout)
+		for _, fe := range verr.Entries() {
+			var cerr *errcode.Error
+			if errors.As(fe, &cerr) {
+				fmt.Printf("%s: %s\n", fe.Field, cerr.Message(errcode.Serbian))
+			}
+		}
+	}
+	fmt.Println("is negative width:", errors.Is(err, errNegativeWidth))
+
+	err = errNoRecord.Wrap(errNoRows2, errcode.P{"table": "users"})
+	fmt.Println(err)
+	fmt.Println(errors.Is(err, errNoRows2), errcode.CodeOf(err))
+}
+
+/*
+Program ispisuje:
+
+	>> length [GEOM_NEGATIVE_LENGTH]: GEOM_NEGATIVE_LENGTH: length -5.00 is less than zero
+	>> width [GEOM_NEGATIVE_WIDTH]: GEOM_NEGATIVE_WIDTH: width -9.00 is less than zero
+	>> length: dužina -5.00 je manja od nule
+	>> width: širina -9.00 je manja od nule
+	>> is negative width: true
+	>> DB_NO_ROWS: no rows found in users: no rows found
+	>> true DB_NO_ROWS
+
+WriteText ispisuje kod dva puta: jednom kao Code unosa, a drugi put kao deo
+Error() same greške.
+
+Greške umesto panike
+--------------------
+U programu "recoverGoroutine" funkcije sum i div paniče. Sa registrovanim
+kodovima iste provere mogu da vrate grešku, a ozbiljnost "fatal" kaže
+pozivaocu da nastavak nema smisla.
+*/
+
+func sumChecked(a, b int) (int, error) {
+	if a == -b {
+		return 0, errSumZero.New(errcode.P{"a": a, "b": b})
+	}
+	return a + b, nil
+}
+
+func divChecked(a, b int) (int, error) {
+	if b == 0 {
+		return 0, errDivideByZero.New(errcode.P{"a": a})
+	}
+	return a / b, nil
+}
+
+func errCodeMath() {
+
+	fmt.Println("\n --- errCodeMath ---")
+
+	_, err1 := sumChecked(5, -5)
+	_, err2 := divChecked(5, 0)
+	for _, err := range []error{err1, err2} {
+		var cerr *errcode.Error
+		if errors.As(err, &cerr) {
+			fmt.Printf("%s (%v): %s\n", cerr.Kind.Code(), cerr.Kind.Severity(), cerr.Message(errcode.Serbian))
+		}
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> MATH_SUM_ZERO (fatal): greška sabiranja: a (5) ne sme biti jednako -b (-5)
+	>> MATH_DIVIDE_BY_ZERO (fatal): greška deljenja: deljenje 5 nulom
+
+Spisak kodova
+-------------
+Pošto su sve vrste u registru, spisak kodova za dokumentaciju ne moramo
+pisati ručno, već ga ispisuje komanda:
+
+	$ learngo error-codes [-lang sr]
+*/
+
+// ErrorCodes runs the "error-codes" command, which lists every registered
+// error code with its severity, documentation and message template.
+func ErrorCodes(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("error-codes", flag.ContinueOnError)
+	lang := flags.String("lang", errcode.DefaultLanguage, "language of the messages")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return errors.New("usage: learngo error-codes [-lang en|sr]")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tSEVERITY\tDOC\tMESSAGE")
+	for _, k := range errcode.All() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.Code(), k.Severity(), k.Doc(), k.Template(*lang))
+	}
+	return tw.Flush()
+}
+
+func errCodeList() {
+
+	fmt.Println("\n --- errCodeList ---")
+
+	if err := ErrorCodes([]string{"-lang", "sr"}, os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> CODE                  SEVERITY  DOC                               MESSAGE
+	>> DB_NO_ROWS            warning   11-deferAndError/wrapError.go     u tabeli {table} nema redova
+	>> GEOM_NEGATIVE_LENGTH  error     11-deferAndError/customError.go   dužina {length:%0.2f} je manja od nule
+	>> GEOM_NEGATIVE_RADIUS  error     11-deferAndError/customError.go   računanje površine nije uspelo, poluprečnik {radius:%0.2f} je manji od nule
+	>> GEOM_NEGATIVE_WIDTH   error     11-deferAndError/customError.go   širina {width:%0.2f} je manja od nule
+	>> MATH_DIVIDE_BY_ZERO   fatal     11-deferAndError/panicRecover.go  greška deljenja: deljenje {a} nulom
+	>> MATH_SUM_ZERO         fatal     11-deferAndError/panicRecover.go  greška sabiranja: a ({a}) ne sme biti jednako -b ({b})
+*/
+
+func ErrorCodesFunc() {
+
+	fmt.Println("\n --- Error Codes ---")
+
+	errCodeNew()
+	errCodeWithValidation()
+	errCodeMath()
+	errCodeList()
+}
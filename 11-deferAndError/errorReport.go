@@ -0,0 +1,367 @@
+/*
+Izveštaj o greškama
+===================
+
+Programi u ovom poglavlju greške ispisuju sa fmt.Println("Generic error", err)
+ili prekidaju rad sa log.Fatal. To je dovoljno dok program gledamo, ali ne i
+za program koji radi danima: ispisane poruke niko ne čita, a iz njih se ne vidi
+ni kog su tipa greške u lancu, ni koliko se puta koja greška ponovila.
+
+Paket "learngo/11-deferAndError/report" svaku grešku zapisuje kao jedan JSON
+objekat u jednom redu (format JSON Lines):
+
+	r := report.New(w, report.Options{})
+	...
+	if err != nil {
+		return r.Report(err) // zapisuje i vraća err
+	}
+
+Zapis (report.Record) sadrži:
+
+- time - vreme prijave,
+- message, type i cause - poruku, konkretan tip greške i tip greške na kraju
+  lanca, koja je pravi uzrok,
+- code - kod greške iz paketa errcode, ako ga ima,
+- chain - sve greške lanca, dobijene sa errors.Unwrap, sa tipom i porukom,
+- fields - polja poznatih tipova grešaka pronađenih sa errors.As, kao Op i Path
+  kod *fs.PathError ili IsTimeout i IsTemporary kod *net.DNSError,
+- stack i panic_stack - mesta omotavanja iz paketa stack i trag steka panike
+  iz paketa supervisor.
+
+Tipove čija polja zapisujemo proširujemo sa report.RegisterFields.
+*/
+
+package de
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"learngo/09-conc/clock"
+	"learngo/11-deferAndError/errcode"
+	"learngo/11-deferAndError/fakedns"
+	"learngo/11-deferAndError/report"
+	"learngo/11-deferAndError/supervisor"
+	"learngo/14-files/appender"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func loadConfig(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	return f.Close()
+}
+
+func reportRecord() {
+
+	fmt.Println("\n --- reportRecord ---")
+
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	var buf bytes.Buffer
+	r := report.New(&buf, report.Options{Clock: clk})
+
+	err := r.Report(loadConfig("/etc/learngo/config.json"))
+	fmt.Println("returned:", err)
+
+	var out bytes.Buffer
+	json.Indent(&out, buf.Bytes(), "", "  ")
+	fmt.Println(out.String())
+}
+
+/*
+Program ispisuje:
+
+	>> returned: loading config: open /etc/learngo/config.json: no such file or directory
+	>> {
+	>>   "time": "2024-01-01T12:00:00Z",
+	>>   "message": "loading config: open /etc/learngo/config.json: no such file or directory",
+	>>   "type": "*fmt.wrapError",
+	>>   "cause": "syscall.Errno",
+	>>   "chain": [
+	>>     {
+	>>       "type": "*fmt.wrapError",
+	>>       "message": "loading config: open /etc/learngo/config.json: no such file or directory"
+	>>     },
+	>>     {
+	>>       "type": "*fs.PathError",
+	>>       "message": "open /etc/learngo/config.json: no such file or directory"
+	>>     },
+	>>     {
+	>>       "type": "syscall.Errno",
+	>>       "message": "no such file or directory"
+	>>     }
+	>>   ],
+	>>   "fields": {
+	>>     "*fs.PathError": {
+	>>       "op": "open",
+	>>       "path": "/etc/learngo/config.json"
+	>>     },
+	>>     "syscall.Errno": {
+	>>       "errno": 2,
+	>>       "temporary": false,
+	>>       "timeout": false
+	>>     }
+	>>   }
+	>> }
+
+U datoteci je ovaj zapis jedan red, ovde je uvučen radi čitljivosti. Lanac
+pokazuje ono što smo ranije dobijali pozivima errors.Unwrap, a polja ono što
+smo dobijali sa errors.As.
+
+Polja mrežnih i drugih grešaka
+------------------------------
+Greške DNS pretrage dobijamo od lažnog DNS servera iz lekcije "errors.go".
+Umesto poruka ispisujemo tipove grešaka u lancu, jer poruka DNS greške sadrži
+slučajno izabrane portove. Iz istog razloga izostavljamo polje server, to je
+adresa servera iz sistemske konfiguracije.
+*/
+
+func reportFields() {
+
+	fmt.Println("\n --- reportFields ---")
+
+	srv, err := fakedns.Start(map[string]fakedns.Answer{
+		"slow.golangbot.com": {Kind: fakedns.NoReply},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer srv.Close()
+
+	_, dnsErr := srv.Resolver().LookupHost(context.Background(), "slow.golangbot.com")
+	_, numErr := strconv.Atoi("12a")
+	errs := []error{
+		fmt.Errorf("fetching feed: %w", dnsErr),
+		fmt.Errorf("parsing page size: %w", numErr),
+		errNoRecord.Wrap(errors.New("sql: no rows in result set"), errcode.P{"table": "users"}),
+	}
+
+	for _, err := range errs {
+		rec := report.NewRecord(err, time.Time{})
+		var types []string
+		for _, l := range rec.Chain {
+			types = append(types, l.Type)
+		}
+		fmt.Println(strings.Join(types, " -> "))
+		fmt.Printf("  cause %s, code %q\n", rec.Cause, rec.Code)
+		delete(rec.Fields["*net.DNSError"], "server")
+		b, _ := json.Marshal(rec.Fields)
+		fmt.Println("  fields", string(b))
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> *fmt.wrapError -> *net.DNSError
+	>>   cause *net.DNSError, code ""
+	>>   fields {"*net.DNSError":{"name":"slow.golangbot.com","not_found":false,"temporary":true,"timeout":true}}
+	>> *fmt.wrapError -> *strconv.NumError -> *errors.errorString
+	>>   cause *errors.errorString, code ""
+	>>   fields {"*strconv.NumError":{"func":"Atoi","num":"12a"}}
+	>> *errcode.Error -> *errors.errorString
+	>>   cause *errors.errorString, code "DB_NO_ROWS"
+	>>   fields {"*errcode.Error":{"code":"DB_NO_ROWS","params":{"table":"users"},"severity":"warning"}}
+
+Polja greške iz paketa errcode sadrže kod, ozbiljnost i parametre, pa se iz
+izveštaja može napisati poruka na bilo kom jeziku.
+
+Stek
+----
+Greške iz paketa stack nose mesto svakog omotavanja, a panika uhvaćena paketom
+supervisor ceo trag steka. Oba se zapisuju, tako da se i posle više dana vidi
+gde je greška nastala.
+*/
+
+func reportStack() {
+
+	fmt.Println("\n --- reportStack ---")
+
+	err := webService5()
+	rec := report.NewRecord(err, time.Time{})
+	fmt.Println(rec.Message)
+	for _, f := range rec.Stack {
+		fmt.Println("  ", f.Function, filepath.Base(f.File))
+	}
+
+	err = supervisor.Call(func() error {
+		var m map[string]int
+		m["a"]++
+		return nil
+	})
+	rec = report.NewRecord(err, time.Time{})
+	fmt.Println(rec.Message)
+	fmt.Println("  fields", rec.Fields["*supervisor.PanicError"])
+	fmt.Println("  panic stack recorded:", strings.HasPrefix(rec.PanicStack, "goroutine "))
+}
+
+/*
+Program ispisuje:
+
+	>> webService: getRecord: no rows found in users
+	>>    learngo/11-deferAndError.webService5 wrapError.go
+	>>    learngo/11-deferAndError.getRecord5 wrapError.go
+	>> panic: assignment to entry in nil map
+	>>   fields map[value:assignment to entry in nil map]
+	>>   panic stack recorded: true
+
+Datoteka koja se rotira
+-----------------------
+Izveštaj raste sa svakom greškom. Zato ga ne pišemo direktno u datoteku, nego
+kroz appender.Appender iz lekcije "14-files/writeFiles.go", koji datoteku
+rotira kada pređe zadatu veličinu. Reporter piše ceo red jednim pozivom Write,
+a appender jedan Write nikad ne deli između dve datoteke, pa nijedan zapis ne
+može da bude presečen.
+
+Za pregled zapisa program ima komandu error-report, koja čita date datoteke i
+broji greške po tipu uzroka (-by cause), tipu greške (-by type) ili kodu
+(-by code):
+
+	go run . error-report -by cause errors.jsonl*
+*/
+
+func reportRotate() {
+
+	fmt.Println("\n --- reportRotate ---")
+
+	dir, err := os.MkdirTemp("", "report")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "errors.jsonl")
+
+	a, err := appender.Open(path, appender.Options{MaxSize: 4096})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	r := report.New(a, report.Options{Clock: clk})
+
+	for i := 0; i < 20; i++ {
+		switch {
+		case i%4 == 3:
+			_, err := circleArea4(-float64(i))
+			r.Report(err)
+		case i%2 == 1:
+			_, err := strconv.Atoi("x" + strconv.Itoa(i))
+			r.Report(fmt.Errorf("parsing line %d: %w", i, err))
+		default:
+			r.Report(loadConfig(fmt.Sprintf("/etc/learngo/config%d.json", i)))
+		}
+		clk.Advance(time.Minute)
+	}
+	if err := a.Close(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := r.Err(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	files, _ := a.Rotated()
+	fmt.Println("rotated files:", len(files))
+	files = append(files, path)
+
+	if err := ErrorReport(files, os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println()
+	if err := ErrorReport(append([]string{"-by", "code"}, files...), os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// ErrorReport implements the error-report command. It reads the files
+// written by a report.Reporter and prints the number of errors in each
+// group.
+func ErrorReport(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("error-report", flag.ContinueOnError)
+	by := flags.String("by", "cause", "group errors by cause, type or code")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: learngo error-report [-by cause|type|code] file...")
+	}
+
+	var key func(report.Record) string
+	switch *by {
+	case "cause":
+		key = func(rec report.Record) string { return rec.Cause }
+	case "type":
+		key = func(rec report.Record) string { return rec.Type }
+	case "code":
+		key = func(rec report.Record) string {
+			if rec.Code == "" {
+				return "-"
+			}
+			return rec.Code
+		}
+	default:
+		return fmt.Errorf("error-report: unknown -by %q", *by)
+	}
+
+	var recs []report.Record
+	for _, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		rs, err := report.Read(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		recs = append(recs, rs...)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COUNT\t"+strings.ToUpper(*by)+"\tFIRST\tLAST\tLAST MESSAGE")
+	for _, g := range report.Summarize(recs, key) {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", g.Count, g.Key,
+			g.First.Format(time.DateTime), g.Last.Format(time.DateTime), g.Message)
+	}
+	return tw.Flush()
+}
+
+/*
+Program ispisuje:
+
+	>> rotated files: 2
+	>> COUNT  CAUSE                FIRST                LAST                 LAST MESSAGE
+	>> 10     syscall.Errno        2024-01-01 12:00:00  2024-01-01 12:18:00  loading config: open /etc/learngo/config18.json: no such file or directory
+	>> 5      *errcode.Error       2024-01-01 12:03:00  2024-01-01 12:19:00  GEOM_NEGATIVE_RADIUS: area calculation failed, radius -19.00 is less than zero
+	>> 5      *errors.errorString  2024-01-01 12:01:00  2024-01-01 12:17:00  parsing line 17: strconv.Atoi: parsing "x17": invalid syntax
+	>>
+	>> COUNT  CODE                  FIRST                LAST                 LAST MESSAGE
+	>> 15     -                     2024-01-01 12:00:00  2024-01-01 12:18:00  loading config: open /etc/learngo/config18.json: no such file or directory
+	>> 5      GEOM_NEGATIVE_RADIUS  2024-01-01 12:03:00  2024-01-01 12:19:00  GEOM_NEGATIVE_RADIUS: area calculation failed, radius -19.00 is less than zero
+
+Greške su raspoređene u više datoteka, ali ih komanda broji zajedno.
+*/
+
+func ErrorReportFunc() {
+
+	fmt.Println("\n --- Error Report ---")
+
+	reportRecord()
+	reportFields()
+	reportStack()
+	reportRotate()
+}
@@ -20,11 +20,14 @@ postoji.
 package de
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"learngo/11-deferAndError/fakedns"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 func errFileNotFound() {
@@ -214,7 +217,27 @@ func errAsDNSError() {
 
 	fmt.Println("\n --- errAsDNSError ---")
 
-	addr, err := net.LookupHost("golangbot123.com")
+	srv, err := fakedns.Start(map[string]fakedns.Answer{
+		"golangbot.com":      fakedns.Addrs("104.21.32.1", "2606:4700:3030::6815:2001"),
+		"slow.golangbot.com": {Kind: fakedns.NoReply},
+		"busy.golangbot.com": {Kind: fakedns.ServerFailure},
+		// sva ostala imena, kao golangbot123.com, ne postoje
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer srv.Close()
+	r := srv.Resolver()
+
+	for _, host := range []string{"golangbot123.com", "slow.golangbot.com", "busy.golangbot.com", "golangbot.com"} {
+		lookupHost(r, host)
+	}
+}
+
+func lookupHost(r *net.Resolver, host string) {
+
+	addr, err := r.LookupHost(context.Background(), host)
 	if err != nil {
 		var dnsErr *net.DNSError
 		if errors.As(err, &dnsErr) {
@@ -226,31 +249,53 @@ func errAsDNSError() {
 				fmt.Println("temporary error")
 				return
 			}
-			fmt.Println("Generic DNS error", err)
+			// Ne ispisujemo err, jer sadrži i adresu DNS servera iz
+			// sistemske konfiguracije, koja je na svakom računaru druga.
+			fmt.Printf("Generic DNS error lookup %s: %s\n", dnsErr.Name, dnsErr.Err)
 			return
 		}
 		fmt.Println("Generic error", err)
 		return
 	}
+	// LookupHost ređa adrese po RFC 6724, a taj redosled zavisi od mreže
+	// računara. Sortiramo ih da bi ispis svuda bio isti.
+	slices.Sort(addr)
 	fmt.Println(addr)
 }
 
 /*
-Napomena: DNS pretrage ne rade u Playground-u. Molimo vas da pokrenete ovaj
-program na vašem lokalnom računaru.
-
 U gornjem programu pokušavamo da dobijemo IP adresu nevažećeg imena domena
 golangbot123.com. Dobijamo osnovnu vrednost greške korišćenjem As funkcije i
 konvertovanjem u *net.DNSError. Zatim proveravamo da li je greška nastala zbog
 isteka vremena ili je privremena.
 
-U našem slučaju, greška nije ni privremena niti je nastala zbog isteka vremena
-i stoga će program ispisati,
+Pravi DNS ne bi dao isti rezultat svuda: u Playground-u i na računaru bez
+mreže pretraga ne uspeva iz drugih razloga, a vreme isteka i privremenu grešku
+ne možemo izazvati po želji. Zato pitamo DNS server iz paketa
+"learngo/11-deferAndError/fakedns". On radi u samom programu, na UDP portu
+adrese 127.0.0.1, i odgovara po scenariju:
 
-	>> Generic DNS error lookup golangbot123.com: no such host
+- fakedns.Addrs(...) - ime postoji i ima date adrese,
+- NotFound - ime ne postoji (NXDOMAIN), za sva imena kojih nema u scenariju,
+- ServerFailure - server ima problem (SERVFAIL), što je privremena greška,
+- NoReply - server ne odgovara, pa pretraga istekne.
 
-Ako je greška bila privremena ili je nastala zbog isteka vremena, onda bi se
-odgovarajuća if naredba izvršila i možemo je obraditi na odgovarajući način.
+srv.Resolver() vraća net.Resolver čija funkcija Dial svaki upit šalje tom
+serveru, umesto serverima iz /etc/resolv.conf. Isti net.Resolver se koristi i
+za prave pretrage, net.LookupHost je samo net.DefaultResolver.LookupHost.
+
+Za golangbot123.com greška nije ni privremena niti je nastala zbog isteka
+vremena i stoga će program ispisati prvi red,
+
+	>> Generic DNS error lookup golangbot123.com: no such host
+	>> operation timed out
+	>> temporary error
+	>> [104.21.32.1 2606:4700:3030::6815:2001]
+
+Za slow.golangbot.com i busy.golangbot.com izvršila se odgovarajuća if
+naredba, pa grešku možemo obraditi na odgovarajući način, na primer ponoviti
+pretragu kasnije. Istek vremena je proveren prvi, jer je greška koja je
+nastala zbog isteka vremena uvek i privremena.
 
 3. Direktno poređenje
 -------------------------------------------------------------------------------
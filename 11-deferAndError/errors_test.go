@@ -0,0 +1,75 @@
+package de
+
+import (
+	"io"
+	"learngo/11-deferAndError/fakedns"
+	"os"
+	"strings"
+	"testing"
+)
+
+// capture returns what fn prints to standard output.
+func capture(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestLookupHost(t *testing.T) {
+	srv, err := fakedns.Start(map[string]fakedns.Answer{
+		// IPv6 first, so that only sorting gives the expected output.
+		"golangbot.com":      fakedns.Addrs("2606:4700:3030::6815:2001", "104.21.32.1"),
+		"slow.golangbot.com": {Kind: fakedns.NoReply},
+		"busy.golangbot.com": {Kind: fakedns.ServerFailure},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	r := srv.Resolver()
+
+	tests := []struct {
+		host, want string
+	}{
+		{"golangbot123.com", "Generic DNS error lookup golangbot123.com: no such host"},
+		{"slow.golangbot.com", "operation timed out"},
+		{"busy.golangbot.com", "temporary error"},
+		{"golangbot.com", "[104.21.32.1 2606:4700:3030::6815:2001]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got := capture(t, func() { lookupHost(r, tt.host) })
+			if strings.TrimSpace(got) != tt.want {
+				t.Errorf("lookupHost printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrAsDNSError(t *testing.T) {
+	got := capture(t, errAsDNSError)
+	want := `
+ --- errAsDNSError ---
+Generic DNS error lookup golangbot123.com: no such host
+operation timed out
+temporary error
+[104.21.32.1 2606:4700:3030::6815:2001]
+`
+	if got != want {
+		t.Errorf("errAsDNSError printed\n%s\nwant\n%s", got, want)
+	}
+}
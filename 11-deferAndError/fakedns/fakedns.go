@@ -0,0 +1,304 @@
+// Package fakedns is a DNS server on a loopback UDP port that answers from a
+// script instead of asking the real DNS. A net.Resolver from
+// Server.Resolver sends every query to it, so lookups give the same result
+// on every machine, with or without network.
+//
+// Only A and AAAA queries are supported, which is what LookupHost and
+// LookupIP send. The messages are encoded by hand, following RFC 1035.
+package fakedns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind tells how the server answers a name.
+type Kind int
+
+const (
+	Found         Kind = iota // answer with Answer.Addrs
+	NotFound                  // NXDOMAIN, the name does not exist
+	ServerFailure             // SERVFAIL, the resolver reports a temporary error
+	NoReply                   // no answer at all, the lookup times out
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Found:
+		return "found"
+	case NotFound:
+		return "not found"
+	case ServerFailure:
+		return "server failure"
+	case NoReply:
+		return "no reply"
+	}
+	return "unknown"
+}
+
+// Answer is the scripted answer for a name.
+type Answer struct {
+	Kind  Kind
+	Addrs []netip.Addr // for Found; IPv4 addresses go to A, IPv6 to AAAA queries
+}
+
+// Addrs returns a Found answer with the given addresses. It panics if an
+// address cannot be parsed, since scripts are written by hand.
+func Addrs(addrs ...string) Answer {
+	a := Answer{Kind: Found}
+	for _, s := range addrs {
+		a.Addrs = append(a.Addrs, netip.MustParseAddr(s))
+	}
+	return a
+}
+
+// DefaultTimeout is how long a resolver from Server.Resolver waits for a
+// reply. The system resolver configuration usually says several seconds,
+// which is too long for a NoReply answer in an example.
+const DefaultTimeout = 100 * time.Millisecond
+
+// Server answers DNS queries from a script. Names without an answer are
+// NotFound.
+type Server struct {
+	// Timeout is how long resolvers wait for a reply, default
+	// DefaultTimeout. Set it before the first lookup.
+	Timeout time.Duration
+
+	conn *net.UDPConn
+	done chan struct{}
+
+	mu      sync.Mutex
+	answers map[string]Answer
+	queries int
+}
+
+// Start starts a server on a free loopback port, answering with answers.
+func Start(answers map[string]Answer) (*Server, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{conn: conn, done: make(chan struct{}), answers: make(map[string]Answer)}
+	for name, a := range answers {
+		s.answers[canonical(name)] = a
+	}
+	go s.serve()
+	return s, nil
+}
+
+// canonical returns name in lower case with the trailing dot, the form in
+// which the resolver asks for it.
+func canonical(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// Set changes the answer for name.
+func (s *Server) Set(name string, a Answer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.answers[canonical(name)] = a
+}
+
+// Queries returns the number of queries received so far.
+func (s *Server) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// Close stops the server and waits for it to exit.
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+// Resolver returns a pure Go resolver that sends every query to s, whatever
+// name servers the system is configured with.
+func (s *Server) Resolver() *net.Resolver {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if !strings.HasPrefix(network, "udp") {
+				// Replies are never truncated, so the resolver does not
+				// retry over TCP.
+				return nil, errors.New("fakedns: only udp is supported")
+			}
+			var d net.Dialer
+			c, err := d.DialContext(ctx, "udp", s.Addr())
+			if err != nil {
+				return nil, err
+			}
+			return &conn{UDPConn: c.(*net.UDPConn), timeout: timeout}, nil
+		},
+	}
+}
+
+// conn shortens the deadline the resolver sets. It embeds *net.UDPConn so
+// that the resolver still sees a net.PacketConn and sends plain UDP
+// messages.
+type conn struct {
+	*net.UDPConn
+	timeout time.Duration
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	if limit := time.Now().Add(c.timeout); t.IsZero() || t.After(limit) {
+		t = limit
+	}
+	return c.UDPConn.SetDeadline(t)
+}
+
+func (s *Server) serve() {
+	defer close(s.done)
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return // closed
+		}
+		reply, ok := s.answer(buf[:n])
+		if ok {
+			s.conn.WriteToUDP(reply, addr)
+		}
+	}
+}
+
+// DNS message constants, RFC 1035 section 4.1.
+const (
+	headerLen = 12
+
+	typeA    = 1
+	typeAAAA = 28
+	classIN  = 1
+
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+
+	flagResponse  = 1 << 15
+	flagAuth      = 1 << 10
+	flagRecDesire = 1 << 8
+	flagRecAvail  = 1 << 7
+)
+
+// answer builds the reply to the query msg. ok is false if the query is
+// malformed or the script says not to reply.
+func (s *Server) answer(msg []byte) (reply []byte, ok bool) {
+	q, ok := parseQuery(msg)
+	if !ok {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	s.queries++
+	a, found := s.answers[strings.ToLower(q.name)]
+	s.mu.Unlock()
+	if !found {
+		a = Answer{Kind: NotFound}
+	}
+
+	var rcode uint16
+	var addrs []netip.Addr
+	switch a.Kind {
+	case NoReply:
+		return nil, false
+	case NotFound:
+		rcode = rcodeNXDomain
+	case ServerFailure:
+		rcode = rcodeServFail
+	case Found:
+		for _, addr := range a.Addrs {
+			if q.typ == typeA && addr.Is4() || q.typ == typeAAAA && addr.Is6() && !addr.Is4In6() {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	flags := flagResponse | flagAuth | flagRecAvail | q.flags&flagRecDesire | rcode
+	reply = binary.BigEndian.AppendUint16(nil, q.id)
+	reply = binary.BigEndian.AppendUint16(reply, flags)
+	reply = binary.BigEndian.AppendUint16(reply, 1)                  // questions
+	reply = binary.BigEndian.AppendUint16(reply, uint16(len(addrs))) // answers
+	reply = binary.BigEndian.AppendUint16(reply, 0)                  // authority records
+	reply = binary.BigEndian.AppendUint16(reply, 0)                  // additional records
+	reply = append(reply, q.raw...)
+	for _, addr := range addrs {
+		ip := addr.AsSlice()
+		reply = binary.BigEndian.AppendUint16(reply, 0xc000|headerLen) // pointer to the name in the question
+		reply = binary.BigEndian.AppendUint16(reply, q.typ)
+		reply = binary.BigEndian.AppendUint16(reply, classIN)
+		reply = binary.BigEndian.AppendUint32(reply, 60) // TTL in seconds
+		reply = binary.BigEndian.AppendUint16(reply, uint16(len(ip)))
+		reply = append(reply, ip...)
+	}
+	return reply, true
+}
+
+type query struct {
+	id    uint16
+	flags uint16
+	name  string // with the trailing dot
+	typ   uint16
+	raw   []byte // the question section, copied to the reply
+}
+
+// parseQuery parses the header and the first question of msg. Additional
+// records, such as the EDNS0 options the Go resolver sends, are ignored.
+func parseQuery(msg []byte) (q query, ok bool) {
+	if len(msg) < headerLen {
+		return q, false
+	}
+	q.id = binary.BigEndian.Uint16(msg[0:])
+	q.flags = binary.BigEndian.Uint16(msg[2:])
+	if q.flags&flagResponse != 0 || binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return q, false
+	}
+
+	var name strings.Builder
+	i := headerLen
+	for {
+		if i >= len(msg) {
+			return q, false
+		}
+		l := int(msg[i])
+		i++
+		if l == 0 {
+			break
+		}
+		if l > 63 || i+l > len(msg) {
+			return q, false // compression is not used in questions
+		}
+		name.Write(msg[i : i+l])
+		name.WriteByte('.')
+		i += l
+	}
+	if i+4 > len(msg) {
+		return q, false
+	}
+	q.typ = binary.BigEndian.Uint16(msg[i:])
+	if class := binary.BigEndian.Uint16(msg[i+2:]); class != classIN {
+		return q, false
+	}
+	q.name = name.String()
+	q.raw = msg[headerLen : i+4]
+	return q, true
+}
@@ -0,0 +1,83 @@
+package fakedns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestAnswers(t *testing.T) {
+	srv, err := Start(map[string]Answer{
+		"golangbot.com":      Addrs("104.21.32.1", "2606:4700:3030::6815:2001"),
+		"slow.golangbot.com": {Kind: NoReply},
+		"busy.golangbot.com": {Kind: ServerFailure},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	r := srv.Resolver()
+
+	tests := []struct {
+		host                         string
+		notFound, timeout, temporary bool
+		addrs                        []string
+	}{
+		{host: "golangbot.com", addrs: []string{"104.21.32.1", "2606:4700:3030::6815:2001"}},
+		{host: "golangbot123.com", notFound: true},
+		{host: "slow.golangbot.com", timeout: true, temporary: true},
+		{host: "busy.golangbot.com", temporary: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			addrs, err := r.LookupHost(context.Background(), tt.host)
+			if tt.addrs != nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				slices.Sort(addrs)
+				if !slices.Equal(addrs, tt.addrs) {
+					t.Errorf("addrs = %v, want %v", addrs, tt.addrs)
+				}
+				return
+			}
+
+			var dnsErr *net.DNSError
+			if !errors.As(err, &dnsErr) {
+				t.Fatalf("err = %v (%T), want a *net.DNSError", err, err)
+			}
+			if dnsErr.IsNotFound != tt.notFound {
+				t.Errorf("IsNotFound = %v, want %v", dnsErr.IsNotFound, tt.notFound)
+			}
+			if dnsErr.IsTimeout != tt.timeout {
+				t.Errorf("IsTimeout = %v, want %v", dnsErr.IsTimeout, tt.timeout)
+			}
+			if dnsErr.IsTemporary != tt.temporary {
+				t.Errorf("IsTemporary = %v, want %v", dnsErr.IsTemporary, tt.temporary)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	srv, err := Start(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	r := srv.Resolver()
+
+	if _, err := r.LookupHost(context.Background(), "Example.COM"); err == nil {
+		t.Fatal("lookup of an unknown name succeeded")
+	}
+	srv.Set("example.com", Addrs("192.0.2.1"))
+	addrs, err := r.LookupHost(context.Background(), "Example.COM")
+	if err != nil || !slices.Equal(addrs, []string{"192.0.2.1"}) {
+		t.Errorf("LookupHost = %v, %v; want [192.0.2.1]", addrs, err)
+	}
+	if srv.Queries() == 0 {
+		t.Error("Queries = 0")
+	}
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"learngo/11-deferAndError/errcode"
+	"learngo/11-deferAndError/supervisor"
+	"learngo/11-deferAndError/validation"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// extractor finds an error of one type in a chain and returns its fields.
+type extractor struct {
+	name string
+	find func(err error) (map[string]any, bool)
+}
+
+var (
+	mu         sync.Mutex
+	extractors []extractor
+)
+
+// RegisterFields makes records include the fields fn returns for the first
+// error of type T in the chain, as found by errors.As. The fields are
+// stored under the name of T, such as "*fs.PathError". Registering the
+// same type again replaces fn.
+func RegisterFields[T error](fn func(T) map[string]any) {
+	var zero T
+	name := fmt.Sprintf("%T", zero)
+	if name == "<nil>" {
+		panic("report: RegisterFields with an interface type")
+	}
+	ex := extractor{name: name, find: func(err error) (map[string]any, bool) {
+		var target T
+		if !errors.As(err, &target) {
+			return nil, false
+		}
+		return fn(target), true
+	}}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range extractors {
+		if extractors[i].name == name {
+			extractors[i] = ex
+			return
+		}
+	}
+	extractors = append(extractors, ex)
+}
+
+func fields(err error) map[string]map[string]any {
+	mu.Lock()
+	list := extractors
+	mu.Unlock()
+
+	var m map[string]map[string]any
+	for _, ex := range list {
+		if f, ok := ex.find(err); ok {
+			if m == nil {
+				m = make(map[string]map[string]any)
+			}
+			m[ex.name] = f
+		}
+	}
+	return m
+}
+
+// The error types of the standard library and of this chapter that carry
+// fields worth keeping.
+func init() {
+	RegisterFields(func(e *fs.PathError) map[string]any {
+		return map[string]any{"op": e.Op, "path": e.Path}
+	})
+	RegisterFields(func(e *os.LinkError) map[string]any {
+		return map[string]any{"op": e.Op, "old": e.Old, "new": e.New}
+	})
+	RegisterFields(func(e *os.SyscallError) map[string]any {
+		return map[string]any{"syscall": e.Syscall}
+	})
+	RegisterFields(func(e syscall.Errno) map[string]any {
+		return map[string]any{"errno": int(e), "timeout": e.Timeout(), "temporary": e.Temporary()}
+	})
+	RegisterFields(func(e *net.DNSError) map[string]any {
+		return map[string]any{
+			"name":      e.Name,
+			"server":    e.Server,
+			"timeout":   e.IsTimeout,
+			"temporary": e.IsTemporary,
+			"not_found": e.IsNotFound,
+		}
+	})
+	RegisterFields(func(e *net.OpError) map[string]any {
+		f := map[string]any{"op": e.Op, "net": e.Net, "timeout": e.Timeout(), "temporary": e.Temporary()}
+		if e.Addr != nil {
+			f["addr"] = e.Addr.String()
+		}
+		return f
+	})
+	RegisterFields(func(e *strconv.NumError) map[string]any {
+		return map[string]any{"func": e.Func, "num": e.Num}
+	})
+	RegisterFields(func(e *json.SyntaxError) map[string]any {
+		return map[string]any{"offset": e.Offset}
+	})
+	RegisterFields(func(e *json.UnmarshalTypeError) map[string]any {
+		return map[string]any{"value": e.Value, "type": e.Type.String(), "field": e.Field, "offset": e.Offset}
+	})
+	RegisterFields(func(e *errcode.Error) map[string]any {
+		return map[string]any{"code": e.Kind.Code(), "severity": e.Kind.Severity().String(), "params": e.Params}
+	})
+	RegisterFields(func(e *validation.FieldError) map[string]any {
+		return map[string]any{"field": e.Field, "code": e.Code}
+	})
+	RegisterFields(func(e *supervisor.PanicError) map[string]any {
+		return map[string]any{"value": fmt.Sprint(e.Value)}
+	})
+}
@@ -0,0 +1,242 @@
+// Package report writes errors as JSON Lines, one object per error, so that
+// they can be searched and counted later instead of scrolling past on the
+// terminal. A record holds the whole chain of wrapped errors with their
+// concrete types, the fields of well known error types found with errors.As,
+// the stack if the error carries one, and the time it was reported.
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"learngo/09-conc/clock"
+	"learngo/11-deferAndError/errcode"
+	"learngo/11-deferAndError/stack"
+	"learngo/11-deferAndError/supervisor"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record is one reported error, one line of the output.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Type    string    `json:"type"`  // concrete type of the reported error
+	Cause   string    `json:"cause"` // concrete type of the first error at the end of the chain
+	Code    string    `json:"code,omitempty"`
+
+	Chain []Link `json:"chain"`
+
+	// Fields holds the fields of the errors found with errors.As, keyed by
+	// type, such as "*fs.PathError". See RegisterFields.
+	Fields map[string]map[string]any `json:"fields,omitempty"`
+
+	Stack      []Frame `json:"stack,omitempty"`       // the frames of every stack.Error in the chain
+	PanicStack string  `json:"panic_stack,omitempty"` // the stack of a supervisor.PanicError
+}
+
+// Link is one error of the chain.
+type Link struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Depth   int    `json:"depth,omitempty"` // > 0 for errors below an errors.Join
+}
+
+// Frame is a frame of a stack.Error.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// NewRecord describes err, reported at t.
+func NewRecord(err error, t time.Time) Record {
+	rec := Record{
+		Time:    t,
+		Message: err.Error(),
+		Type:    typeName(err),
+		Code:    errcode.CodeOf(err),
+	}
+
+	var walk func(err error, depth int)
+	walk = func(err error, depth int) {
+		for err != nil {
+			rec.Chain = append(rec.Chain, Link{Type: typeName(err), Message: err.Error(), Depth: depth})
+			if j, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, err := range j.Unwrap() {
+					walk(err, depth+1)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+		if rec.Cause == "" {
+			rec.Cause = rec.Chain[len(rec.Chain)-1].Type
+		}
+	}
+	walk(err, 0)
+
+	rec.Fields = fields(err)
+
+	for _, f := range stack.Frames(err) {
+		rec.Stack = append(rec.Stack, Frame{Function: f.Function, File: f.File, Line: f.Line})
+	}
+	var pe *supervisor.PanicError
+	if errors.As(err, &pe) {
+		rec.PanicStack = string(pe.Stack)
+	}
+	return rec
+}
+
+func typeName(err error) string {
+	return fmt.Sprintf("%T", err)
+}
+
+// Options configures a Reporter.
+type Options struct {
+	Clock clock.Clock // default clock.Real()
+}
+
+// Reporter writes records to a writer, one JSON object per line. It is
+// safe for concurrent use. To keep the output from growing without bound,
+// write to an appender.Appender from learngo/14-files/appender, which
+// rotates files.
+type Reporter struct {
+	clk clock.Clock
+
+	mu     sync.Mutex
+	w      io.Writer
+	err    error // first error
+	broken bool  // a write failed, nothing more is written
+}
+
+// New returns a Reporter writing to w.
+func New(w io.Writer, opt Options) *Reporter {
+	if opt.Clock == nil {
+		opt.Clock = clock.Real()
+	}
+	return &Reporter{w: w, clk: opt.Clock}
+}
+
+// Report writes a record for err and returns err, so that it can be used
+// in a return statement. A nil err is not reported. If a field cannot be
+// encoded, such as a NaN float, the fields of that record are written as
+// strings. After a write fails nothing more is written.
+func (r *Reporter) Report(err error) error {
+	if err == nil {
+		return nil
+	}
+	rec := NewRecord(err, r.clk.Now())
+	b, merr := json.Marshal(rec)
+	if merr != nil {
+		rec.Fields = stringFields(rec.Fields)
+		b, merr = json.Marshal(rec)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.broken {
+		return err
+	}
+	if merr != nil {
+		r.setErr(merr)
+		return err
+	}
+	if _, werr := r.w.Write(append(b, '\n')); werr != nil {
+		r.setErr(werr)
+		r.broken = true
+	}
+	return err
+}
+
+// setErr must be called with r.mu held.
+func (r *Reporter) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// stringFields returns fields with every value formatted by fmt.Sprint.
+func stringFields(fields map[string]map[string]any) map[string]map[string]any {
+	out := make(map[string]map[string]any, len(fields))
+	for typ, f := range fields {
+		sf := make(map[string]any, len(f))
+		for k, v := range f {
+			sf[k] = fmt.Sprint(v)
+		}
+		out[typ] = sf
+	}
+	return out
+}
+
+// Err returns the first error met while marshaling or writing a record.
+// Records whose fields could only be written as strings are not errors.
+func (r *Reporter) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Read reads the records written by a Reporter. Empty lines are skipped.
+func Read(r io.Reader) ([]Record, error) {
+	var recs []Record
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024) // panic stacks make long lines
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return recs, fmt.Errorf("report: line %d: %w", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, s.Err()
+}
+
+// Group counts the records with the same key.
+type Group struct {
+	Key     string
+	Count   int
+	First   time.Time
+	Last    time.Time
+	Message string // message of the last record
+}
+
+// Summarize groups records by key, such as the Cause, Type or Code of a
+// record. Groups are sorted by count, largest first, then by key.
+func Summarize(recs []Record, key func(Record) string) []Group {
+	groups := make(map[string]*Group)
+	for _, rec := range recs {
+		k := key(rec)
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Key: k, First: rec.Time}
+			groups[k] = g
+		}
+		g.Count++
+		if rec.Time.Before(g.First) {
+			g.First = rec.Time
+		}
+		if !rec.Time.Before(g.Last) {
+			g.Last = rec.Time
+			g.Message = rec.Message
+		}
+	}
+
+	list := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
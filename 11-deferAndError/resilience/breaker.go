@@ -0,0 +1,206 @@
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Breaker.Execute without calling fn while the
+// breaker is open.
+var ErrOpen = errors.New("resilience: circuit breaker is open")
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	// Closed lets every call through and counts consecutive failures.
+	Closed State = iota
+	// Open rejects every call until OpenTimeout has passed.
+	Open
+	// HalfOpen lets a few trial calls through to probe for recovery.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSettings configures a Breaker.
+type BreakerSettings struct {
+	FailureThreshold int           // consecutive failures that open the breaker, 0 means 5
+	OpenTimeout      time.Duration // time spent open before a trial, 0 means 10s
+	HalfOpenMaxCalls int           // trial calls allowed at once while half-open, 0 means 1
+	SuccessThreshold int           // successful trials needed to close, 0 means 1
+
+	// IsFailure decides which errors count against the breaker. By default
+	// every non-nil error does.
+	IsFailure func(err error) bool
+	// OnStateChange is called after every transition, outside the lock.
+	OnStateChange func(from, to State)
+	// Now is the clock, time.Now by default.
+	Now func() time.Time
+}
+
+// Breaker is a circuit breaker. After FailureThreshold consecutive failures
+// it opens and fails fast with ErrOpen, which gives the failing dependency
+// time to recover. After OpenTimeout it moves to half-open and lets trial
+// calls through: a success closes it again, a failure opens it again.
+type Breaker struct {
+	s BreakerSettings
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	trials    int
+	openedAt  time.Time
+
+	// generation changes on every transition. A call records its outcome
+	// only if the generation has not changed since it was let through, so
+	// a slow call from before the breaker opened cannot close it again.
+	generation uint64
+}
+
+// NewBreaker returns a closed breaker.
+func NewBreaker(s BreakerSettings) *Breaker {
+	if s.FailureThreshold <= 0 {
+		s.FailureThreshold = 5
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = 10 * time.Second
+	}
+	if s.HalfOpenMaxCalls <= 0 {
+		s.HalfOpenMaxCalls = 1
+	}
+	if s.SuccessThreshold <= 0 {
+		s.SuccessThreshold = 1
+	}
+	if s.IsFailure == nil {
+		s.IsFailure = func(err error) bool { return err != nil }
+	}
+	if s.Now == nil {
+		s.Now = time.Now
+	}
+	return &Breaker{s: s}
+}
+
+// State returns the current state, moving from open to half-open if the
+// timeout has passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	changes := b.tick()
+	st := b.state
+	b.mu.Unlock()
+	b.notify(changes)
+	return st
+}
+
+// Execute calls fn if the breaker allows it and records the outcome. A
+// panic in fn counts as a failure and is passed on to the caller.
+func (b *Breaker) Execute(fn func() error) error {
+	b.mu.Lock()
+	changes := b.tick()
+	switch b.state {
+	case Open:
+		b.mu.Unlock()
+		b.notify(changes)
+		return ErrOpen
+	case HalfOpen:
+		if b.trials >= b.s.HalfOpenMaxCalls {
+			b.mu.Unlock()
+			b.notify(changes)
+			return ErrOpen
+		}
+		b.trials++
+	}
+	gen := b.generation
+	b.mu.Unlock()
+	b.notify(changes)
+
+	failed := true
+	defer func() {
+		b.mu.Lock()
+		changes := b.record(gen, failed)
+		b.mu.Unlock()
+		b.notify(changes)
+	}()
+	err := fn()
+	failed = b.s.IsFailure(err)
+	return err
+}
+
+type transition struct {
+	from, to State
+}
+
+// tick must be called with b.mu held.
+func (b *Breaker) tick() []transition {
+	if b.state == Open && b.s.Now().Sub(b.openedAt) >= b.s.OpenTimeout {
+		return []transition{b.setState(HalfOpen)}
+	}
+	return nil
+}
+
+// record must be called with b.mu held. Outcomes of calls let through
+// in an earlier generation are ignored; their trial slot, if any, was
+// already freed by the transition.
+func (b *Breaker) record(gen uint64, failed bool) []transition {
+	if gen != b.generation {
+		return nil
+	}
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return nil
+		}
+		b.failures++
+		if b.failures >= b.s.FailureThreshold {
+			return []transition{b.setState(Open)}
+		}
+	case HalfOpen:
+		if b.trials > 0 {
+			b.trials--
+		}
+		if failed {
+			return []transition{b.setState(Open)}
+		}
+		b.successes++
+		if b.successes >= b.s.SuccessThreshold {
+			return []transition{b.setState(Closed)}
+		}
+	}
+	return nil
+}
+
+// setState must be called with b.mu held.
+func (b *Breaker) setState(to State) transition {
+	t := transition{b.state, to}
+	b.state = to
+	b.failures = 0
+	b.successes = 0
+	b.trials = 0
+	b.generation++
+	if to == Open {
+		b.openedAt = b.s.Now()
+	}
+	return t
+}
+
+func (b *Breaker) notify(changes []transition) {
+	if b.s.OnStateChange == nil {
+		return
+	}
+	for _, t := range changes {
+		b.s.OnStateChange(t.from, t.to)
+	}
+}
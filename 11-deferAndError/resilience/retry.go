@@ -0,0 +1,173 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Policy describes how Retry repeats a failing call.
+type Policy struct {
+	MaxAttempts int           // total number of calls, <= 0 means 5
+	Initial     time.Duration // delay after the first failure, 0 means 100ms
+	Max         time.Duration // upper bound for a single delay, 0 means 10s
+	Multiplier  float64       // growth factor between delays, < 1 means 2
+	Jitter      float64       // fraction of each delay that is randomized, 0..1
+
+	// Retryable overrides IsRetryable when set.
+	Retryable func(err error) bool
+	// OnRetry is called before sleeping between attempts.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultPolicy returns a policy with 5 attempts starting at 100ms, doubling
+// up to 10s, with half of every delay randomized.
+func DefaultPolicy() Policy {
+	return Policy{Jitter: 0.5}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.Initial <= 0 {
+		p.Initial = 100 * time.Millisecond
+	}
+	if p.Max <= 0 {
+		p.Max = 10 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	return p
+}
+
+// Backoff returns the delay after the given failed attempt (1 based). The
+// delay grows exponentially, is capped at Max, and then the Jitter fraction
+// of it is replaced by a random amount so that many clients failing at the
+// same moment do not retry in lockstep.
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	d := float64(p.Initial)
+	for i := 1; i < attempt && d < float64(p.Max); i++ {
+		d *= p.Multiplier
+	}
+	if d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		fixed := d * (1 - p.Jitter)
+		d = fixed + rand.Float64()*(d-fixed)
+	}
+	return time.Duration(d)
+}
+
+// Retry calls fn until it succeeds, returns an error that is not retryable,
+// the attempts run out or ctx is done.
+func Retry(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	p = p.withDefaults()
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if !retryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := p.Backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w (last error: %w)", ctx.Err(), err)
+		}
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, whatever its type says.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string   { return e.err.Error() }
+func (e *transientError) Unwrap() error   { return e.err }
+func (e *transientError) Retryable() bool { return true }
+
+// Transient marks err as worth retrying.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err}
+}
+
+// IsRetryable classifies an error by looking through its whole chain with
+// errors.As. An error is retryable when something in the chain:
+//
+//   - has a Retryable() bool method that returns true, or
+//   - has a Timeout() bool method that returns true, or
+//   - has a Temporary() bool method that returns true,
+//
+// and the chain does not contain a Permanent error or a context error.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) && r.Retryable() {
+		return true
+	}
+	var t interface{ Timeout() bool }
+	if errors.As(err, &t) && t.Timeout() {
+		return true
+	}
+	var tmp interface{ Temporary() bool }
+	if errors.As(err, &tmp) && tmp.Temporary() {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+/*
+Ponavljanje i prekidač kola
+===========================
+
+U programu "errAsDNSError" smo naučili da pomoću errors.As saznamo da li je
+greška nastala zbog isteka vremena (Timeout) ili je privremena (Temporary).
+Ali program je to samo ispisao. Privremena greška je upravo ona koju ima
+smisla pokušati ponovo - mreža je možda na trenutak bila zagušena, server se
+restartovao, i slično.
+
+Ponavljanje sa eksponencijalnim odlaganjem
+------------------------------------------
+Naivno ponavljanje u petlji, odmah nakon greške, samo dodatno opterećuje
+server koji već ima problem. Zato se između pokušaja čeka, i to sve duže:
+100ms, 200ms, 400ms, 800ms ... Ovo se zove eksponencijalno odlaganje (eng.
+exponential backoff). Odlaganje se ograničava odozgo (npr. 10s), da ne bismo
+čekali satima.
+
+Ako hiljadu klijenata dobije grešku u istom trenutku, svi će ponovo pokušati u
+istom trenutku i opet oboriti server. Zato se deo odlaganja bira slučajno
+(eng. jitter), pa se pokušaji razliju kroz vreme.
+
+Paket "learngo/11-deferAndError/resilience" ima funkciju Retry:
+
+	err := resilience.Retry(ctx, resilience.DefaultPolicy(),
+		func(ctx context.Context) error {
+			return callServer(ctx)
+		})
+
+Koje greške ponoviti?
+---------------------
+Nema smisla ponavljati grešku "no such host" - domen neće početi da postoji
+za 200ms. Funkcija resilience.IsRetryable klasifikuje grešku isto kao što smo
+to radili ručno u "errAsDNSError", samo što umesto konkretnog tipa
+*net.DNSError traži bilo šta u lancu grešaka što ima metodu:
+
+	Retryable() bool
+	Timeout() bool
+	Temporary() bool
+
+i vraća true. Ovo radi jer errors.As prihvata i pokazivač na interfejs kao
+cilj. Zahvaljujući tome, IsRetryable radi sa *net.DNSError, *os.PathError sa
+isteklim rokom, ali i sa našim sopstvenim tipovima grešaka, čak i kada su
+omotane sa %w kao u "wrapError.go".
+
+Greška se može i eksplicitno označiti: resilience.Permanent(err) se nikada ne
+ponavlja, a resilience.Transient(err) se uvek ponavlja.
+
+Primer
+------
+Napravimo DNS pretragu koja prva dva puta vrati privremenu grešku, a treći put
+uspe.
+*/
+
+package de
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learngo/11-deferAndError/resilience"
+	"net"
+	"sync"
+	"time"
+)
+
+func flakyLookup(failures int) func(ctx context.Context) ([]string, error) {
+	calls := 0
+	return func(ctx context.Context) ([]string, error) {
+		calls++
+		if calls <= failures {
+			return nil, &net.DNSError{Err: "server misbehaving", Name: "golangbot.com", IsTemporary: true}
+		}
+		return []string{"104.21.48.1"}, nil
+	}
+}
+
+func retryDNS() {
+
+	fmt.Println("\n --- retryDNS ---")
+
+	lookup := flakyLookup(2)
+	policy := resilience.Policy{
+		MaxAttempts: 4,
+		Initial:     50 * time.Millisecond,
+		Jitter:      0.2,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			fmt.Printf("attempt %d failed: %s, next try in %v\n", attempt, err, delay.Round(10*time.Millisecond))
+		},
+	}
+
+	var addr []string
+	err := resilience.Retry(context.Background(), policy, func(ctx context.Context) error {
+		var err error
+		addr, err = lookup(ctx)
+		return err
+	})
+	if err != nil {
+		fmt.Println("Generic error", err)
+		return
+	}
+	fmt.Println(addr)
+}
+
+/*
+Program ispisuje:
+
+	>> attempt 1 failed: lookup golangbot.com: server misbehaving, next try in 50ms
+	>> attempt 2 failed: lookup golangbot.com: server misbehaving, next try in 90ms
+	>> [104.21.48.1]
+
+Drugo odlaganje je trebalo da bude 100ms, ali je 20% njega slučajno, pa je
+ispalo 90ms.
+
+Sopstvene greške i omotavanje
+-----------------------------
+Proširimo "DBError3" iz "wrapError.go" tako da zna da li je privremena. Greška
+se i dalje omotava sa %w u "webService", a Retry je ipak prepoznaje.
+*/
+
+type DBError4 struct {
+	desc      string
+	temporary bool
+}
+
+func (dbError DBError4) Error() string {
+	return dbError.desc
+}
+
+func (dbError DBError4) Temporary() bool {
+	return dbError.temporary
+}
+
+func webService4(getRecord func() error) error {
+	if err := getRecord(); err != nil {
+		return fmt.Errorf("Error %w when calling DB", err)
+	}
+	return nil
+}
+
+func retryWrapped() {
+
+	fmt.Println("\n --- retryWrapped ---")
+
+	policy := resilience.Policy{MaxAttempts: 3, Initial: 10 * time.Millisecond}
+
+	attempts := 0
+	err := resilience.Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return webService4(func() error {
+			if attempts < 3 {
+				return DBError4{desc: "connection reset", temporary: true}
+			}
+			return nil
+		})
+	})
+	fmt.Println("attempts:", attempts, "error:", err)
+
+	attempts = 0
+	err = resilience.Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return webService4(func() error {
+			return DBError4{desc: "no rows found"}
+		})
+	})
+	fmt.Println("attempts:", attempts, "error:", err)
+
+	var dbError DBError4
+	if errors.As(err, &dbError) {
+		fmt.Println("errors.As still works:", dbError.desc)
+	}
+}
+
+/*
+Prvi poziv uspeva iz trećeg pokušaja. Drugi poziv vraća grešku koja nije
+privremena, pa se odmah odustaje. Retry vraća grešku nepromenjenu, pa
+errors.As i dalje nalazi DBError4 u lancu:
+
+	>> attempts: 3 error: <nil>
+	>> attempts: 1 error: Error no rows found when calling DB
+	>> errors.As still works: no rows found
+
+Kada se pokušaji potroše, Retry vraća poslednju grešku omotanu porukom
+"giving up after N attempts: ...", a kada se context otkaže tokom čekanja,
+vraća grešku koja omotava i ctx.Err() i poslednju grešku.
+
+Prekidač kola
+-------------
+Ponavljanje pomaže kod kratkih smetnji. Ali ako je baza podataka pala na deset
+minuta, svaki zahtev će i dalje pokušati pet puta i čekati sekundama pre nego
+što odustane. Još gore, svi ti pokušaji opterećuju bazu baš dok pokušava da se
+podigne.
+
+Prekidač kola (eng. circuit breaker) radi kao osigurač u kući. Ima tri stanja:
+
+- zatvoren (closed) - pozivi prolaze normalno. Broje se uzastopne greške.
+- otvoren (open) - posle N uzastopnih grešaka prekidač "iskače". Pozivi se
+  uopšte ne izvršavaju, već odmah vraćaju resilience.ErrOpen.
+- poluotvoren (half-open) - kada prođe "OpenTimeout", propušta se jedan probni
+  poziv. Ako uspe, prekidač se zatvara. Ako ne uspe, ponovo se otvara.
+
+Funkcija OnStateChange se poziva pri svakoj promeni stanja. U primeru ispod
+vreme ne čekamo stvarno, već prekidaču dajemo sopstveni sat "Now" koji
+pomeramo ručno.
+*/
+
+func retryBreaker() {
+
+	fmt.Println("\n --- retryBreaker ---")
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	br := resilience.NewBreaker(resilience.BreakerSettings{
+		FailureThreshold: 3,
+		OpenTimeout:      30 * time.Second,
+		Now:              func() time.Time { return now },
+		OnStateChange: func(from, to resilience.State) {
+			fmt.Printf("breaker %s -> %s\n", from, to)
+		},
+	})
+
+	dbDown := true
+	callDB := func() error {
+		if dbDown {
+			return DBError4{desc: "connection refused", temporary: true}
+		}
+		return nil
+	}
+
+	for i := 1; i <= 5; i++ {
+		fmt.Printf("call %d: %v\n", i, br.Execute(callDB))
+	}
+
+	now = now.Add(31 * time.Second)
+	fmt.Printf("trial call: %v\n", br.Execute(callDB))
+
+	now = now.Add(31 * time.Second)
+	dbDown = false
+	fmt.Printf("trial call: %v\n", br.Execute(callDB))
+	fmt.Println("state:", br.State())
+}
+
+/*
+Program ispisuje:
+
+	>> call 1: connection refused
+	>> call 2: connection refused
+	>> breaker closed -> open
+	>> call 3: connection refused
+	>> call 4: resilience: circuit breaker is open
+	>> call 5: resilience: circuit breaker is open
+	>> breaker open -> half-open
+	>> breaker half-open -> open
+	>> trial call: connection refused
+	>> breaker open -> half-open
+	>> breaker half-open -> closed
+	>> trial call: <nil>
+	>> state: closed
+
+Pozivi 4 i 5 se uopšte ne izvršavaju.
+
+Worker pool sa ponavljanjem i prekidačem
+----------------------------------------
+Ponavljanje i prekidač se lepo slažu sa worker pool-om iz poglavlja o
+konkurentnosti. Svaki worker ponavlja svoj posao, a svi workeri dele jedan
+prekidač, jer svi pozivaju isti server. Prekidač je bezbedan za konkurentnu
+upotrebu.
+
+ErrOpen nije privremena greška, pa je Retry ne ponavlja - prekidač je
+otvoren upravo zato da ne bismo gubili vreme.
+*/
+
+func retryWorker(id int, jobs <-chan int, br *resilience.Breaker, wg *sync.WaitGroup) {
+	defer wg.Done()
+	policy := resilience.Policy{MaxAttempts: 3, Initial: 5 * time.Millisecond}
+	for job := range jobs {
+		attempts := 0
+		err := resilience.Retry(context.Background(), policy, func(ctx context.Context) error {
+			return br.Execute(func() error {
+				attempts++
+				if job%3 == 0 && attempts == 1 {
+					return resilience.Transient(errors.New("server busy"))
+				}
+				return nil
+			})
+		})
+		fmt.Printf("worker %d, job %d, attempts %d, error %v\n", id, job, attempts, err)
+	}
+}
+
+func retryWorkerPool() {
+
+	fmt.Println("\n --- retryWorkerPool ---")
+
+	br := resilience.NewBreaker(resilience.BreakerSettings{FailureThreshold: 5})
+	jobs := make(chan int, 10)
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go retryWorker(i, jobs, br, &wg)
+	}
+	for j := 1; j <= 6; j++ {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+	fmt.Println("breaker state:", br.State())
+}
+
+/*
+Poslovi 3 i 6 prvi put dobijaju grešku "server busy" i uspevaju iz drugog
+pokušaja. Pošto se greške ne nižu jedna za drugom, prekidač ostaje zatvoren:
+
+	>> worker 3, job 1, attempts 1, error <nil>
+	>> worker 1, job 2, attempts 1, error <nil>
+	>> worker 2, job 3, attempts 2, error <nil>
+	>> ...
+	>> breaker state: closed
+*/
+
+func RetryFunc() {
+
+	fmt.Println("\n --- Retry Func ---")
+
+	retryDNS()
+	retryWrapped()
+	retryBreaker()
+	retryWorkerPool()
+}
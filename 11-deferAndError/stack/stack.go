@@ -0,0 +1,119 @@
+// Package stack wraps errors with the place where they were wrapped. Each
+// wrap records a single program counter, which costs about as much as the
+// fmt.Errorf call it replaces; file names and line numbers are looked up
+// only when the error is printed with %+v.
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// Error is an error annotated with a message and the frame of the function
+// that created it.
+type Error struct {
+	msg string
+	err error
+	pc  uintptr
+}
+
+// New returns an error with the message msg and the caller's frame.
+func New(msg string) error {
+	return &Error{msg: msg, pc: caller()}
+}
+
+// Wrap returns err with msg in front of its message and the caller's frame.
+// It returns nil if err is nil, so that
+//
+//	return stack.Wrap(f(), "calling f")
+//
+// is safe.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{msg: msg, err: err, pc: caller()}
+}
+
+// Wrapf is Wrap with a formatted message.
+func Wrapf(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{msg: fmt.Sprintf(format, args...), err: err, pc: caller()}
+}
+
+// caller returns the program counter of the function that called New,
+// Wrap or Wrapf.
+func caller() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	return pcs[0]
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.err == nil:
+		return e.msg
+	case e.msg == "":
+		return e.err.Error()
+	}
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Frame returns the frame recorded when e was created.
+func (e *Error) Frame() runtime.Frame {
+	frame, _ := runtime.CallersFrames([]uintptr{e.pc}).Next()
+	return frame
+}
+
+// Format prints the message for %s and %v. %+v adds the frame of every
+// *Error in the chain, outermost first, like a panic trace:
+//
+//	webService: getRecord: no rows found
+//	main.webService
+//		/src/app/db.go:25
+//	main.getRecord
+//		/src/app/db.go:18
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error())
+		for _, f := range Frames(e) {
+			fmt.Fprintf(s, "\n%s\n\t%s:%d", f.Function, f.File, f.Line)
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// Frames returns the frames of every *Error in err's tree, outermost
+// first. Errors joined with errors.Join are visited in order.
+func Frames(err error) []runtime.Frame {
+	var frames []runtime.Frame
+	var walk func(err error)
+	walk = func(err error) {
+		for err != nil {
+			if e, ok := err.(*Error); ok {
+				frames = append(frames, e.Frame())
+			}
+			if j, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, err := range j.Unwrap() {
+					walk(err)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+	return frames
+}
@@ -0,0 +1,267 @@
+/*
+Bezbedne gorutine i nadzor
+==========================
+
+U programu "recoverGoroutine" smo videli da recover radi samo u gorutini koja
+je izazvala paniku. Zato su i sum i div morale same da odlože poziv
+recovery(). Ako to zaboravimo u samo jednoj gorutini, panika u njoj ruši ceo
+program, sa svim ostalim gorutinama. A i kada se oporavimo, pozivalac ne
+saznaje ništa: recovery() samo ispiše poruku, a kanal se zatvori bez
+rezultata.
+
+Paket "learngo/11-deferAndError/supervisor" to rešava na jednom mestu:
+
+	supervisor.Call(fn)            // poziva fn, paniku pretvara u grešku
+	errc := supervisor.Go(fn)      // isto, ali u novoj gorutini, greška stiže na kanal
+	supervisor.GoFunc(fn, report)  // isto, greška se prosleđuje funkciji report
+
+Panika postaje greška tipa *supervisor.PanicError, sa vrednošću prosleđenom
+funkciji panic i tragom steka gorutine (debug.Stack) u trenutku panike. Ako je
+vrednost greška, kao runtime.Error kod deljenja nulom, errors.As je nalazi
+kroz PanicError.
+
+Gorutina može da se završi i bez povratka iz fn, pozivom runtime.Goexit (to
+radi i t.FailNow u testovima). Goexit se ne može zaustaviti, ali odložene
+funkcije se izvršavaju, pa Go i GoFunc tada prijave grešku
+supervisor.ErrGoexit, umesto da kanal bude zatvoren bez greške.
+*/
+
+package de
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"learngo/09-conc/clock"
+	"learngo/11-deferAndError/resilience"
+	"learngo/11-deferAndError/supervisor"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+func sumUnsafe(a, b int) (int, error) {
+	if a == (-b) {
+		panic("sum error: a can't be eq (-b)")
+	}
+	return a + b, nil
+}
+
+func divUnsafe(a, b int) (int, error) {
+	return a / b, nil
+}
+
+func safeGoChannel() {
+
+	fmt.Println("\n --- safeGoChannel ---")
+
+	var sum, div int
+	errSum := supervisor.Go(func() (err error) {
+		sum, err = sumUnsafe(5, -5)
+		return err
+	})
+	errDiv := supervisor.Go(func() (err error) {
+		div, err = divUnsafe(5, 0)
+		return err
+	})
+
+	for _, err := range []error{<-errSum, <-errDiv} {
+		fmt.Println(err)
+
+		var pe *supervisor.PanicError
+		if errors.As(err, &pe) {
+			fmt.Println("  stack mentions the function:",
+				strings.Contains(string(pe.Stack), "sumUnsafe") || strings.Contains(string(pe.Stack), "divUnsafe"))
+		}
+		var re runtime.Error
+		fmt.Println("  runtime error:", errors.As(err, &re))
+	}
+	fmt.Println("sum is", sum, "div is", div)
+
+	done := make(chan struct{})
+	supervisor.GoFunc(func() error {
+		var m map[string]int
+		m["a"] = 1
+		return nil
+	}, func(err error) {
+		fmt.Println("reported:", err)
+		close(done)
+	})
+	<-done
+	fmt.Println("normally returned from safeGoChannel")
+}
+
+/*
+Program ispisuje:
+
+	>> panic: sum error: a can't be eq (-b)
+	>>   stack mentions the function: true
+	>>   runtime error: false
+	>> panic: runtime error: integer divide by zero
+	>>   stack mentions the function: true
+	>>   runtime error: true
+	>> sum is 0 div is 0
+	>> reported: panic: assignment to entry in nil map
+	>> normally returned from safeGoChannel
+
+sumUnsafe i divUnsafe nemaju nijedan defer, a program se ipak nije srušio.
+Sa fmt.Printf("%+v", err) PanicError ispisuje i ceo trag steka.
+
+Nadzor i ponovno pokretanje
+---------------------------
+Radnik koji radi dok radi program (čita redove poruka, obrađuje zahteve) ne
+treba da nestane zbog jedne greške. U jeziku Erlang ovaj problem rešava
+nadzornik (supervisor): proces koji pokreće radnike i ponovo ih pokreće kada
+padnu. supervisor.Run radi isto za jednu funkciju:
+
+	err := supervisor.Run(ctx, "worker", supervisor.Policy{
+		Restart:     supervisor.OnFailure,
+		MaxRestarts: 5,
+		Backoff:     &resilience.Policy{Initial: time.Second},
+	}, worker)
+
+Policy.Restart kaže kada se funkcija ponovo pokreće:
+
+- Never - nikad, funkcija se izvrši jednom,
+- OnFailure - posle greške ili panike,
+- Always - posle svakog završetka, i uspešnog.
+
+MaxRestarts ograničava broj ponovnih pokretanja, posle čega Run vraća
+ErrTooManyRestarts. Backoff je resilience.Policy iz lekcije o ponavljanju,
+pa se između pokretanja čeka sve duže: radnik koji pada odmah po pokretanju
+neće zauzeti ceo procesor. OnExit se poziva posle svakog završetka.
+
+Čekanje između pokretanja koristi clock.Clock, pa u primeru lažnim satom
+pomeramo vreme bez stvarnog čekanja.
+*/
+
+func superviseRestart() {
+
+	fmt.Println("\n --- superviseRestart ---")
+
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan int)
+
+	run := 0
+	worker := func(ctx context.Context) error {
+		run++
+		fmt.Println("run", run, "started")
+		started <- run
+		switch run {
+		case 1:
+			var m map[string]int
+			m["jobs"]++ // panika
+		case 2:
+			return errors.New("connection refused")
+		}
+		<-ctx.Done()
+		return nil
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- supervisor.Run(ctx, "worker", supervisor.Policy{
+			Restart:     supervisor.OnFailure,
+			MaxRestarts: 5,
+			Backoff:     &resilience.Policy{Initial: time.Second},
+			Clock:       clk,
+			OnExit: func(e supervisor.Exit) {
+				fmt.Printf("run %d: %v, restart: %v after %v\n", e.Run, e.Err, e.Restart, e.Delay)
+			},
+		}, worker)
+	}()
+
+	<-started
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	<-started
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Second)
+	<-started
+
+	cancel()
+	fmt.Println("Run returned:", <-done)
+}
+
+/*
+Program ispisuje:
+
+	>> run 1 started
+	>> run 1: panic: assignment to entry in nil map, restart: true after 1s
+	>> run 2 started
+	>> run 2: connection refused, restart: true after 2s
+	>> run 3 started
+	>> run 3: <nil>, restart: false after 0s
+	>> Run returned: <nil>
+
+Posle panike i greške radnik je treći put uspešno pokrenut i radi dok ga ne
+zaustavimo otkazivanjem konteksta.
+
+Previše ponovnih pokretanja
+---------------------------
+Radnik koji pada uvek, na primer zbog pogrešne konfiguracije, nema smisla
+pokretati zauvek. Posle MaxRestarts pokretanja Run odustaje i vraća
+ErrTooManyRestarts, omotanu oko poslednje greške. supervisor.Supervisor
+pokreće više radnika, svakog sa svojom politikom, i Wait vraća greške svih.
+*/
+
+func superviseGiveUp() {
+
+	fmt.Println("\n --- superviseGiveUp ---")
+
+	ctx := context.Background()
+	var s supervisor.Supervisor
+
+	runs := 0
+	s.Go(ctx, "broken", supervisor.Policy{Restart: supervisor.OnFailure, MaxRestarts: 3},
+		func(ctx context.Context) error {
+			runs++
+			return errors.New("config file not found")
+		})
+
+	ticks := 0
+	s.Go(ctx, "ticker", supervisor.Policy{Restart: supervisor.Always, MaxRestarts: 2},
+		func(ctx context.Context) error {
+			ticks++
+			return nil
+		})
+
+	s.Go(ctx, "once", supervisor.Policy{Restart: supervisor.Never},
+		func(ctx context.Context) error {
+			panic("bug")
+		})
+
+	err := s.Wait()
+	fmt.Println("broken ran", runs, "times, ticker ran", ticks, "times")
+	lines := strings.Split(err.Error(), "\n")
+	sort.Strings(lines) // redosled zavisi od toga koji je radnik prvi završio
+	for _, e := range lines {
+		fmt.Println(e)
+	}
+	fmt.Println(errors.Is(err, supervisor.ErrTooManyRestarts))
+}
+
+/*
+Program ispisuje:
+
+	>> broken ran 4 times, ticker ran 3 times
+	>> broken: supervisor: too many restarts: broken after 3 restarts: config file not found
+	>> once: panic: bug
+	>> ticker: supervisor: too many restarts: ticker after 2 restarts
+	>> true
+
+"broken" je pokrenut jednom i ponovo još tri puta. "ticker" se uspešno
+završava, ali politika Always ga ponovo pokreće, dok ne dostigne MaxRestarts.
+"once" ima politiku Never, pa je njegova panika samo vraćena kao greška.
+*/
+
+func SuperviseFunc() {
+
+	fmt.Println("\n --- Supervise Func ---")
+
+	safeGoChannel()
+	superviseRestart()
+	superviseGiveUp()
+}
@@ -0,0 +1,226 @@
+// Package supervisor runs functions in goroutines without letting a panic
+// crash the program: the panic becomes an error with the stack of the
+// goroutine attached. Supervised workers can also be restarted when they
+// fail, following a restart policy.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"learngo/09-conc/clock"
+	"learngo/11-deferAndError/resilience"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// PanicError is a panic recovered from fn.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the stack of the panicking goroutine, from debug.Stack
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, such as a runtime.Error,
+// so errors.Is and errors.As can see it.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Format prints the message for %s and %v, and the stack after it for %+v.
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	io.WriteString(s, e.Error())
+	if verb == 'v' && s.Flag('+') {
+		io.WriteString(s, "\n")
+		s.Write(e.Stack)
+	}
+}
+
+// Call calls fn and returns its error, or a *PanicError if it panics.
+func Call(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// ErrGoexit is reported by Go and GoFunc when fn ends its goroutine with
+// runtime.Goexit, as t.FailNow does, instead of returning.
+var ErrGoexit = errors.New("supervisor: goroutine exited without returning")
+
+// Go calls fn in a new goroutine and returns a channel that receives its
+// error, a *PanicError if it panics, or ErrGoexit, and is then closed. The
+// channel is buffered, so the goroutine does not leak if nobody receives.
+func Go(fn func() error) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		callReport(fn, func(err error) { ch <- err })
+	}()
+	return ch
+}
+
+// GoFunc calls fn in a new goroutine and passes its error, a *PanicError if
+// it panics, or ErrGoexit to report. report is called even when the error
+// is nil.
+func GoFunc(fn func() error, report func(error)) {
+	go callReport(fn, report)
+}
+
+// callReport calls fn and passes the result of Call to report. runtime.Goexit
+// cannot be recovered, but the deferred functions still run, so report gets
+// ErrGoexit before the goroutine ends.
+func callReport(fn func() error, report func(error)) {
+	returned := false
+	defer func() {
+		if !returned {
+			report(ErrGoexit)
+		}
+	}()
+	err := Call(fn)
+	returned = true
+	report(err)
+}
+
+// Restart tells when a supervised function is started again.
+type Restart int
+
+const (
+	Never     Restart = iota // run once
+	OnFailure                // restart after an error or a panic
+	Always                   // restart after every exit, also a clean one
+)
+
+// ErrTooManyRestarts is returned by Run when Policy.MaxRestarts is reached.
+var ErrTooManyRestarts = errors.New("supervisor: too many restarts")
+
+// Policy configures Run.
+type Policy struct {
+	Restart Restart
+
+	// MaxRestarts is the number of restarts after which Run gives up,
+	// <= 0 means no limit.
+	MaxRestarts int
+
+	// Backoff gives the delay before each restart, growing with the number
+	// of restarts. nil restarts at once.
+	Backoff *resilience.Policy
+
+	// ResetAfter forgets earlier restarts when a run lasts at least this
+	// long, so that a worker that failed a few times a week ago is not
+	// stopped by MaxRestarts today. 0 never forgets.
+	ResetAfter time.Duration
+
+	// OnExit is called after every run.
+	OnExit func(Exit)
+
+	Clock clock.Clock // default clock.Real()
+}
+
+// Exit describes how a run ended.
+type Exit struct {
+	Name    string
+	Run     int   // 1 based
+	Err     error // nil, the returned error or a *PanicError
+	Restart bool  // whether fn is started again
+	Delay   time.Duration
+}
+
+// Run calls fn, restarting it as p says, until it exits without a restart
+// or ctx is done. It returns the error of the last run, wrapped in
+// ErrTooManyRestarts when the limit was reached, or ctx.Err() if ctx was
+// done while waiting to restart.
+func Run(ctx context.Context, name string, p Policy, fn func(ctx context.Context) error) error {
+	clk := p.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+
+	restarts := 0
+	for run := 1; ; run++ {
+		start := clk.Now()
+		err := Call(func() error { return fn(ctx) })
+		if p.ResetAfter > 0 && clk.Now().Sub(start) >= p.ResetAfter {
+			restarts = 0
+		}
+
+		exit := Exit{Name: name, Run: run, Err: err}
+		switch {
+		case ctx.Err() != nil:
+		case p.Restart == Always, p.Restart == OnFailure && err != nil:
+			exit.Restart = true
+		}
+		limited := exit.Restart && p.MaxRestarts > 0 && restarts >= p.MaxRestarts
+		if limited {
+			exit.Restart = false
+		}
+		if exit.Restart && p.Backoff != nil {
+			exit.Delay = p.Backoff.Backoff(restarts + 1)
+		}
+		if p.OnExit != nil {
+			p.OnExit(exit)
+		}
+
+		if limited {
+			if err == nil {
+				return fmt.Errorf("%w: %s after %d restarts", ErrTooManyRestarts, name, restarts)
+			}
+			return fmt.Errorf("%w: %s after %d restarts: %w", ErrTooManyRestarts, name, restarts, err)
+		}
+		if !exit.Restart {
+			return err
+		}
+
+		restarts++
+		if exit.Delay > 0 {
+			t := clk.NewTimer(exit.Delay)
+			select {
+			case <-t.C():
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Supervisor runs several supervised functions and waits for them. The
+// zero value is ready to use.
+type Supervisor struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// Go calls Run in a new goroutine. If fn ends the goroutine with
+// runtime.Goexit, Wait reports ErrGoexit for it.
+func (s *Supervisor) Go(ctx context.Context, name string, p Policy, fn func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		callReport(func() error { return Run(ctx, name, p, fn) }, func(err error) {
+			if err != nil {
+				s.mu.Lock()
+				s.errs = append(s.errs, fmt.Errorf("%s: %w", name, err))
+				s.mu.Unlock()
+			}
+		})
+	}()
+}
+
+// Wait waits for every function started with Go to stop for good and
+// returns their errors joined.
+func (s *Supervisor) Wait() error {
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
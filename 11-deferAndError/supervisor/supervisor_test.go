@@ -0,0 +1,62 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestGoReportsGoexit(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"nil", func() error { return nil }, nil},
+		{"error", func() error { return boom }, boom},
+		{"goexit", func() error { runtime.Goexit(); return nil }, ErrGoexit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := Go(tt.fn)
+			if err := <-ch; err != tt.want {
+				t.Errorf("Go sent %v, want %v", err, tt.want)
+			}
+			if _, ok := <-ch; ok {
+				t.Error("channel not closed after the error")
+			}
+
+			reported := make(chan error, 1)
+			GoFunc(tt.fn, func(err error) { reported <- err })
+			if err := <-reported; err != tt.want {
+				t.Errorf("GoFunc reported %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoPanic(t *testing.T) {
+	err := <-Go(func() error {
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	})
+	var pe *PanicError
+	var re runtime.Error
+	if !errors.As(err, &pe) || !errors.As(err, &re) {
+		t.Errorf("Go sent %v, want a *PanicError wrapping a runtime.Error", err)
+	}
+}
+
+func TestSupervisorGoexit(t *testing.T) {
+	var s Supervisor
+	s.Go(context.Background(), "worker", Policy{Restart: OnFailure}, func(context.Context) error {
+		runtime.Goexit()
+		return nil
+	})
+	if err := s.Wait(); !errors.Is(err, ErrGoexit) {
+		t.Errorf("Wait = %v, want ErrGoexit", err)
+	}
+}
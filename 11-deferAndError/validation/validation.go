@@ -0,0 +1,162 @@
+// Package validation collects every problem found while checking a value,
+// instead of stopping at the first one.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FieldError is a problem with a single field.
+type FieldError struct {
+	Field string // path of the field, e.g. "rect.length" or "shapes[2].radius"
+	Code  string // machine readable, e.g. "negative"
+	Err   error  // what is wrong, errors.Is and errors.As see it through Unwrap
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Err.Error()
+	}
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the entry as {"field": ..., "code": ..., "message": ...}.
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string `json:"field,omitempty"`
+		Code    string `json:"code,omitempty"`
+		Message string `json:"message"`
+	}{e.Field, e.Code, e.Err.Error()})
+}
+
+// Errors is a list of FieldErrors. The zero value is empty and ready to use.
+//
+// Unwrap returns the entries, so errors.Is and errors.As look at every one
+// of them, as they do for the result of errors.Join.
+type Errors struct {
+	entries []*FieldError
+}
+
+// Add records that field failed with the given code. A nil err is replaced
+// by an error whose message is the code, or "invalid" without one.
+func (e *Errors) Add(field, code string, err error) {
+	if err == nil {
+		msg := code
+		if msg == "" {
+			msg = "invalid"
+		}
+		err = errors.New(msg)
+	}
+	e.entries = append(e.entries, &FieldError{Field: field, Code: code, Err: err})
+}
+
+// Addf is Add with a message made by fmt.Errorf.
+func (e *Errors) Addf(field, code, format string, args ...any) {
+	e.Add(field, code, fmt.Errorf(format, args...))
+}
+
+// AddError adds the entries of err under the path prefix. A *FieldError or
+// *Errors keeps its codes and gets prefix in front of its field paths; the
+// result of errors.Join is split into its errors; any other error becomes
+// one entry for prefix, without a code. A nil err is ignored.
+func (e *Errors) AddError(prefix string, err error) {
+	switch err := err.(type) {
+	case nil:
+	case *FieldError:
+		e.entries = append(e.entries, &FieldError{Field: Join(prefix, err.Field), Code: err.Code, Err: err.Err})
+	case *Errors:
+		for _, fe := range err.entries {
+			e.AddError(prefix, fe)
+		}
+	case interface{ Unwrap() []error }:
+		for _, err := range err.Unwrap() {
+			e.AddError(prefix, err)
+		}
+	default:
+		e.entries = append(e.entries, &FieldError{Field: prefix, Err: err})
+	}
+}
+
+// Join joins field paths: Join("shapes[2]", "radius") is "shapes[2].radius"
+// and Join("shapes", "[2]") is "shapes[2]".
+func Join(prefix, field string) string {
+	switch {
+	case prefix == "":
+		return field
+	case field == "":
+		return prefix
+	case strings.HasPrefix(field, "["):
+		return prefix + field
+	}
+	return prefix + "." + field
+}
+
+// Len returns the number of entries.
+func (e *Errors) Len() int {
+	return len(e.entries)
+}
+
+// Entries returns the entries in the order they were added.
+func (e *Errors) Entries() []*FieldError {
+	return e.entries
+}
+
+// Err returns e if it has entries and nil otherwise. Return e.Err() rather
+// than e, so that a function without problems returns a true nil error.
+func (e *Errors) Err() error {
+	if e == nil || len(e.entries) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error joins the entries with "; ".
+func (e *Errors) Error() string {
+	msgs := make([]string, len(e.entries))
+	for i, fe := range e.entries {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *Errors) Unwrap() []error {
+	errs := make([]error, len(e.entries))
+	for i, fe := range e.entries {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// WriteText writes one entry per line, as "field [code]: message".
+func (e *Errors) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, fe := range e.entries {
+		b.WriteString(fe.Field)
+		if fe.Code != "" {
+			b.WriteString(" [" + fe.Code + "]")
+		}
+		if fe.Field != "" || fe.Code != "" {
+			b.WriteString(": ")
+		}
+		b.WriteString(fe.Err.Error())
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// MarshalJSON encodes the entries as a JSON array.
+func (e *Errors) MarshalJSON() ([]byte, error) {
+	if e.entries == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(e.entries)
+}
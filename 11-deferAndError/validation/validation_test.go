@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAddNilError(t *testing.T) {
+	var errs Errors
+	errs.Add("length", "negative", nil)
+	errs.Add("width", "", nil)
+
+	if got, want := errs.Error(), "length: negative; width: invalid"; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	var b strings.Builder
+	if err := errs.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "length [negative]: negative\nwidth: invalid\n"; got != want {
+		t.Errorf("WriteText = %q, want %q", got, want)
+	}
+	if _, err := json.Marshal(&errs); err != nil {
+		t.Errorf("Marshal: %v", err)
+	}
+}
+
+func TestAddError(t *testing.T) {
+	errNoSize := errors.New("shape has no size")
+
+	var rect Errors
+	rect.Addf("width", "negative", "width %d is negative", -1)
+
+	var errs Errors
+	errs.AddError("shapes[0]", rect.Err())
+	errs.Add("shapes[1]", "empty", errNoSize)
+	errs.AddError("", errors.Join(errors.New("truncated"), nil))
+	errs.AddError("ignored", nil)
+
+	var all Errors
+	all.AddError("request", errs.Err())
+
+	want := "request.shapes[0].width: width -1 is negative; request.shapes[1]: shape has no size; request: truncated"
+	if got := all.Error(); got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	if !errors.Is(all.Err(), errNoSize) {
+		t.Error("errors.Is does not see the entries")
+	}
+	b, err := json.Marshal(&all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `{"field":"request.shapes[1]","code":"empty","message":"shape has no size"}`) {
+		t.Errorf("JSON = %s", b)
+	}
+}
+
+func TestErrEmpty(t *testing.T) {
+	var errs Errors
+	if err := errs.Err(); err != nil {
+		t.Errorf("Err of empty Errors = %v, want nil", err)
+	}
+	if b, _ := json.Marshal(&errs); string(b) != "[]" {
+		t.Errorf("JSON of empty Errors = %s, want []", b)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct{ prefix, field, want string }{
+		{"", "radius", "radius"},
+		{"shapes[2]", "", "shapes[2]"},
+		{"shapes[2]", "radius", "shapes[2].radius"},
+		{"shapes", "[2]", "shapes[2]"},
+	}
+	for _, tt := range tests {
+		if got := Join(tt.prefix, tt.field); got != tt.want {
+			t.Errorf("Join(%q, %q) = %q, want %q", tt.prefix, tt.field, got, tt.want)
+		}
+	}
+}
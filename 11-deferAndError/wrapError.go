@@ -14,6 +14,8 @@ package de
 import (
 	"errors"
 	"fmt"
+	"learngo/11-deferAndError/stack"
+	"time"
 )
 
 var errNoRows = errors.New("'no rows found'")
@@ -187,10 +189,100 @@ otkazuje. Dakle, "omotane" greške treba smatrati delom API-ja i treba izvršiti
 odgovarajuće izmene verzije ako odlučimo da izmenimo grešku koju vraćamo.
 */
 
+/*
+Odakle je greška došla?
+-----------------------
+Kada greška stigne do vrha programa, njena poruka kaže šta se desilo, ali ne
+i gde. Poruka "no rows found" može doći iz bilo kog upita u programu. Panika
+ispisuje trag steka, ali obična greška je samo vrednost i ne zna ništa o
+funkcijama kroz koje je prošla.
+
+Paket "learngo/11-deferAndError/stack" zato pri svakom omotavanju zapamti i
+mesto na kome je greška omotana:
+
+	return stack.Wrap(err, "calling DB")   // umesto fmt.Errorf("calling DB: %w", err)
+	return stack.New("no rows found")       // umesto errors.New, unutar funkcije
+
+Zapamćeno mesto je jedan broj, brojač programa (program counter), koji vraća
+runtime.Callers. Ime datoteke i broj reda se iz njega računaju tek kada se
+greška ispiše sa %+v. Zato je omotavanje skoro jednako brzo kao fmt.Errorf i
+može ostati uključeno i u produkciji.
+
+stack.Error ima metodu Unwrap, pa errors.Is, errors.As i errors.Unwrap rade
+kao i do sada.
+*/
+
+type DBError5 struct {
+	table string
+}
+
+func (e *DBError5) Error() string {
+	return "no rows found in " + e.table
+}
+
+func getRecord5(table string) error {
+	return stack.Wrap(&DBError5{table: table}, "getRecord")
+}
+
+func webService5() error {
+	if err := getRecord5("users"); err != nil {
+		return stack.Wrap(err, "webService")
+	}
+	return nil
+}
+
+func handler5() error {
+	err := webService5()
+	return stack.Wrapf(err, "GET /users/%d", 42)
+}
+
+func errWrappStack() {
+
+	fmt.Println("\n --- errWrappStack ---")
+
+	err := handler5()
+	fmt.Printf("%v\n", err)
+	fmt.Printf("%+v\n", err)
+
+	var dbError *DBError5
+	if errors.As(err, &dbError) {
+		fmt.Println("table:", dbError.table)
+	}
+	fmt.Println(errors.Unwrap(err) != nil)
+
+	start := time.Now()
+	const n = 100000
+	for i := 0; i < n; i++ {
+		_ = stack.Wrap(dbError, "webService")
+	}
+	fmt.Println("one wrap takes less than 1µs:", time.Since(start)/n < time.Microsecond)
+}
+
+/*
+Program ispisuje:
+
+	>> GET /users/42: webService: getRecord: no rows found in users
+	>> GET /users/42: webService: getRecord: no rows found in users
+	>> learngo/11-deferAndError.handler5
+	>> 	/home/user/learngo/11-deferAndError/wrapError.go:236
+	>> learngo/11-deferAndError.webService5
+	>> 	/home/user/learngo/11-deferAndError/wrapError.go:229
+	>> learngo/11-deferAndError.getRecord5
+	>> 	/home/user/learngo/11-deferAndError/wrapError.go:224
+	>> table: users
+	>> true
+	>> one wrap takes less than 1µs: true
+
+Putanje datoteka zavise od toga gde je program preveden. Svaki red traga je
+jedno mesto omotavanja, od spoljašnjeg ka unutrašnjem, pa se vidi ceo put
+greške, od getRecord5 do handler5.
+*/
+
 func WrappError() {
 	fmt.Println("\n --- Wrapping Error ---")
 
 	errWrapp()
 	errWrappIs()
 	errWrappAs()
+	errWrappStack()
 }
@@ -0,0 +1,422 @@
+// Package appender provides a buffered, rotating file writer that many
+// goroutines can share.
+package appender
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"learngo/09-conc/clock"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Write after Close.
+var ErrClosed = errors.New("appender: closed")
+
+// Naming selects how rotated files are named.
+type Naming int
+
+const (
+	// Numbered renames app.log to app.log.1, shifting older files to
+	// app.log.2, app.log.3 and so on. The highest number is the oldest.
+	Numbered Naming = iota
+	// Timestamped renames app.log to app.log.20240301-101500.000. If that
+	// file already exists, because two rotations happened in the same
+	// millisecond, a sequence number is added: app.log.20240301-101500.000-1.
+	Timestamped
+)
+
+// TimeLayout is the layout of the suffix of Timestamped files.
+const TimeLayout = "20060102-150405.000"
+
+// Options configures an Appender.
+type Options struct {
+	BufferSize    int           // flush when this many bytes are buffered, default 64KiB
+	FlushInterval time.Duration // flush buffered data at least this often, default 1s
+
+	MaxSize int64         // rotate before the file grows past this size, 0 means never
+	MaxAge  time.Duration // rotate files older than this, 0 means never
+	Naming  Naming
+	Keep    int // number of rotated files to keep, 0 keeps all
+
+	Perm  fs.FileMode // default 0644
+	Clock clock.Clock // default clock.Real()
+}
+
+// Stats counts what an Appender has done.
+type Stats struct {
+	Writes    int
+	Bytes     int64
+	Flushes   int
+	Rotations int
+}
+
+// Appender appends to a file through a buffer. Write is safe for concurrent
+// use and a single Write is never split between two files, so every
+// fmt.Fprintln(a, ...) ends up as one whole line.
+type Appender struct {
+	path string
+	opt  Options
+
+	mu     sync.Mutex
+	f      *os.File
+	buf    []byte
+	size   int64 // bytes in the current file, not counting buf
+	opened time.Time
+	stats  Stats
+	err    error // first write error, reported by every later call
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open opens path for appending, creating it if needed.
+func Open(path string, opt Options) (*Appender, error) {
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = 64 << 10
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = time.Second
+	}
+	if opt.Perm == 0 {
+		opt.Perm = 0644
+	}
+	if opt.Clock == nil {
+		opt.Clock = clock.Real()
+	}
+
+	a := &Appender{
+		path: path,
+		opt:  opt,
+		buf:  make([]byte, 0, opt.BufferSize),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	go a.loop()
+	return a, nil
+}
+
+func (a *Appender) open() error {
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, a.opt.Perm)
+	if err != nil {
+		return fmt.Errorf("appender: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("appender: %w", err)
+	}
+	a.f = f
+	a.size = st.Size()
+	a.opened = a.opt.Clock.Now()
+	return nil
+}
+
+// loop flushes the buffer and checks the file age every FlushInterval.
+func (a *Appender) loop() {
+	defer close(a.done)
+	for {
+		t := a.opt.Clock.NewTimer(a.opt.FlushInterval)
+		select {
+		case <-t.C():
+			a.mu.Lock()
+			if a.err == nil && !a.closed {
+				a.err = a.tick()
+			}
+			a.mu.Unlock()
+		case <-a.stop:
+			t.Stop()
+			return
+		}
+	}
+}
+
+func (a *Appender) tick() error {
+	if a.expired() {
+		return a.rotate()
+	}
+	return a.flush()
+}
+
+func (a *Appender) expired() bool {
+	return a.opt.MaxAge > 0 && a.size+int64(len(a.buf)) > 0 &&
+		a.opt.Clock.Now().Sub(a.opened) >= a.opt.MaxAge
+}
+
+// Write buffers p, flushing and rotating as needed.
+func (a *Appender) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return 0, ErrClosed
+	}
+	if a.err != nil {
+		return 0, a.err
+	}
+
+	pending := a.size + int64(len(a.buf))
+	if a.expired() || a.opt.MaxSize > 0 && pending > 0 && pending+int64(len(p)) > a.opt.MaxSize {
+		if a.err = a.rotate(); a.err != nil {
+			return 0, a.err
+		}
+	}
+
+	if len(a.buf)+len(p) > a.opt.BufferSize {
+		if a.err = a.flush(); a.err != nil {
+			return 0, a.err
+		}
+	}
+	if len(p) >= a.opt.BufferSize {
+		// Too big to buffer, write it straight through.
+		n, err := a.f.Write(p)
+		a.size += int64(n)
+		a.count(n)
+		if err != nil {
+			a.err = fmt.Errorf("appender: %w", err)
+		}
+		return n, a.err
+	}
+	a.buf = append(a.buf, p...)
+	a.count(len(p))
+	return len(p), nil
+}
+
+func (a *Appender) count(n int) {
+	a.stats.Writes++
+	a.stats.Bytes += int64(n)
+}
+
+// Flush writes the buffered data to the file.
+func (a *Appender) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+	if a.err == nil {
+		a.err = a.flush()
+	}
+	return a.err
+}
+
+func (a *Appender) flush() error {
+	if len(a.buf) == 0 {
+		return nil
+	}
+	n, err := a.f.Write(a.buf)
+	a.size += int64(n)
+	a.buf = a.buf[:0]
+	a.stats.Flushes++
+	if err != nil {
+		return fmt.Errorf("appender: %w", err)
+	}
+	return nil
+}
+
+// Rotate flushes the current file, renames it and starts a new one.
+func (a *Appender) Rotate() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+	if a.err == nil {
+		a.err = a.rotate()
+	}
+	return a.err
+}
+
+func (a *Appender) rotate() error {
+	if err := a.flush(); err != nil {
+		return err
+	}
+	if err := a.f.Close(); err != nil {
+		return fmt.Errorf("appender: %w", err)
+	}
+
+	var err error
+	if a.opt.Naming == Timestamped {
+		err = a.rotateTimestamped()
+	} else {
+		err = a.rotateNumbered()
+	}
+	if err != nil {
+		return fmt.Errorf("appender: rotate: %w", err)
+	}
+	a.stats.Rotations++
+	return a.open()
+}
+
+func (a *Appender) rotateNumbered() error {
+	last := 0
+	for {
+		if _, err := os.Lstat(a.numbered(last + 1)); err != nil {
+			break
+		}
+		last++
+	}
+	for i := last; i >= 1; i-- {
+		if a.opt.Keep > 0 && i >= a.opt.Keep {
+			if err := os.Remove(a.numbered(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Rename(a.numbered(i), a.numbered(i+1)); err != nil {
+			return err
+		}
+	}
+	return os.Rename(a.path, a.numbered(1))
+}
+
+func (a *Appender) numbered(i int) string {
+	return a.path + "." + strconv.Itoa(i)
+}
+
+func (a *Appender) rotateTimestamped() error {
+	name, err := a.timestampedName()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(a.path, name); err != nil {
+		return err
+	}
+	if a.opt.Keep <= 0 {
+		return nil
+	}
+	old, err := a.Rotated()
+	if err != nil {
+		return err
+	}
+	for len(old) > a.opt.Keep {
+		if err := os.Remove(old[0]); err != nil {
+			return err
+		}
+		old = old[1:]
+	}
+	return nil
+}
+
+// timestampedName returns the name for a file rotated now. If files with
+// the same time already exist, it adds a sequence number higher than theirs,
+// so the name stays unique and sorts after them even when Keep has removed
+// the first ones.
+func (a *Appender) timestampedName() (string, error) {
+	base := a.path + "." + a.opt.Clock.Now().Format(TimeLayout)
+	matches, err := filepath.Glob(base + "*")
+	if err != nil {
+		return "", err
+	}
+	seq := -1
+	for _, m := range matches {
+		if _, n, ok := parseTimestamped(strings.TrimPrefix(m, a.path+".")); ok {
+			seq = max(seq, n)
+		}
+	}
+	if seq < 0 {
+		return base, nil
+	}
+	return base + "-" + strconv.Itoa(seq+1), nil
+}
+
+// Rotated returns the rotated files that belong to this Appender, oldest
+// first.
+func (a *Appender) Rotated() ([]string, error) {
+	matches, err := filepath.Glob(a.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		name string
+		age  int64 // larger is older
+		seq  int   // for equal ages, smaller is older
+	}
+	var files []file
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, a.path+".")
+		if a.opt.Naming == Timestamped {
+			if t, seq, ok := parseTimestamped(suffix); ok {
+				files = append(files, file{m, -t.UnixNano(), seq})
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > 0 {
+			files = append(files, file{m, int64(n), 0})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].age != files[j].age {
+			return files[i].age > files[j].age
+		}
+		return files[i].seq < files[j].seq
+	})
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+// parseTimestamped parses the suffix of a Timestamped file, a time in
+// TimeLayout optionally followed by "-" and a sequence number.
+func parseTimestamped(suffix string) (time.Time, int, bool) {
+	if len(suffix) < len(TimeLayout) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(TimeLayout, suffix[:len(TimeLayout)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	rest := suffix[len(TimeLayout):]
+	if rest == "" {
+		return t, 0, true
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+	if !strings.HasPrefix(rest, "-") || err != nil || seq <= 0 {
+		return time.Time{}, 0, false
+	}
+	return t, seq, true
+}
+
+// Stats returns the counters collected so far.
+func (a *Appender) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// Close flushes the buffer, closes the file and stops the background
+// flusher. It returns the first error that any write, flush or rotation
+// ran into, so checking Close is enough to know whether everything written
+// reached the file.
+func (a *Appender) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrClosed
+	}
+	a.closed = true
+	close(a.stop)
+	err := a.err
+	if err == nil {
+		err = a.flush()
+	}
+	if cerr := a.f.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("appender: %w", cerr)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+	return err
+}
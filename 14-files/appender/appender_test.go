@@ -0,0 +1,170 @@
+package appender
+
+import (
+	"errors"
+	"fmt"
+	"learngo/09-conc/clock"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC)
+
+// contents returns the lines of the rotated files, oldest first, followed by
+// the lines of the current file.
+func contents(t *testing.T, a *Appender, path string) []string {
+	t.Helper()
+	files, err := a.Rotated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, name := range append(files, path) {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Fields(string(b))...)
+	}
+	return lines
+}
+
+func TestRotateBySize(t *testing.T) {
+	for _, naming := range []Naming{Numbered, Timestamped} {
+		t.Run(fmt.Sprint(naming), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			// The fake clock never moves, so all rotations happen in the
+			// same millisecond.
+			a, err := Open(path, Options{MaxSize: 10, Naming: naming, Clock: clock.NewFake(start)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+
+			var want []string
+			for i := range 5 {
+				line := fmt.Sprintf("line-%d", i)
+				want = append(want, line)
+				fmt.Fprintln(a, line)
+			}
+			if err := a.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Stats().Rotations; got != 4 {
+				t.Errorf("Rotations = %d, want 4", got)
+			}
+			if got := contents(t, a, path); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("lines = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRotatedNames(t *testing.T) {
+	tests := []struct {
+		naming Naming
+		want   []string
+	}{
+		{Numbered, []string{"app.log.3", "app.log.2", "app.log.1"}},
+		{Timestamped, []string{
+			"app.log.20240301-101500.000",
+			"app.log.20240301-101500.000-1",
+			"app.log.20240301-101501.000",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.naming), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			f := clock.NewFake(start)
+			a, err := Open(path, Options{Naming: tt.naming, Clock: f})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+
+			for i := range 3 {
+				if i == 2 {
+					f.Advance(time.Second)
+				}
+				fmt.Fprintln(a, i)
+				if err := a.Rotate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			files, err := a.Rotated()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, name := range files {
+				got = append(got, filepath.Base(name))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Rotated = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeep(t *testing.T) {
+	for _, naming := range []Naming{Numbered, Timestamped} {
+		t.Run(fmt.Sprint(naming), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			a, err := Open(path, Options{Naming: naming, Keep: 2, Clock: clock.NewFake(start)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+
+			for i := range 4 {
+				fmt.Fprintln(a, i)
+				if err := a.Rotate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := contents(t, a, path); strings.Join(got, " ") != "2 3" {
+				t.Errorf("lines = %v, want the two newest files", got)
+			}
+		})
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := clock.NewFake(start)
+	a, err := Open(path, Options{MaxAge: time.Hour, FlushInterval: time.Minute, Clock: f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	fmt.Fprintln(a, "old")
+	f.BlockUntil(1)
+	f.Advance(time.Hour)
+	fmt.Fprintln(a, "new")
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Stats().Rotations; got != 1 {
+		t.Errorf("Rotations = %d, want 1", got)
+	}
+	if got := contents(t, a, path); strings.Join(got, " ") != "old new" {
+		t.Errorf("lines = %v, want [old new]", got)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	a, err := Open(filepath.Join(t.TempDir(), "app.log"), Options{Clock: clock.NewFake(start)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Write([]byte("x\n")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want ErrClosed", err)
+	}
+}
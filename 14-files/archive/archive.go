@@ -0,0 +1,422 @@
+// Package archive reads and writes gzip files and zip and tar archives using
+// only the standard library.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"learngo/14-files/safewrite"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsafePath is returned for an entry whose name would escape the
+	// extraction directory, such as "../../etc/passwd" or "/etc/passwd".
+	ErrUnsafePath = errors.New("archive: unsafe entry name")
+	// ErrFormat is returned for a file name without a known extension.
+	ErrFormat = errors.New("archive: unknown archive format")
+	// ErrNotFound is returned by ReadFile for a missing entry.
+	ErrNotFound = errors.New("archive: entry not found")
+	// ErrNotRegular is returned by ReadFile and Extract for an entry that
+	// is neither a regular file nor a directory, such as a link.
+	ErrNotRegular = errors.New("archive: not a regular file")
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Decompress returns a reader of the uncompressed content of r if r starts
+// with the gzip magic number, and a reader of r unchanged otherwise. It
+// looks at the content rather than the name, so a renamed file still works.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(head) != string(gzipMagic) {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// Format is an archive format.
+type Format int
+
+const (
+	Zip Format = iota + 1
+	Tar
+	TarGz
+)
+
+// FormatOf returns the format of an archive from its name: .zip, .tar,
+// .tar.gz or .tgz.
+func FormatOf(name string) (Format, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return Zip, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return Tar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGz, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrFormat, name)
+}
+
+// Entry describes one file, directory or link in an archive.
+type Entry struct {
+	Name    string // slash separated, directories end in "/"
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+	Link    string // target of a symbolic or hard link
+}
+
+// IsDir reports whether the entry is a directory.
+func (e Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// IsRegular reports whether the entry is a regular file. Links, devices
+// and the like are listed, but cannot be read or extracted.
+func (e Entry) IsRegular() bool {
+	return e.Mode.IsRegular() && e.Link == ""
+}
+
+// Walk calls fn for every entry of the archive at name, in archive order.
+// For regular files r reads the content; it is only valid during the call.
+// For everything else r is nil. The pax global header that git archive
+// writes is metadata, not an entry, and is skipped.
+func Walk(name string, fn func(e Entry, r io.Reader) error) error {
+	format, err := FormatOf(name)
+	if err != nil {
+		return err
+	}
+	if format == Zip {
+		return walkZip(name, fn)
+	}
+	return walkTar(name, fn)
+}
+
+func walkZip(name string, fn func(e Entry, r io.Reader) error) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		e := Entry{Name: f.Name, Size: int64(f.UncompressedSize64), Mode: f.Mode(), ModTime: f.Modified}
+		if !e.IsRegular() {
+			if err := fn(e, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = fn(e, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(name string, fn func(e Entry, r io.Reader) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := Decompress(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		e := Entry{Name: h.Name, Size: h.Size, Mode: h.FileInfo().Mode(), ModTime: h.ModTime, Link: h.Linkname}
+		if e.IsRegular() {
+			err = fn(e, tr)
+		} else {
+			err = fn(e, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// List returns the entries of the archive at name.
+func List(name string) ([]Entry, error) {
+	var entries []Entry
+	err := Walk(name, func(e Entry, r io.Reader) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// ReadFile returns the content of the entry called file. It returns
+// ErrNotRegular if the entry is a link or another special file.
+func ReadFile(name, file string) ([]byte, error) {
+	var data []byte
+	found := false
+	err := Walk(name, func(e Entry, r io.Reader) error {
+		if e.Name != file || e.IsDir() {
+			return nil
+		}
+		if !e.IsRegular() {
+			return notRegular(e)
+		}
+		found = true
+		var err error
+		data, err = io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return fs.SkipAll
+	})
+	if err != nil && err != fs.SkipAll {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s in %s", ErrNotFound, file, name)
+	}
+	return data, nil
+}
+
+func notRegular(e Entry) error {
+	if e.Link != "" {
+		return fmt.Errorf("%w: %s is a link to %s", ErrNotRegular, e.Name, e.Link)
+	}
+	return fmt.Errorf("%w: %s (%v)", ErrNotRegular, e.Name, e.Mode.Type())
+}
+
+// SafeJoin joins dir and an entry name, returning ErrUnsafePath if the
+// result would be outside dir.
+func SafeJoin(dir, name string) (string, error) {
+	local := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if strings.Contains(name, `\`) || !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	return filepath.Join(dir, local), nil
+}
+
+// Extract writes the entries of the archive at name into dir. All entry
+// names are checked before anything is written, so an archive with an
+// unsafe name or with an entry that is not a regular file or directory
+// leaves dir untouched. Files are created through an os.Root,
+// so a symbolic link already in dir cannot send them outside of it.
+func Extract(name, dir string) error {
+	err := Walk(name, func(e Entry, r io.Reader) error {
+		if _, err := SafeJoin(dir, e.Name); err != nil {
+			return err
+		}
+		if !e.IsDir() && !e.IsRegular() {
+			return notRegular(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	return Walk(name, func(e Entry, r io.Reader) error {
+		local := filepath.FromSlash(strings.TrimSuffix(e.Name, "/"))
+		if e.IsDir() {
+			return mkdirAll(root, local)
+		}
+		if err := mkdirAll(root, filepath.Dir(local)); err != nil {
+			return err
+		}
+		f, err := root.OpenFile(local, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, e.Mode.Perm()|0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// mkdirAll is os.MkdirAll inside root.
+func mkdirAll(root *os.Root, name string) error {
+	if name == "." {
+		return nil
+	}
+	if err := mkdirAll(root, filepath.Dir(name)); err != nil {
+		return err
+	}
+	err := root.Mkdir(name, 0755)
+	if errors.Is(err, fs.ErrExist) {
+		var st fs.FileInfo
+		if st, err = root.Stat(name); err == nil && !st.IsDir() {
+			err = fmt.Errorf("archive: %s is not a directory", name)
+		}
+	}
+	return err
+}
+
+// Create writes the files under dir into a new archive at name. The format
+// comes from the name, and the archive is written with safewrite, so a
+// failure never leaves a partial archive behind. If name is inside dir, the
+// archive does not contain itself, old or new.
+func Create(name, dir string) error {
+	format, err := FormatOf(name)
+	if err != nil {
+		return err
+	}
+	skip, err := archiveFiles(name)
+	if err != nil {
+		return err
+	}
+
+	return safewrite.Write(name, safewrite.Options{}, func(w io.Writer) error {
+		// safewrite already compresses names ending in .gz.
+		if format == TarGz && !strings.HasSuffix(strings.ToLower(name), ".gz") {
+			gz := gzip.NewWriter(w)
+			return errors.Join(createTar(gz, dir, skip), gz.Close())
+		}
+		if format == Zip {
+			zw := zip.NewWriter(w)
+			return errors.Join(createZip(zw, dir, skip), zw.Close())
+		}
+		return createTar(w, dir, skip)
+	})
+}
+
+// archiveFiles returns a function reporting whether a path is the archive
+// at name or the temporary file safewrite writes it to.
+func archiveFiles(name string) (func(full string) bool, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	tmp := "." + filepath.Base(abs) + ".tmp-"
+	return func(full string) bool {
+		f, err := filepath.Abs(full)
+		if err != nil {
+			return false
+		}
+		return f == abs || filepath.Dir(f) == filepath.Dir(abs) && strings.HasPrefix(filepath.Base(f), tmp)
+	}, nil
+}
+
+// walkDir calls fn for everything under dir except dir itself and the
+// files for which skip is true, with the slash separated name relative to
+// dir.
+func walkDir(dir string, skip func(full string) bool, fn func(name, full string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, full)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !d.IsDir() && skip(full) {
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil // symlinks, devices and the like are left out
+		}
+		return fn(filepath.ToSlash(rel), full, d)
+	})
+}
+
+func createZip(zw *zip.Writer, dir string, skip func(string) bool) error {
+	return walkDir(dir, skip, func(name, full string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		h, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if d.IsDir() {
+			h.Name += "/"
+			_, err = zw.CreateHeader(h)
+			return err
+		}
+		h.Method = zip.Deflate
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		return copyFile(w, full)
+	})
+}
+
+func createTar(w io.Writer, dir string, skip func(string) bool) error {
+	tw := tar.NewWriter(w)
+	err := walkDir(dir, skip, func(name, full string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		h, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if d.IsDir() {
+			h.Name += "/"
+		}
+		h.Uname, h.Gname, h.Uid, h.Gid = "", "", 0, 0
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return copyFile(tw, full)
+	})
+	return errors.Join(err, tw.Close())
+}
+
+func copyFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
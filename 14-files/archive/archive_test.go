@@ -0,0 +1,257 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var tree = map[string]string{
+	"a.txt":         "alpha",
+	"docs/b.txt":    "beta",
+	"docs/sub/c.md": "gamma",
+}
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree returns the regular files under dir by slash separated name.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"out.zip", "out.tar", "out.tar.gz", "out.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			src, tmp := t.TempDir(), t.TempDir()
+			writeTree(t, src, tree)
+
+			archive := filepath.Join(tmp, name)
+			if err := Create(archive, src); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ReadFile(archive, "docs/b.txt")
+			if err != nil || string(data) != "beta" {
+				t.Errorf("ReadFile = %q, %v", data, err)
+			}
+
+			dst := filepath.Join(tmp, "extracted")
+			if err := Extract(archive, dst); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTree(t, dst); !reflect.DeepEqual(got, tree) {
+				t.Errorf("extracted %v, want %v", got, tree)
+			}
+		})
+	}
+}
+
+func TestCreateInsideDir(t *testing.T) {
+	for _, name := range []string{"self.zip", "self.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tree)
+			archive := filepath.Join(dir, name)
+			// Twice, so that the second run sees the first archive too.
+			for range 2 {
+				if err := Create(archive, dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := List(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				if !e.IsDir() {
+					got = append(got, e.Name)
+				}
+			}
+			sort.Strings(got)
+			if want := []string{"a.txt", "docs/b.txt", "docs/sub/c.md"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("entries = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func writeZip(t *testing.T, name string, files ...string) {
+	t.Helper()
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("pwned"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestExtractRejectsUnsafeNames(t *testing.T) {
+	for _, evil := range []string{"../evil.txt", "a/../../evil.txt", "/evil.txt", `..\evil.txt`} {
+		t.Run(evil, func(t *testing.T) {
+			tmp := t.TempDir()
+			archive := filepath.Join(tmp, "evil.zip")
+			writeZip(t, archive, "good.txt", evil)
+
+			dst := filepath.Join(tmp, "dst")
+			if err := Extract(archive, dst); !errors.Is(err, ErrUnsafePath) {
+				t.Fatalf("Extract = %v, want ErrUnsafePath", err)
+			}
+			if _, err := os.Stat(dst); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("dst was created: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(tmp, "evil.txt")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("evil.txt was written outside dst")
+			}
+		})
+	}
+}
+
+func TestExtractRejectsUnsafeTarNames(t *testing.T) {
+	tmp := t.TempDir()
+	archive := filepath.Join(tmp, "evil.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("pwned"))
+	tw.Close()
+	f.Close()
+
+	if err := Extract(archive, filepath.Join(tmp, "dst")); !errors.Is(err, ErrUnsafePath) {
+		t.Fatalf("Extract = %v, want ErrUnsafePath", err)
+	}
+}
+
+func TestExtractDoesNotFollowSymlinkOut(t *testing.T) {
+	tmp := t.TempDir()
+	outside := filepath.Join(tmp, "outside")
+	dst := filepath.Join(tmp, "dst")
+	for _, dir := range []string{outside, dst} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dst, "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	archive := filepath.Join(tmp, "link.zip")
+	writeZip(t, archive, "link/evil.txt")
+	if err := Extract(archive, dst); err == nil {
+		t.Error("Extract through a symlink out of dst succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("evil.txt was written through the symlink")
+	}
+}
+
+// writeGitArchive writes a tar like git archive does: a pax global header
+// with the commit id first, then the files, plus a symbolic and a hard link.
+func writeGitArchive(t *testing.T, name string) {
+	t.Helper()
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	headers := []*tar.Header{
+		{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "4b825dc642cb6eb9a060e54bf8d69288fbee4904"}},
+		{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "repo/a.txt", Mode: 0644, Size: 5},
+		{Typeflag: tar.TypeSymlink, Name: "repo/link", Linkname: "a.txt", Mode: 0777},
+		{Typeflag: tar.TypeLink, Name: "repo/hard", Linkname: "repo/a.txt", Mode: 0644},
+	}
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size > 0 {
+			tw.Write([]byte("alpha"))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitArchive(t *testing.T) {
+	tmp := t.TempDir()
+	archive := filepath.Join(tmp, "repo.tar")
+	writeGitArchive(t, archive)
+
+	entries, err := List(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name+" "+e.Link)
+	}
+	want := []string{"repo/ ", "repo/a.txt ", "repo/link a.txt", "repo/hard repo/a.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %q, want %q", got, want)
+	}
+
+	if data, err := ReadFile(archive, "repo/a.txt"); err != nil || string(data) != "alpha" {
+		t.Errorf("ReadFile(a.txt) = %q, %v", data, err)
+	}
+	for _, link := range []string{"repo/link", "repo/hard"} {
+		if _, err := ReadFile(archive, link); !errors.Is(err, ErrNotRegular) {
+			t.Errorf("ReadFile(%s) = %v, want ErrNotRegular", link, err)
+		}
+	}
+
+	dst := filepath.Join(tmp, "dst")
+	if err := Extract(archive, dst); !errors.Is(err, ErrNotRegular) {
+		t.Errorf("Extract = %v, want ErrNotRegular", err)
+	}
+	if _, err := os.Stat(dst); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dst was created: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Kompresija i arhive
+===================
+
+Do sada smo radili samo sa običnim datotekama. Logovi se često čuvaju
+kompresovani (app.log.gz), a više datoteka se prenosi spakovano u jednu
+arhivu (.zip, .tar.gz). Standardna biblioteka Go-a ima sve što nam treba:
+
+- "compress/gzip" - kompresija jednog toka bajtova,
+- "archive/zip" - zip arhive, sa kompresijom svake datoteke posebno,
+- "archive/tar" - tar arhive, bez kompresije; .tar.gz je tar arhiva
+  kompresovana gzip-om kao celina.
+
+gzip.Reader i gzip.Writer su obični io.Reader i io.Writer, pa se mogu umetnuti
+između datoteke i koda koji čita ili piše:
+
+	zr, err := gzip.NewReader(f)   // čita kompresovano iz f, vraća raspakovano
+	zw := gzip.NewWriter(f)        // prima nekompresovano, u f piše kompresovano
+	zw.Close()                     // obavezno, upisuje kraj toka
+
+Gzip datoteke
+-------------
+Paketi iz ovog poglavlja rade sa .gz datotekama bez ikakve promene koda koji
+ih koristi:
+
+- safewrite.Write i safewrite.WriteFile kompresuju sadržaj ako se ime
+  završava sa ".gz",
+- metode ReadAll, ReadChunks i ReadLines tipa source.Source raspakuju
+  datoteke čije se ime završava sa ".gz".
+*/
+
+package files
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"learngo/14-files/archive"
+	"learngo/14-files/safewrite"
+	"learngo/14-files/source"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func archiveGzip(dir string) {
+
+	fmt.Println("\n --- archiveGzip ---")
+
+	text := strings.Repeat("Hello World. Welcome to file handling in Go.\n", 1000)
+	for _, name := range []string{"plain.log", "packed.log.gz"} {
+		err := safewrite.WriteFile(filepath.Join(dir, name), []byte(text), safewrite.Options{})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		st, _ := os.Stat(filepath.Join(dir, name))
+
+		src := source.New(os.DirFS(dir))
+		lines := 0
+		src.ReadLines(name, func(line string) error {
+			lines++
+			return nil
+		})
+		fmt.Printf("%-14s %6d bytes on disk, %d lines\n", name, st.Size(), lines)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> plain.log       45000 bytes on disk, 1000 lines
+	>> packed.log.gz     211 bytes on disk, 1000 lines
+
+Isti tekst ponovljen 1000 puta se kompresuje na 211 bajtova, a ReadLines vraća
+iste redove iz obe datoteke.
+
+Arhive
+------
+Paket "learngo/14-files/archive" radi sa zip i tar arhivama. Format se bira
+po nastavku imena (.zip, .tar, .tar.gz ili .tgz):
+
+	archive.Create(name, dir)        // pakuje sve datoteke iz dir
+	archive.List(name)               // spisak stavki (Entry)
+	archive.ReadFile(name, file)     // sadržaj jedne stavke
+	archive.Extract(name, dir)       // raspakuje sve u dir
+
+Arhiva se upisuje pomoću safewrite, pa neuspelo pakovanje ne ostavlja
+polovičnu arhivu.
+*/
+
+// writeTree creates files, given by slash separated name, under dir. The
+// examples in archives.go, checksums.go and grepTool.go start from such a
+// tree.
+func writeTree(dir string, files map[string]string) error {
+	for name, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var archiveTree = map[string]string{
+	"readme.txt":        "Hello World. Welcome to file handling in Go.\n",
+	"logs/app.log":      "first line\nsecond line\n",
+	"logs/old/2023.log": "old line\n",
+}
+
+func archiveRoundTrip(dir string) {
+
+	fmt.Println("\n --- archiveRoundTrip ---")
+
+	in := filepath.Join(dir, "in")
+	if err := writeTree(in, archiveTree); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, name := range []string{"files.zip", "files.tar.gz"} {
+		path := filepath.Join(dir, name)
+		if err := archive.Create(path, in); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		entries, err := archive.List(path)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Print(name, ":")
+		for _, e := range entries {
+			fmt.Printf(" %s(%d)", e.Name, e.Size)
+		}
+		fmt.Println()
+
+		b, err := archive.ReadFile(path, "logs/app.log")
+		fmt.Printf("logs/app.log: %q %v\n", b, err)
+
+		out := filepath.Join(dir, "out-"+name)
+		if err := archive.Extract(path, out); err != nil {
+			fmt.Println(err)
+			return
+		}
+		b, err = os.ReadFile(filepath.Join(out, "logs", "old", "2023.log"))
+		fmt.Printf("extracted logs/old/2023.log: %q %v\n", b, err)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> files.zip: logs/(0) logs/app.log(23) logs/old/(0) logs/old/2023.log(9) readme.txt(45)
+	>> logs/app.log: "first line\nsecond line\n" <nil>
+	>> extracted logs/old/2023.log: "old line\n" <nil>
+	>> files.tar.gz: logs/(0) logs/app.log(23) logs/old/(0) logs/old/2023.log(9) readme.txt(45)
+	>> logs/app.log: "first line\nsecond line\n" <nil>
+	>> extracted logs/old/2023.log: "old line\n" <nil>
+
+Imena u arhivi uvek koriste "/", bez obzira na operativni sistem, a imena
+direktorijuma se završavaju sa "/".
+
+Opasna imena (Zip Slip)
+-----------------------
+Ime stavke u arhivi je običan string koji je upisao onaj ko je arhivu
+napravio. Ako raspakujemo stavku "../../home/user/.bashrc" naivnim
+filepath.Join(dir, name), upisaćemo datoteku van direktorijuma dir. Ovaj napad
+je poznat kao "Zip Slip".
+
+archive.SafeJoin odbija apsolutna imena, imena sa ".." koja izlaze iz
+direktorijuma i imena sa "\". Za proveru koristi filepath.IsLocal. Extract
+prvo proveri sva imena, pa tek onda upisuje, tako da iz loše arhive ne upiše
+ništa. Stavke koje nisu obične datoteke ili direktorijumi, na primer
+simboličke veze, Extract takođe odbija greškom archive.ErrNotRegular, jer veza
+može pokazivati van dir. List ih ipak prikazuje (polje Entry.Link je cilj
+veze), a ReadFile za njih vraća istu grešku. Pax globalno zaglavlje koje na
+početak arhive stavlja git archive nije stavka, pa ga Walk preskače.
+
+Ali veza može već postojati u dir, pre raspakivanja. Ako je dir/docs veza ka
+/etc, ispravno ime "docs/passwd" bi preko nje upisalo /etc/passwd. Zato
+Extract ne koristi os.OpenFile, već otvara dir kao os.Root (Go 1.24). Sve
+operacije nad os.Root ostaju unutar njega, a putanja koja kroz vezu izlazi
+van vraća grešku.
+
+Napravimo takvu arhivu ručno, paketom archive/zip.
+*/
+
+func archiveZipSlip(dir string) {
+
+	fmt.Println("\n --- archiveZipSlip ---")
+
+	path := filepath.Join(dir, "evil.zip")
+	err := safewrite.Write(path, safewrite.Options{}, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		for _, name := range []string{"good.txt", "../evil.txt"} {
+			f, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			io.WriteString(f, "content of "+name+"\n")
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	out := filepath.Join(dir, "out-evil")
+	err = archive.Extract(path, out)
+	fmt.Println(err, errors.Is(err, archive.ErrUnsafePath))
+
+	_, err = os.Stat(filepath.Join(out, "good.txt"))
+	fmt.Println("good.txt extracted:", err == nil)
+	_, err = os.Stat(filepath.Join(dir, "evil.txt"))
+	fmt.Println("evil.txt extracted:", err == nil)
+}
+
+/*
+Program ispisuje:
+
+	>> archive: unsafe entry name: "../evil.txt" true
+	>> good.txt extracted: false
+	>> evil.txt extracted: false
+*/
+
+func ArchiveFunc() {
+
+	fmt.Println("\n --- Archive Func ---")
+
+	dir, err := os.MkdirTemp("", "archive")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	archiveGzip(dir)
+	archiveRoundTrip(dir)
+	archiveZipSlip(dir)
+}
@@ -0,0 +1,173 @@
+// Package checksum hashes files, writes and verifies sha256sum compatible
+// manifests, finds duplicate files and compares directory trees. Files are
+// streamed through the hash, so memory use does not depend on their size.
+package checksum
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrUnknownAlgorithm is returned for an Algorithm that is not supported.
+var ErrUnknownAlgorithm = errors.New("checksum: unknown algorithm")
+
+// Algorithm names a hash function.
+type Algorithm string
+
+const (
+	MD5    Algorithm = "md5"
+	SHA1   Algorithm = "sha1"
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+	CRC32  Algorithm = "crc32" // IEEE, as used by zip and gzip
+)
+
+// New returns a new hash.Hash computing a.
+func (a Algorithm) New() (hash.Hash, error) {
+	switch a {
+	case MD5:
+		return md5.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	case CRC32:
+		return crc32.NewIEEE(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, string(a))
+}
+
+// Options configures the functions that work on whole trees.
+type Options struct {
+	Algorithm Algorithm // default SHA256
+	Workers   int       // files hashed at once, <= 0 means GOMAXPROCS
+}
+
+// algorithm returns the configured algorithm after checking that it is
+// supported, so that a typo is reported once and not for every file.
+func (o Options) algorithm() (Algorithm, error) {
+	a := o.Algorithm
+	if a == "" {
+		a = SHA256
+	}
+	_, err := a.New()
+	return a, err
+}
+
+// Sum returns the hex encoded hash of everything read from r.
+func Sum(r io.Reader, alg Algorithm) (string, error) {
+	sums, err := Sums(r, alg)
+	if err != nil {
+		return "", err
+	}
+	return sums[0], nil
+}
+
+// Sums reads r once and returns its hex encoded hash for every algorithm,
+// in the same order.
+func Sums(r io.Reader, algs ...Algorithm) ([]string, error) {
+	hs := make([]hash.Hash, len(algs))
+	ws := make([]io.Writer, len(algs))
+	for i, a := range algs {
+		h, err := a.New()
+		if err != nil {
+			return nil, err
+		}
+		hs[i], ws[i] = h, h
+	}
+	if _, err := io.Copy(io.MultiWriter(ws...), r); err != nil {
+		return nil, err
+	}
+	sums := make([]string, len(hs))
+	for i, h := range hs {
+		sums[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+// File returns the hex encoded hash of the named file.
+func File(name string, alg Algorithm) (string, error) {
+	return fileSum(context.Background(), name, alg)
+}
+
+func fileSum(ctx context.Context, name string, alg Algorithm) (string, error) {
+	h, err := alg.New()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, ctxReader{ctx, f}); err != nil {
+		return "", fmt.Errorf("checksum: %s: %w", name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ctxReader stops reading when ctx is cancelled, so that hashing a large
+// file does not outlive the operation it belongs to.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// file is a regular file found under a root.
+type file struct {
+	Rel  string // slash separated, relative to the root
+	Size int64
+}
+
+// walk returns the regular files under root sorted by Rel. WalkDir visits
+// "x/1" after "x.txt", since it sorts names within a directory, not paths,
+// so the result is sorted again.
+func walk(root string) ([]file, error) {
+	var files []file
+	err := filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, full)
+		if err != nil {
+			return err
+		}
+		files = append(files, file{Rel: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Rel < files[j].Rel })
+	return files, err
+}
+
+func join(root, rel string) string {
+	return filepath.Join(root, filepath.FromSlash(rel))
+}
@@ -0,0 +1,198 @@
+package checksum
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"learngo/09-conc/bounded"
+	"strings"
+)
+
+// ErrFormat is returned by ReadManifest for a line it cannot parse.
+var ErrFormat = errors.New("checksum: malformed manifest line")
+
+// Entry is one line of a manifest.
+type Entry struct {
+	Sum    string // hex encoded
+	Path   string // slash separated, relative to the root of the manifest
+	Binary bool   // written as "*path", which only matters on Windows
+}
+
+// Manifest hashes every regular file under root and returns the entries
+// sorted by path.
+func Manifest(ctx context.Context, root string, opt Options) ([]Entry, error) {
+	files, err := walk(root)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := opt.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	return bounded.ParallelMap(ctx, files, bounded.Options{Limit: opt.Workers},
+		func(ctx context.Context, f file) (Entry, error) {
+			sum, err := fileSum(ctx, join(root, f.Rel), alg)
+			return Entry{Sum: sum, Path: f.Rel}, err
+		})
+}
+
+// WriteManifest writes the entries in the format of sha256sum and friends:
+//
+//	<hex sum>  <path>
+//
+// A path containing a backslash or a line break is escaped and its line
+// starts with a backslash, as GNU coreutils do it.
+func WriteManifest(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		mode := " "
+		if e.Binary {
+			mode = "*"
+		}
+		name, escaped := escape(e.Path)
+		if escaped {
+			bw.WriteString(`\`)
+		}
+		fmt.Fprintf(bw, "%s %s%s\n", e.Sum, mode, name)
+	}
+	return bw.Flush()
+}
+
+// ReadManifest parses what WriteManifest, sha256sum, md5sum and similar
+// tools write. Empty lines are skipped.
+func ReadManifest(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		escaped := strings.HasPrefix(line, `\`)
+		if escaped {
+			line = line[1:]
+		}
+
+		sum, rest, ok := strings.Cut(line, " ")
+		if !ok || len(rest) < 2 || (rest[0] != ' ' && rest[0] != '*') {
+			return entries, fmt.Errorf("%w %d", ErrFormat, n)
+		}
+		if _, err := hex.DecodeString(sum); err != nil || sum == "" {
+			return entries, fmt.Errorf("%w %d: bad sum", ErrFormat, n)
+		}
+		e := Entry{Sum: strings.ToLower(sum), Path: rest[1:], Binary: rest[0] == '*'}
+		if escaped {
+			e.Path, ok = unescape(e.Path)
+			if !ok {
+				return entries, fmt.Errorf("%w %d: bad escape", ErrFormat, n)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+func escape(name string) (string, bool) {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return name, false
+	}
+	return escaper.Replace(name), true
+}
+
+func unescape(name string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' {
+			b.WriteByte(name[i])
+			continue
+		}
+		if i++; i == len(name) {
+			return "", false
+		}
+		switch name[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// Status is the outcome of verifying one entry.
+type Status int
+
+const (
+	OK      Status = iota
+	Failed         // the file differs
+	Missing        // the file does not exist
+	Error          // the file could not be read, see Result.Err
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Failed:
+		return "FAILED"
+	case Missing:
+		return "MISSING"
+	}
+	return "ERROR"
+}
+
+// Result is the outcome of verifying one entry.
+type Result struct {
+	Path   string
+	Status Status
+	Err    error
+}
+
+// Verify hashes the file of every entry, relative to root, and compares it
+// with the sum in the entry. The results are in the order of entries. The
+// returned error is nil if every file matched.
+func Verify(ctx context.Context, root string, entries []Entry, opt Options) ([]Result, error) {
+	alg, err := opt.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	results, err := bounded.ParallelMap(ctx, entries, bounded.Options{Limit: opt.Workers, Mode: bounded.AllErrors},
+		func(ctx context.Context, e Entry) (Result, error) {
+			sum, err := fileSum(ctx, join(root, e.Path), alg)
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				return Result{Path: e.Path, Status: Missing, Err: err}, nil
+			case ctx.Err() != nil:
+				return Result{}, err
+			case err != nil:
+				return Result{Path: e.Path, Status: Error, Err: err}, nil
+			case sum != e.Sum:
+				return Result{Path: e.Path, Status: Failed}, nil
+			}
+			return Result{Path: e.Path, Status: OK}, nil
+		})
+	if err != nil {
+		return results, err
+	}
+
+	bad := 0
+	for _, r := range results {
+		if r.Status != OK {
+			bad++
+		}
+	}
+	if bad > 0 {
+		return results, fmt.Errorf("checksum: %d of %d files did not match", bad, len(results))
+	}
+	return results, nil
+}
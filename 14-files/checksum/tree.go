@@ -0,0 +1,162 @@
+package checksum
+
+import (
+	"context"
+	"learngo/09-conc/bounded"
+	"sort"
+)
+
+// Group is a set of files with the same content.
+type Group struct {
+	Size  int64
+	Sum   string
+	Paths []string // sorted
+}
+
+// Duplicates returns the groups of files under root that have the same
+// content. Only files whose size is shared by another file are hashed, so
+// most files are never read. Empty files are not reported. The groups are
+// sorted by their first path.
+func Duplicates(ctx context.Context, root string, opt Options) ([]Group, error) {
+	files, err := walk(root)
+	if err != nil {
+		return nil, err
+	}
+
+	bySize := map[int64][]file{}
+	for _, f := range files {
+		if f.Size > 0 {
+			bySize[f.Size] = append(bySize[f.Size], f)
+		}
+	}
+	var candidates []file
+	for _, f := range files {
+		if len(bySize[f.Size]) > 1 {
+			candidates = append(candidates, f)
+		}
+	}
+
+	alg, err := opt.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	sums, err := bounded.ParallelMap(ctx, candidates, bounded.Options{Limit: opt.Workers},
+		func(ctx context.Context, f file) (string, error) {
+			return fileSum(ctx, join(root, f.Rel), alg)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		size int64
+		sum  string
+	}
+	byKey := map[key]*Group{}
+	var groups []*Group
+	for i, f := range candidates {
+		k := key{f.Size, sums[i]}
+		g := byKey[k]
+		if g == nil {
+			g = &Group{Size: f.Size, Sum: sums[i]}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		g.Paths = append(g.Paths, f.Rel)
+	}
+
+	var out []Group
+	for _, g := range groups {
+		if len(g.Paths) > 1 {
+			out = append(out, *g)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Paths[0] < out[j].Paths[0] })
+	return out, nil
+}
+
+// ChangeKind tells how a file differs between two trees.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "modified"
+}
+
+// Change is a file that differs between two trees.
+type Change struct {
+	Path string
+	Kind ChangeKind
+}
+
+// Diff compares the regular files under a and b and returns what changed
+// from a to b, sorted by path. Files of different sizes are modified
+// without being read; files of equal size are compared by hash.
+func Diff(ctx context.Context, a, b string, opt Options) ([]Change, error) {
+	af, err := walk(a)
+	if err != nil {
+		return nil, err
+	}
+	bf, err := walk(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	var same []string // in both trees with the same size
+	i, j := 0, 0
+	for i < len(af) || j < len(bf) {
+		switch {
+		case j == len(bf) || (i < len(af) && af[i].Rel < bf[j].Rel):
+			changes = append(changes, Change{af[i].Rel, Removed})
+			i++
+		case i == len(af) || bf[j].Rel < af[i].Rel:
+			changes = append(changes, Change{bf[j].Rel, Added})
+			j++
+		default:
+			if af[i].Size != bf[j].Size {
+				changes = append(changes, Change{af[i].Rel, Modified})
+			} else {
+				same = append(same, af[i].Rel)
+			}
+			i++
+			j++
+		}
+	}
+
+	alg, err := opt.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	differ, err := bounded.ParallelMap(ctx, same, bounded.Options{Limit: opt.Workers},
+		func(ctx context.Context, rel string) (bool, error) {
+			sa, err := fileSum(ctx, join(a, rel), alg)
+			if err != nil {
+				return false, err
+			}
+			sb, err := fileSum(ctx, join(b, rel), alg)
+			return sa != sb, err
+		})
+	if err != nil {
+		return nil, err
+	}
+	for k, rel := range same {
+		if differ[k] {
+			changes = append(changes, Change{rel, Modified})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
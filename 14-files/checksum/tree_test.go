@@ -0,0 +1,79 @@
+package checksum
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]string
+		want []Change
+	}{
+		{
+			name: "same",
+			a:    map[string]string{"a.txt": "a", "d/b.txt": "b"},
+			b:    map[string]string{"a.txt": "a", "d/b.txt": "b"},
+		},
+		{
+			// WalkDir visits x.txt before x/1, but x.txt < x/1 is false.
+			name: "directory sorts after file with same prefix",
+			a:    map[string]string{"x/1": "1", "x.txt": "x"},
+			b:    map[string]string{"x.txt": "x"},
+			want: []Change{{"x/1", Removed}},
+		},
+		{
+			name: "added removed modified",
+			a:    map[string]string{"keep": "k", "gone": "g", "size": "s", "hash": "h"},
+			b:    map[string]string{"keep": "k", "new": "n", "size": "ss", "hash": "H"},
+			want: []Change{{"gone", Removed}, {"hash", Modified}, {"new", Added}, {"size", Modified}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := t.TempDir(), t.TempDir()
+			writeTree(t, a, tt.a)
+			writeTree(t, b, tt.b)
+			got, err := Diff(context.Background(), a, b, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestSorted(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"x/1": "1", "x.txt": "x", "a": "a"})
+	entries, err := Manifest(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Path)
+	}
+	if want := []string{"a", "x.txt", "x/1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Manifest paths = %v, want %v", got, want)
+	}
+}
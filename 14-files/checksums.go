@@ -0,0 +1,325 @@
+/*
+Kontrolne sume
+==============
+
+Heš funkcija (hash) od proizvoljno mnogo bajtova pravi kratak niz bajtova
+fiksne dužine, kontrolnu sumu. Ista sadržina uvek daje istu sumu, a i
+najmanja promena daje potpuno drugačiju. Zato sume koristimo da proverimo da
+li je preuzeta datoteka ispravna, da nađemo datoteke istog sadržaja i da
+uporedimo dva direktorijuma bez poređenja bajt po bajt.
+
+Standardna biblioteka ima više heš funkcija i sve implementiraju interfejs
+hash.Hash, koji je io.Writer:
+
+	h := sha256.New()
+	io.Copy(h, f)                        // čita f u delovima od 32 KB
+	sum := hex.EncodeToString(h.Sum(nil))
+
+io.Copy čita datoteku deo po deo i svaki deo upisuje u heš, pa datoteka od
+nekoliko gigabajta zauzima isto memorije kao datoteka od nekoliko bajtova.
+
+Paket "learngo/14-files/checksum" podržava md5, sha1, sha256, sha512 i
+crc32:
+
+	checksum.File(name, checksum.SHA256)          // suma jedne datoteke
+	checksum.Sums(r, checksum.MD5, checksum.SHA1) // više suma u jednom čitanju
+
+md5 i sha1 više nisu bezbedni, jer je moguće namerno napraviti dve različite
+datoteke sa istom sumom. Za otkrivanje slučajnih grešaka su i dalje dobri, a
+crc32 je najbrži, ali i najslabiji.
+*/
+
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"learngo/14-files/checksum"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+func checksumFile() {
+
+	fmt.Println("\n --- checksumFile ---")
+
+	f, err := os.Open("14-files/test.txt")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+
+	algs := []checksum.Algorithm{checksum.CRC32, checksum.MD5, checksum.SHA1, checksum.SHA256}
+	sums, err := checksum.Sums(f, algs...)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i, a := range algs {
+		fmt.Printf("%-6s %s\n", a, sums[i])
+	}
+
+	_, err = checksum.File("14-files/test.txt", "sha3")
+	fmt.Println(err, errors.Is(err, checksum.ErrUnknownAlgorithm))
+}
+
+/*
+Program ispisuje:
+
+	>> crc32  e50af5b9
+	>> md5    8f4d9726e522c564a8656d0bccccc8d8
+	>> sha1   623c7543ef7f5e0ecdee4b723ae2a1a16609f336
+	>> sha256 47da926e602e1154b85b46543aef76800ef17284a9facb59c96a5b506701b465
+	>> checksum: unknown algorithm: "sha3" true
+
+Datoteka je pročitana samo jednom: io.MultiWriter svaki pročitani deo upisuje
+u sve četiri heš funkcije.
+
+Velike datoteke
+---------------
+Da bismo pokazali da memorija ne zavisi od veličine, izračunajmo sumu 256 MB
+nula, bez pravljenja ikakve datoteke, i izmerimo koliko je memorije
+alocirano.
+*/
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func checksumLarge() {
+
+	fmt.Println("\n --- checksumLarge ---")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	sum, err := checksum.Sum(io.LimitReader(zeros{}, 256<<20), checksum.SHA256)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("sha256", sum)
+	fmt.Println("allocated less than 100 KB:", after.TotalAlloc-before.TotalAlloc < 100<<10)
+}
+
+/*
+Program ispisuje:
+
+	>> sha256 a6d72ac7690f53be6ae46ba88506bd97302a093f7108472bd9efc3cefda06484
+	>> allocated less than 100 KB: true
+
+Manifest
+--------
+Unix alati sha256sum, md5sum i slični ispisuju po jedan red za svaku
+datoteku, sumu, dva razmaka i ime:
+
+	e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  a.txt
+
+Takva datoteka se zove manifest i obično se objavljuje pored datoteka za
+preuzimanje, pod imenom SHA256SUMS. Komanda "sha256sum -c SHA256SUMS" zatim
+proverava svaku datoteku iz manifesta.
+
+checksum.Manifest izračuna sume svih datoteka u direktorijumu, više
+istovremeno, pomoću bounded.ParallelMap. checksum.WriteManifest i
+checksum.ReadManifest pišu i čitaju isti format kao sha256sum, pa manifest
+napravljen u Go-u proverava sha256sum i obrnuto. checksum.Verify proverava
+manifest i za svaku datoteku vraća OK, FAILED, MISSING ili ERROR.
+*/
+
+var checksumTree = map[string]string{
+	"readme.txt":        "Hello World. Welcome to file handling in Go.\n",
+	"logs/app.log":      "first line\nsecond line\n",
+	"logs/app.log.1":    "first line\nsecond line\n",
+	"logs/old/2023.log": "old line\n",
+	"copy/readme.txt":   "Hello World. Welcome to file handling in Go.\n",
+	"copy/notes.txt":    "Hello World. Welcome to file handling in Go!\n",
+}
+
+func checksumManifest(dir string) {
+
+	fmt.Println("\n --- checksumManifest ---")
+
+	root := filepath.Join(dir, "manifest")
+	if err := writeTree(root, checksumTree); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ctx := context.Background()
+	entries, err := checksum.Manifest(ctx, root, checksum.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	sums := filepath.Join(dir, "SHA256SUMS")
+	f, err := os.Create(sums)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = errors.Join(checksum.WriteManifest(f, entries), f.Close())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	b, _ := os.ReadFile(sums)
+	fmt.Print(string(b))
+
+	// Promenimo jednu datoteku i obrišimo drugu.
+	os.WriteFile(filepath.Join(root, "readme.txt"), []byte("Hello again.\n"), 0644)
+	os.Remove(filepath.Join(root, "logs", "old", "2023.log"))
+
+	entries, err = checksum.ReadManifest(strings.NewReader(string(b)))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	results, err := checksum.Verify(ctx, root, entries, checksum.Options{})
+	for _, r := range results {
+		fmt.Printf("%s: %v\n", r.Path, r.Status)
+	}
+	fmt.Println(err)
+}
+
+/*
+Program ispisuje:
+
+	>> 2eeb38295e28640f103e25182fcb9308dbc5fb14d528a1844ad36da65eaba0ec  copy/notes.txt
+	>> 9488eb37decc2666113595654d425e7f7e7b520652bc9c6bcc9e8670a81dc057  copy/readme.txt
+	>> c2097f55f01fc297fc7f4acf21438123e06e4d409a818524428534e850642f4f  logs/app.log
+	>> c2097f55f01fc297fc7f4acf21438123e06e4d409a818524428534e850642f4f  logs/app.log.1
+	>> 9e9894bfcaf8d63b9898e90db0fe05f5a0388d45bcf60a0a208ad31153072142  logs/old/2023.log
+	>> 9488eb37decc2666113595654d425e7f7e7b520652bc9c6bcc9e8670a81dc057  readme.txt
+	>> copy/notes.txt: OK
+	>> copy/readme.txt: OK
+	>> logs/app.log: OK
+	>> logs/app.log.1: OK
+	>> logs/old/2023.log: MISSING
+	>> readme.txt: FAILED
+	>> checksum: 2 of 6 files did not match
+
+Ako na sistemu postoji sha256sum, isti manifest može da proveri i on:
+
+	$ cd manifest && sha256sum -c ../SHA256SUMS
+	copy/notes.txt: OK
+	copy/readme.txt: OK
+	logs/app.log: OK
+	logs/app.log.1: OK
+	sha256sum: logs/old/2023.log: No such file or directory
+	logs/old/2023.log: FAILED open or read
+	readme.txt: FAILED
+	sha256sum: WARNING: 1 listed file could not be read
+	sha256sum: WARNING: 1 computed checksum did NOT match
+
+Duplikati
+---------
+Da bismo našli datoteke istog sadržaja, ne moramo da računamo sumu svake
+datoteke. Datoteke različite veličine sigurno nisu iste, pa
+checksum.Duplicates prvo grupiše datoteke po veličini, koju daje
+filepath.WalkDir bez čitanja sadržaja. Sume računa samo za datoteke čiju
+veličinu ima još neka datoteka, a zatim ih grupiše po sumi.
+*/
+
+func checksumDuplicates(dir string) {
+
+	fmt.Println("\n --- checksumDuplicates ---")
+
+	root := filepath.Join(dir, "dups")
+	if err := writeTree(root, checksumTree); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	groups, err := checksum.Duplicates(context.Background(), root, checksum.Options{Workers: 4})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, g := range groups {
+		fmt.Printf("%d bytes %s...: %v\n", g.Size, g.Sum[:12], g.Paths)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> 45 bytes 9488eb37decc...: [copy/readme.txt readme.txt]
+	>> 23 bytes c2097f55f01f...: [logs/app.log logs/app.log.1]
+
+copy/notes.txt ima istu veličinu kao readme.txt, pa je i njena suma
+izračunata, ali se razlikuje u poslednjem znaku i nije prijavljena.
+
+Poređenje direktorijuma
+-----------------------
+checksum.Diff poredi dva direktorijuma i vraća datoteke koje su dodate,
+obrisane ili izmenjene. I ovde se veličina proverava pre sadržaja: datoteka
+koja je promenila veličinu je izmenjena bez čitanja, a sume se računaju samo
+za datoteke iste veličine.
+*/
+
+func checksumDiff(dir string) {
+
+	fmt.Println("\n --- checksumDiff ---")
+
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := writeTree(a, checksumTree); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := writeTree(b, checksumTree); err != nil {
+		fmt.Println(err)
+		return
+	}
+	os.WriteFile(filepath.Join(b, "logs", "app.log"), []byte("first line\nthird line\n"), 0644)
+	os.WriteFile(filepath.Join(b, "logs", "app.log.1"), []byte("first line\n"), 0644)
+	os.Remove(filepath.Join(b, "copy", "notes.txt"))
+	os.WriteFile(filepath.Join(b, "logs", "new.log"), []byte("new line\n"), 0644)
+
+	changes, err := checksum.Diff(context.Background(), a, b, checksum.Options{Algorithm: checksum.SHA1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, c := range changes {
+		fmt.Printf("%-8v %s\n", c.Kind, c.Path)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> removed  copy/notes.txt
+	>> modified logs/app.log
+	>> modified logs/app.log.1
+	>> added    logs/new.log
+
+logs/app.log.1 je promenila veličinu, pa nije čitana. logs/app.log je iste
+veličine, pa su izračunate sume obe verzije.
+*/
+
+func ChecksumFunc() {
+
+	fmt.Println("\n --- Checksum Func ---")
+
+	dir, err := os.MkdirTemp("", "checksum")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	checksumFile()
+	checksumLarge()
+	checksumManifest(dir)
+	checksumDuplicates(dir)
+	checksumDiff(dir)
+}
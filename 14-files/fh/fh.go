@@ -2,11 +2,16 @@ package fh
 
 import (
 	"fmt"
-	"os"
+	"learngo/14-files/source"
 )
 
 func ReadAll() {
-	contents, err := os.ReadFile("test.txt")
+	src, err := source.Module()
+	if err != nil {
+		fmt.Println("File reading error", err)
+		return
+	}
+	contents, err := src.ReadAll("14-files/fh/test.txt")
 	if err != nil {
 		fmt.Println("File reading error", err)
 		return
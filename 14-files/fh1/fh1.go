@@ -2,11 +2,18 @@ package fh1
 
 import (
 	"fmt"
+	"learngo/14-files/source"
 	"os"
+	"path/filepath"
 )
 
 func ReadAll() {
-	contents, err := os.ReadFile("/home/radosav/go/src/learngo/14-files/fh1/test.txt")
+	root, err := source.ModuleRoot()
+	if err != nil {
+		fmt.Println("File reading error", err)
+		return
+	}
+	contents, err := os.ReadFile(filepath.Join(root, "14-files", "fh1", "test.txt"))
 	if err != nil {
 		fmt.Println("File reading error", err)
 		return
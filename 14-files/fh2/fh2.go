@@ -3,12 +3,20 @@ package fh2
 import (
 	"flag"
 	"fmt"
+	"learngo/14-files/source"
 	"os"
+	"path/filepath"
 )
 
 func ReadAllFlag() {
 
-	fptr := flag.String("fpath", "test.txt", "file path to read from")
+	// The default is the test.txt of this package, found from the module
+	// root like in fh1, so that the program works from any directory.
+	def := "test.txt"
+	if root, err := source.ModuleRoot(); err == nil {
+		def = filepath.Join(root, "14-files", "fh2", "test.txt")
+	}
+	fptr := flag.String("fpath", def, "file path to read from")
 	flag.Parse()
 
 	fmt.Println("value of fpath is", *fptr)
@@ -0,0 +1,134 @@
+// Package flock provides advisory file locks shared between processes.
+//
+// Advisory means that the lock only protects against programs that also
+// take it: the operating system does not stop anybody from writing to a
+// locked file.
+package flock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	// ErrTimeout is returned by LockTimeout when the lock is still held by
+	// somebody else after the timeout.
+	ErrTimeout = errors.New("flock: timed out waiting for lock")
+	// ErrUnsupported is returned on platforms without an implementation.
+	ErrUnsupported = errors.New("flock: not supported on this platform")
+)
+
+// Mode is the kind of lock.
+type Mode int
+
+const (
+	// Shared locks can be held by many processes at once, for reading.
+	Shared Mode = iota
+	// Exclusive locks are held by one process, for writing.
+	Exclusive
+)
+
+func (m Mode) String() string {
+	if m == Shared {
+		return "shared"
+	}
+	return "exclusive"
+}
+
+// Method is the system call used to take the lock.
+type Method int
+
+const (
+	// Flock locks the whole file with flock(2). The lock belongs to the open
+	// file, so two descriptors opened in the same process exclude each other.
+	Flock Method = iota
+	// Fcntl locks the whole file with fcntl(2) F_SETLK, the POSIX record
+	// lock that also works over NFS. The lock belongs to the process: it is
+	// released when the process closes any descriptor of the file, and it
+	// never excludes another descriptor in the same process.
+	Fcntl
+)
+
+// Locker locks an open file.
+type Locker struct {
+	f      *os.File
+	method Method
+}
+
+// New returns a Locker for f. For Fcntl, f must be open for reading to take
+// a shared lock and for writing to take an exclusive one.
+func New(f *os.File, method Method) *Locker {
+	return &Locker{f: f, method: method}
+}
+
+// Lock waits until it gets the lock. It returns nil only when it holds it.
+func (l *Locker) Lock(mode Mode) error {
+	ok, err := lock(l.f, l.method, mode, true)
+	if err == nil && !ok {
+		err = errors.New("lock not acquired")
+	}
+	return l.wrap("lock", err)
+}
+
+// TryLock takes the lock if nobody else holds it and reports whether it
+// did.
+func (l *Locker) TryLock(mode Mode) (bool, error) {
+	ok, err := lock(l.f, l.method, mode, false)
+	return ok, l.wrap("lock", err)
+}
+
+// LockTimeout tries to take the lock until timeout passes, then returns
+// ErrTimeout.
+func (l *Locker) LockTimeout(mode Mode, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	wait := time.Millisecond
+	for {
+		ok, err := l.TryLock(mode)
+		if err != nil || ok {
+			return err
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return fmt.Errorf("%w: %s lock on %s", ErrTimeout, mode, l.f.Name())
+		}
+		time.Sleep(min(wait, left))
+		wait = min(2*wait, 50*time.Millisecond)
+	}
+}
+
+// Unlock releases the lock. Closing the file releases it as well.
+func (l *Locker) Unlock() error {
+	return l.wrap("unlock", unlock(l.f, l.method))
+}
+
+func (l *Locker) wrap(op string, err error) error {
+	if err == nil || errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	return &os.PathError{Op: op, Path: l.f.Name(), Err: err}
+}
+
+// WithLock runs fn while holding a lock on the file path+".lock", created if
+// needed. A separate lock file is needed when fn replaces path, as a safe
+// write does: a lock on the old file would not stop anybody from locking the
+// new one. A timeout <= 0 waits forever.
+func WithLock(path string, mode Mode, timeout time.Duration, fn func() error) error {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	l := New(f, Flock)
+	if timeout > 0 {
+		err = l.LockTimeout(mode, timeout)
+	} else {
+		err = l.Lock(mode)
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(fn(), l.Unlock())
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package flock
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+func lock(f *os.File, method Method, mode Mode, block bool) (bool, error) {
+	var err error
+	if method == Fcntl {
+		typ := int16(syscall.F_RDLCK)
+		if mode == Exclusive {
+			typ = syscall.F_WRLCK
+		}
+		cmd := syscall.F_SETLK
+		if block {
+			cmd = syscall.F_SETLKW
+		}
+		err = fcntl(f, cmd, typ)
+	} else {
+		how := syscall.LOCK_SH
+		if mode == Exclusive {
+			how = syscall.LOCK_EX
+		}
+		if !block {
+			how |= syscall.LOCK_NB
+		}
+		err = retry(func() error { return syscall.Flock(int(f.Fd()), how) })
+	}
+
+	// Only a non-blocking call reports a busy lock this way. A blocking one
+	// returns when it has the lock, so any error there is a real failure.
+	if !block && (errors.Is(err, syscall.EWOULDBLOCK) || errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EACCES)) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(f *os.File, method Method) error {
+	if method == Fcntl {
+		return fcntl(f, syscall.F_SETLK, syscall.F_UNLCK)
+	}
+	return retry(func() error { return syscall.Flock(int(f.Fd()), syscall.LOCK_UN) })
+}
+
+func fcntl(f *os.File, cmd int, typ int16) error {
+	lk := syscall.Flock_t{Type: typ, Whence: io.SeekStart, Start: 0, Len: 0} // Len 0 is the whole file
+	return retry(func() error { return syscall.FcntlFlock(f.Fd(), cmd, &lk) })
+}
+
+// retry repeats a blocking call interrupted by a signal.
+func retry(call func() error) error {
+	for {
+		if err := call(); err != syscall.EINTR {
+			return err
+		}
+	}
+}
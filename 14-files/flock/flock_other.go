@@ -0,0 +1,13 @@
+//go:build !linux
+
+package flock
+
+import "os"
+
+func lock(f *os.File, method Method, mode Mode, block bool) (bool, error) {
+	return false, ErrUnsupported
+}
+
+func unlock(f *os.File, method Method) error {
+	return ErrUnsupported
+}
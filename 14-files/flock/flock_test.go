@@ -0,0 +1,173 @@
+//go:build linux
+
+package flock
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The test binary runs itself as a child process to hold locks, since
+// fcntl locks never exclude each other within one process.
+const (
+	childEnv  = "FLOCK_TEST_CHILD" // what the child does: hold, try or count
+	pathEnv   = "FLOCK_TEST_PATH"
+	methodEnv = "FLOCK_TEST_METHOD"
+)
+
+func TestMain(m *testing.M) {
+	if what := os.Getenv(childEnv); what != "" {
+		if err := child(what, os.Getenv(pathEnv), os.Getenv(methodEnv)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func child(what, path, method string) error {
+	m := Flock
+	if method == "fcntl" {
+		m = Fcntl
+	}
+	switch what {
+	case "hold":
+		// Take the lock, say so, and keep it until stdin is closed.
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := New(f, m).Lock(Exclusive); err != nil {
+			return err
+		}
+		fmt.Println("locked")
+		bufio.NewReader(os.Stdin).ReadString('\n')
+		return nil
+	case "try":
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		ok, err := New(f, m).TryLock(Exclusive)
+		fmt.Println(ok)
+		return err
+	case "count":
+		for range 50 {
+			err := WithLock(path, Exclusive, 0, func() error {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				n, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+				return os.WriteFile(path, []byte(strconv.Itoa(n+1)), 0644)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown child %q", what)
+}
+
+func command(what, path, method string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), childEnv+"="+what, pathEnv+"="+path, methodEnv+"="+method)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+func TestExclusiveAcrossProcesses(t *testing.T) {
+	for _, method := range []string{"flock", "fcntl"} {
+		t.Run(method, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data")
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			holder := command("hold", path, method)
+			release, err := holder.StdinPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := holder.StdoutPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := holder.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer holder.Process.Kill()
+			if line, _ := bufio.NewReader(out).ReadString('\n'); line != "locked\n" {
+				t.Fatalf("holder said %q", line)
+			}
+
+			try := func() string {
+				b, err := command("try", path, method).Output()
+				if err != nil {
+					t.Fatal(err)
+				}
+				return strings.TrimSpace(string(b))
+			}
+			if got := try(); got != "false" {
+				t.Errorf("TryLock while held = %s, want false", got)
+			}
+
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			m := Flock
+			if method == "fcntl" {
+				m = Fcntl
+			}
+			if err := New(f, m).LockTimeout(Exclusive, 50*time.Millisecond); !errors.Is(err, ErrTimeout) {
+				t.Errorf("LockTimeout while held = %v, want ErrTimeout", err)
+			}
+
+			release.Close()
+			if err := holder.Wait(); err != nil {
+				t.Fatal(err)
+			}
+			if got := try(); got != "true" {
+				t.Errorf("TryLock after release = %s, want true", got)
+			}
+		})
+	}
+}
+
+func TestWithLockCounter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	if err := os.WriteFile(path, []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = command("count", path, "flock").Run()
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "100" {
+		t.Errorf("counter = %s, want 100", b)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Jedan API za sve izvore datoteka
+================================
+
+U lekciji o čitanju datoteka videli smo četiri načina da dođemo do test.txt:
+
+- fh.ReadAll čita "test.txt" relativno u odnosu na tekući direktorijum
+  (CWD), pa radi samo ako je program pokrenut iz 14-files/fh,
+- fh1.ReadAll koristi apsolutni put,
+- fh2.ReadAllFlag put dobija iz zastave komandne linije,
+- fh3.ReadAllEmbed ugrađuje datoteku u izvršnu datoteku.
+
+"ReadChunkByChunk" i "ReadLineByLine" su koristili "./14-files/test.txt", što
+radi samo kada je program pokrenut iz korena repozitorijuma.
+
+Svaki od ovih načina zahteva drugačiji kod za čitanje. Od verzije 1.16, Go ima
+paket "io/fs" sa interfejsom koji predstavlja sistem datoteka samo za čitanje:
+
+	type FS interface {
+		Open(name string) (fs.File, error)
+	}
+
+Ovaj interfejs implementiraju:
+
+- os.DirFS(dir) - pravi direktorijum na disku,
+- embed.FS - datoteke ugrađene direktivom //go:embed,
+- fstest.MapFS - datoteke u memoriji, odlično za testove,
+- zip.Reader - sadržaj zip arhive,
+
+i još mnogi drugi. Ako naš kod čita iz fs.FS, ne zanima ga odakle datoteke
+stvarno dolaze.
+
+Imena u fs.FS uvek koriste kosu crtu "/", nikada ne počinju sa "/" i ne sadrže
+".." (proverava ih fs.ValidPath). Zato su uvek relativna u odnosu na koren
+sistema datoteka, a ne u odnosu na tekući direktorijum.
+
+Paket source
+------------
+Paket "learngo/14-files/source" obavija fs.FS tipom Source sa metodama:
+
+	ReadAll(name string) ([]byte, error)
+	ReadChunks(name string, size int, fn func(chunk []byte) error) error
+	ReadLines(name string, fn func(line string) error) error
+	Open(name string) (fs.File, error)
+
+Funkcija source.Module() vraća Source čiji je koren direktorijum modula
+learngo - onaj u kome je go.mod. Traži ga redom: u promenljivoj okruženja
+LEARNGO_ROOT, pa od direktorijuma u kome je paket source kompajliran, od
+direktorijuma izvršne datoteke i na kraju od tekućeg direktorijuma, penjući
+se naviše dok ne nađe go.mod sa redom "module learngo".
+
+Pogledajmo isti kod nad tri različita izvora.
+*/
+
+package files
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"learngo/14-files/source"
+	"strings"
+)
+
+//go:embed test.txt
+var embedded embed.FS
+
+func printFirstLine(name string, src *source.Source, file string) {
+	first := ""
+	err := src.ReadLines(file, func(line string) error {
+		first = line
+		return fs.SkipAll
+	})
+	if err != nil && err != fs.SkipAll {
+		fmt.Printf("%-8s error: %v\n", name, err)
+		return
+	}
+	fmt.Printf("%-8s %s\n", name, first)
+}
+
+// memoryZip returns a Source reading files from a zip archive built in
+// memory.
+func memoryZip(files map[string]string) (*source.Source, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, text := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+	return source.New(zr), nil
+}
+
+func readFromFS() {
+
+	fmt.Println("\n --- readFromFS ---")
+
+	disk, err := source.Module()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	mem, err := memoryZip(map[string]string{
+		"14-files/test.txt": "Zdravo svete. Dobrodošli u rad sa datotekama.\n",
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	printFirstLine("disk", disk, "14-files/test.txt")
+	printFirstLine("embed", source.New(embedded), "test.txt")
+	printFirstLine("memory", mem, "14-files/test.txt")
+}
+
+/*
+Program ispisuje:
+
+	>> disk     Hello World. Welcome to file handling in Go.
+	>> embed    Hello World. Welcome to file handling in Go.
+	>> memory   Zdravo svete. Dobrodošli u rad sa datotekama.
+
+Funkcija "printFirstLine" ne zna da li čita sa diska, iz izvršne datoteke ili
+iz memorije. Vraćanjem fs.SkipAll iz fn prekidamo čitanje posle prvog reda.
+
+Izvor u memoriji je zip arhiva koju "memoryZip" napravi u bytes.Buffer, jer
+*zip.Reader implementira fs.FS. fstest.MapFS bi bio kraći, ali paket
+testing/fstest je namenjen testovima i ne treba ga uvoziti u običan kod.
+
+Primetite da embed.FS sadrži datoteku pod imenom "test.txt", jer je direktiva
+//go:embed relativna u odnosu na direktorijum paketa. Ako želimo ista imena
+kao na disku, možemo napraviti pod-izvor metodom Sub:
+
+	sub, err := disk.Sub("14-files")
+	sub.ReadAll("test.txt")
+
+Čitanje u delovima
+------------------
+ReadChunks je "ReadChunkByChunk" spakovan u funkciju. Slice koji dobija fn se
+ponovo koristi, pa ga treba kopirati ako nam treba posle poziva.
+*/
+
+func readChunksFromFS() {
+
+	fmt.Println("\n --- readChunksFromFS ---")
+
+	src := source.New(embedded)
+	var chunks []string
+	err := src.ReadChunks("test.txt", 8, func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(len(chunks), "chunks, first three:", strings.Join(chunks[:3], "|"))
+
+	_, err = src.ReadAll("../test.txt")
+	fmt.Println(err)
+}
+
+/*
+Program ispisuje:
+
+	>> 15 chunks, first three: Hello Wo|rld. Wel|come to
+	>> open ../test.txt: file does not exist
+
+Ime "../test.txt" nije validno ime u fs.FS, pa embed.FS kaže da takva
+datoteka ne postoji. os.DirFS bi za isto ime vratio "invalid argument", pre
+bilo kakvog pristupa disku. U oba slučaja izlazak iz korena izvora nije
+moguć.
+*/
+
+func FSFunc() {
+
+	fmt.Println("\n --- FS Func ---")
+
+	readFromFS()
+	readChunksFromFS()
+}
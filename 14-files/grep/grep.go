@@ -0,0 +1,284 @@
+// Package grep searches the text files of a directory tree concurrently.
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"learngo/09-conc/bounded"
+	"learngo/14-files/textio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// binaryPeek is how many leading bytes are checked for a NUL byte to tell
+// a binary file, the same heuristic git and GNU grep use.
+const binaryPeek = 8000
+
+// maxLineLen caps the part of a line that is kept and searched.
+const maxLineLen = 1 << 20
+
+// Options configures Search.
+type Options struct {
+	Pattern    string
+	Regexp     bool // Pattern is a regular expression instead of a literal
+	IgnoreCase bool
+	Context    int // lines of context before and after every match
+	Workers    int // files searched at once, <= 0 means GOMAXPROCS
+}
+
+// Line is a line of context around a match.
+type Line struct {
+	Number int    `json:"line"`
+	Text   string `json:"text"`
+}
+
+// Match is a line that contains the pattern.
+type Match struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Col    int    `json:"col"` // 1-based byte column of the first match
+	Text   string `json:"text"`
+	Before []Line `json:"before,omitempty"`
+	After  []Line `json:"after,omitempty"`
+}
+
+// File is the result of searching one file.
+type File struct {
+	Path    string
+	Matches []Match
+}
+
+// matcher returns the byte index of the first match in line, or -1.
+type matcher func(line string) int
+
+func compile(opt Options) (matcher, error) {
+	if opt.Pattern == "" {
+		return nil, errors.New("grep: empty pattern")
+	}
+	if !opt.Regexp && !opt.IgnoreCase {
+		return func(line string) int { return strings.Index(line, opt.Pattern) }, nil
+	}
+	expr := opt.Pattern
+	if !opt.Regexp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if opt.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("grep: %w", err)
+	}
+	return func(line string) int {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			return -1
+		}
+		return loc[0]
+	}, nil
+}
+
+// Search searches the files under root, skipping binary files, the .git
+// directory and paths ignored by .gitignore files. The results are sorted
+// by path and contain only files with matches. Paths are relative to root.
+// Files and directories that cannot be read are reported in the returned
+// error, next to the results of all the others. Only a root that cannot be
+// read stops the search.
+func Search(ctx context.Context, root string, opt Options) ([]File, error) {
+	match, err := compile(opt)
+	if err != nil {
+		return nil, err
+	}
+	paths, walkErrs, err := walk(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := bounded.ParallelMap(ctx, paths, bounded.Options{Limit: opt.Workers, Mode: bounded.AllErrors},
+		func(ctx context.Context, rel string) (File, error) {
+			return searchFile(filepath.Join(root, filepath.FromSlash(rel)), rel, match, opt.Context)
+		})
+
+	var out []File
+	for _, f := range files {
+		if len(f.Matches) > 0 {
+			out = append(out, f)
+		}
+	}
+	return out, errors.Join(append(walkErrs, err)...)
+}
+
+// walk returns the slash separated paths of the regular files under root
+// that are not ignored, sorted. WalkDir alone would put "a/b.go" before
+// "a.go", since it sorts the names within each directory. Directories below
+// root that cannot be read, or whose .gitignore cannot be read, are skipped
+// and their errors returned in errs; err is only set if root itself cannot
+// be walked.
+func walk(root string) (paths []string, errs []error, err error) {
+	var ig ignorer
+	err = filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if full == root {
+				return err
+			}
+			errs = append(errs, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, full)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel == "." {
+				return ig.load(full, "")
+			}
+			if d.Name() == ".git" || ig.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if err := ig.load(full, rel); err != nil {
+				// Without its rules we do not know what to skip.
+				errs = append(errs, err)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && !ig.ignored(rel, false) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, errs, err
+}
+
+func searchFile(full, rel string, match matcher, context int) (File, error) {
+	res := File{Path: rel}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(binaryPeek)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return res, nil
+	}
+
+	var (
+		before []Line // up to context lines not yet printed with a match
+		cur    = -1   // index of the match still collecting lines after it
+	)
+	lr := textio.NewLineReader(br, textio.LineOptions{MaxLen: maxLineLen, Truncate: true})
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", rel, err)
+		}
+
+		if col := match(l.Text); col >= 0 {
+			res.Matches = append(res.Matches, Match{
+				Path:   rel,
+				Line:   l.Number,
+				Col:    col + 1,
+				Text:   l.Text,
+				Before: before,
+			})
+			before = nil
+			cur = len(res.Matches) - 1
+			continue
+		}
+		if context == 0 {
+			continue
+		}
+
+		line := Line{Number: l.Number, Text: l.Text}
+		if cur >= 0 && len(res.Matches[cur].After) < context {
+			res.Matches[cur].After = append(res.Matches[cur].After, line)
+			continue
+		}
+		if len(before) == context {
+			before = before[1:]
+		}
+		before = append(before, line)
+	}
+}
+
+// PrintText writes the matches as "path:line:col: text". With context,
+// the lines around them are written as "path-line- text", and "--"
+// separates groups of lines that are not next to each other. A line is
+// never written twice, so when matches are close together the context of
+// one ends where the next begins.
+func PrintText(w io.Writer, files []File, context int) error {
+	bw := bufio.NewWriter(w)
+	first := true
+	for _, f := range files {
+		last := -1
+		for _, m := range f.Matches {
+			start := m.Line - len(m.Before)
+			if context > 0 && !first && (last < 0 || start > last+1) {
+				fmt.Fprintln(bw, "--")
+			}
+			first = false
+			for _, l := range m.Before {
+				fmt.Fprintf(bw, "%s-%d- %s\n", f.Path, l.Number, l.Text)
+			}
+			fmt.Fprintf(bw, "%s:%d:%d: %s\n", f.Path, m.Line, m.Col, m.Text)
+			for _, l := range m.After {
+				fmt.Fprintf(bw, "%s-%d- %s\n", f.Path, l.Number, l.Text)
+			}
+			last = m.Line + len(m.After)
+		}
+	}
+	return bw.Flush()
+}
+
+// PrintCounts writes "path:count" for every file.
+func PrintCounts(w io.Writer, files []File) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range files {
+		fmt.Fprintf(bw, "%s:%d\n", f.Path, len(f.Matches))
+	}
+	return bw.Flush()
+}
+
+// PrintJSON writes one JSON object per match, or per file with counts.
+func PrintJSON(w io.Writer, files []File, counts bool) error {
+	enc := json.NewEncoder(w)
+	for _, f := range files {
+		if counts {
+			c := struct {
+				Path  string `json:"path"`
+				Count int    `json:"count"`
+			}{f.Path, len(f.Matches)}
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, m := range f.Matches {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
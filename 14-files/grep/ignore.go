@@ -0,0 +1,123 @@
+package grep
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// rule is one line of a .gitignore file.
+type rule struct {
+	base     string   // directory of the .gitignore, relative to the root, "" for the root
+	segments []string // pattern split on "/"
+	negate   bool     // "!pattern" re-includes what an earlier rule ignored
+	dirOnly  bool     // "pattern/" matches only directories
+	anchored bool     // a pattern with a "/" before its end matches from base only
+}
+
+// ignorer holds the rules of every .gitignore seen so far. Rules of deeper
+// directories come later and win, as in git.
+type ignorer struct {
+	rules []rule
+}
+
+// load adds the rules of dir/.gitignore, if there is one. rel is dir
+// relative to the root.
+func (ig *ignorer) load(dir, rel string) error {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := rule{base: rel}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`) // "\#file" and "\!file"
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.segments = strings.Split(line, "/")
+		ig.rules = append(ig.rules, r)
+	}
+	return sc.Err()
+}
+
+// ignored reports whether the slash separated path rel, relative to the
+// root, is ignored.
+func (ig *ignorer) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range ig.rules {
+		if r.match(rel, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (r rule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	sub := rel
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		sub = rel[len(r.base)+1:]
+	}
+	if !r.anchored {
+		// A pattern without a slash matches a name at any depth. Parents
+		// that match were already skipped by the walk, so the last
+		// element is all that is left to check.
+		ok, _ := path.Match(r.segments[0], path.Base(sub))
+		return ok
+	}
+	return matchSegments(r.segments, strings.Split(sub, "/"))
+}
+
+// matchSegments matches a path against a pattern, both split on "/". A
+// "**" segment matches any number of path segments.
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
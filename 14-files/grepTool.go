@@ -0,0 +1,173 @@
+/*
+Alat grep
+=========
+
+U ovom poglavlju smo naučili da čitamo datoteku red po red, a u poglavlju o
+konkurentnosti da ograničimo broj gorutina koje rade istovremeno. Zajedno
+su dovoljni za pravi alat: pretragu teksta u svim datotekama direktorijuma,
+kao što radi Unix komanda grep.
+
+Paket "learngo/14-files/grep" radi sledeće:
+
+	1. Obiđe stablo direktorijuma funkcijom filepath.WalkDir. Preskače
+	   direktorijum .git i sve što je navedeno u .gitignore datotekama.
+	   Poddirektorijum koji ne može da pročita takođe preskače, a grešku
+	   vraća uz rezultate ostalih datoteka.
+	2. Svaku datoteku pretražuje posebna gorutina, a bounded.ParallelMap
+	   ograničava koliko ih radi odjednom.
+	3. Datoteku čita red po red tipom textio.LineReader, pa redovi mogu biti
+	   proizvoljno dugi. Datoteka koja u prvih 8000 bajtova ima bajt 0 je
+	   binarna i preskače se, kao u git-u.
+	4. ParallelMap vraća rezultate istim redosledom kojim su datoteke date,
+	   a datoteke su posle obilaska sortirane po celoj putanji. WalkDir sam
+	   sortira samo imena unutar jednog direktorijuma, pa bi "a/b.go" došlo
+	   pre "a.go". Zato je izlaz uvek isti, bez obzira na to koja se
+	   gorutina prva završila.
+
+Obrazac u .gitignore može biti ime ("*.log"), put ("build/out", "/vendor"),
+direktorijum ("tmp/"), "**" za bilo koji broj direktorijuma i "!" za
+izuzetak. Pravila iz dubljih .gitignore datoteka važe samo u svom
+direktorijumu i imaju prednost.
+
+Alat se pokreće kao podkomanda:
+
+	learngo grep [-re] [-i] [-c] [-C n] [-j n] [-json] pattern [dir]
+
+-re znači da je pattern regularni izraz, -i ne razlikuje mala i velika slova,
+-c ispisuje samo broj pogodaka po datoteci, -C n ispisuje n redova konteksta,
+-j n pretražuje n datoteka istovremeno, a -json ispisuje po jedan JSON objekat
+u redu.
+*/
+
+package files
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"learngo/14-files/grep"
+	"os"
+	"runtime"
+)
+
+// Grep runs the "grep" command with the given arguments and writes the
+// results to w.
+func Grep(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("grep", flag.ContinueOnError)
+	var opt grep.Options
+	flags.BoolVar(&opt.Regexp, "re", false, "pattern is a regular expression")
+	flags.BoolVar(&opt.IgnoreCase, "i", false, "ignore case")
+	flags.IntVar(&opt.Context, "C", 0, "lines of context")
+	flags.IntVar(&opt.Workers, "j", runtime.NumCPU(), "files searched at once")
+	count := flags.Bool("c", false, "print only the number of matching lines")
+	asJSON := flags.Bool("json", false, "print JSON lines")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: learngo grep [flags] pattern [dir]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return errors.New("grep: wrong number of arguments")
+	}
+	opt.Pattern = flags.Arg(0)
+	root := "."
+	if flags.NArg() == 2 {
+		root = flags.Arg(1)
+	}
+
+	files, err := grep.Search(context.Background(), root, opt)
+
+	var perr error
+	switch {
+	case *asJSON:
+		perr = grep.PrintJSON(w, files, *count)
+	case *count:
+		perr = grep.PrintCounts(w, files)
+	default:
+		perr = grep.PrintText(w, files, opt.Context)
+	}
+	return errors.Join(err, perr)
+}
+
+var grepTree = map[string]string{
+	".gitignore":       "*.log\n/build/\n",
+	"main.go":          "package main\n\nfunc main() {\n\t// TODO: flags\n\trun()\n}\n",
+	"run.go":           "package main\n\nfunc run() {\n\t// todo: errors\n}\n",
+	"app.log":          "TODO in a log file\n",
+	"build/out.txt":    "TODO in build output\n",
+	"docs/.gitignore":  "draft.md\n",
+	"docs/draft.md":    "TODO: write docs\n",
+	"docs/readme.md":   "Nothing to do.\nStill TODO: examples\n",
+	"assets/logo.png":  "\x89PNG\r\n\x1a\n\x00\x00TODO",
+	"docs/build/a.txt": "TODO not ignored, /build/ is anchored to the root\n",
+}
+
+func grepRun() {
+
+	fmt.Println("\n --- grepRun ---")
+
+	dir, err := os.MkdirTemp("", "grep")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	if err := writeTree(dir, grepTree); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, args := range [][]string{
+		{"TODO", dir},
+		{"-i", "-c", "todo", dir},
+		{"-re", "-C", "1", `func \w+\(\)`, dir},
+		{"-json", "-re", "TODO: [a-z]+$", dir},
+	} {
+		fmt.Println("$ learngo grep", args[:len(args)-1])
+		if err := Grep(args, os.Stdout); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> $ learngo grep [TODO]
+	>> docs/build/a.txt:1:1: TODO not ignored, /build/ is anchored to the root
+	>> docs/readme.md:2:7: Still TODO: examples
+	>> main.go:4:5: 	// TODO: flags
+	>> $ learngo grep [-i -c todo]
+	>> docs/build/a.txt:1
+	>> docs/readme.md:1
+	>> main.go:1
+	>> run.go:1
+	>> $ learngo grep [-re -C 1 func \w+\(\)]
+	>> main.go-2-
+	>> main.go:3:1: func main() {
+	>> main.go-4- 	// TODO: flags
+	>> --
+	>> run.go-2-
+	>> run.go:3:1: func run() {
+	>> run.go-4- 	// todo: errors
+	>> $ learngo grep [-json -re TODO: [a-z]+$]
+	>> {"path":"docs/readme.md","line":2,"col":7,"text":"Still TODO: examples"}
+	>> {"path":"main.go","line":4,"col":5,"text":"\t// TODO: flags"}
+
+app.log i build/ su navedeni u .gitignore, docs/draft.md u docs/.gitignore, a
+logo.png je binarna datoteka, pa ništa od toga nije pretraženo. Obrazac
+"/build/" počinje kosom crtom, pa važi samo za build u korenu, a ne i za
+docs/build.
+*/
+
+func GrepFunc() {
+
+	fmt.Println("\n --- Grep Func ---")
+
+	grepRun()
+}
@@ -0,0 +1,138 @@
+/*
+Indeks redova
+=============
+
+Da bismo pročitali 12345. red datoteke, "ReadLineByLine" mora da pročita sve
+redove pre njega. Za datoteku od nekoliko gigabajta to traje sekundama, a ako
+listamo log stranu po stranu, čitamo iste redove iznova.
+
+Datoteka na disku dozvoljava skok na bilo koji bajt metodom Seek:
+
+	f.Seek(offset, io.SeekStart)
+
+Problem je što ne znamo na kom bajtu počinje red n, jer redovi nisu iste
+dužine. Rešenje je indeks: jednom pročitamo celu datoteku i zapamtimo
+poziciju (offset) svakog N-tog reda. Da bismo pročitali red n, skočimo na
+najbliži zapamćeni red pre njega i preskočimo najviše N-1 redova.
+
+Paket "learngo/14-files/lineindex" čuva indeks u posebnoj datoteci (sidecar)
+sa nastavkom ".idx" pored indeksirane datoteke:
+
+	ix, change, err := lineindex.Open("app.log", 1000)
+	line, err := ix.ReadLine(12345)
+	lines, err := ix.ReadRange(12345, 12354)
+
+Za N = 1000 indeks zauzima 8 bajtova na svakih 1000 redova.
+*/
+
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"learngo/14-files/lineindex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func writeBigLog(path string, from, to int) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(w, "%06d request handled in %dms\n", i, i%97)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func lineIndexRead() {
+
+	fmt.Println("\n --- lineIndexRead ---")
+
+	dir, err := os.MkdirTemp("", "lineindex")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	if err := writeBigLog(path, 1, 200000); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ix, change, err := lineindex.Open(path, 1000)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("index", change, "lines:", ix.Len())
+
+	line, _ := ix.ReadLine(123456)
+	fmt.Println(line)
+
+	lines, _ := ix.ReadRange(199999, 200005)
+	fmt.Println(len(lines), "lines:", lines)
+
+	_, err = ix.ReadLine(200001)
+	fmt.Println(err)
+
+	_, change, _ = lineindex.Open(path, 1000)
+	fmt.Println("opened again:", change)
+
+	writeBigLog(path, 200001, 200010)
+	change, _ = ix.Update()
+	line, _ = ix.ReadLine(200010)
+	fmt.Println("after append:", change, ix.Len(), line)
+
+	time.Sleep(10 * time.Millisecond) // make sure the modification time changes
+	os.Remove(path)
+	writeBigLog(path, 1, 10)
+	change, _ = ix.Update()
+	fmt.Println("after rewrite:", change, ix.Len())
+}
+
+/*
+Program ispisuje:
+
+	>> index rebuilt lines: 200000
+	>> 123456 request handled in 72ms
+	>> 2 lines: [199999 request handled in 82ms 200000 request handled in 83ms]
+	>> lineindex: line out of range: 200001-200001 of 200000
+	>> opened again: unchanged
+	>> after append: updated 200010 200010 request handled in 93ms
+	>> after rewrite: rebuilt 10
+
+Kada sidecar datoteka ne postoji, Open pravi indeks od početka ("rebuilt").
+Drugi Open samo učita indeks, jer se datoteka nije promenila ("unchanged").
+
+Zastareli indeks
+----------------
+Za svaku datoteku indeks pamti njenu veličinu i vreme poslednje izmene (mtime).
+Ako su isti, indeks je ispravan. Ako se datoteka promenila, Update razlikuje
+dva slučaja:
+
+- datoteka je veća, a poslednja 64 bajta indeksiranog dela su ista (proverava
+  se kontrolnom sumom CRC32) - u datoteku je samo dopisano, pa se čita samo
+  novi deo ("updated"),
+- u svim drugim slučajevima datoteka je prepisana i indeks se pravi ponovo
+  ("rebuilt").
+
+Logovi uglavnom samo rastu, pa je ažuriranje obično jeftino. Ako se log
+rotira, stara datoteka je zamenjena novom, manjom, i indeks se pravi ponovo.
+*/
+
+func IndexFunc() {
+
+	fmt.Println("\n --- Index Func ---")
+
+	lineIndexRead()
+}
@@ -0,0 +1,276 @@
+// Package lineindex gives random access to the lines of large text files.
+//
+// An index records the byte offset of every Nth line in a sidecar file
+// named after the indexed file with ".idx" appended. Reading line n seeks to
+// the nearest recorded line before it and skips at most N-1 lines.
+package lineindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"learngo/14-files/safewrite"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrOutOfRange is returned for a line number past the end of the file.
+var ErrOutOfRange = errors.New("lineindex: line out of range")
+
+// Change tells what Update had to do.
+type Change int
+
+const (
+	Unchanged Change = iota // the file did not change
+	Updated                 // the file grew, only the new part was scanned
+	Rebuilt                 // the file was rewritten or the index was unusable
+)
+
+func (c Change) String() string {
+	switch c {
+	case Unchanged:
+		return "unchanged"
+	case Updated:
+		return "updated"
+	}
+	return "rebuilt"
+}
+
+// tailLen is how many bytes before the end of the indexed part are checked
+// to tell an append from a rewrite.
+const tailLen = 64
+
+var magic = [4]byte{'L', 'I', 'D', 'X'}
+
+// header is the fixed part of the sidecar file; the offsets follow it.
+type header struct {
+	Magic    [4]byte
+	Every    uint32
+	Size     int64 // bytes up to the end of the last complete line
+	Lines    int64 // complete lines in Size bytes
+	FileSize int64 // size of the file when it was indexed
+	ModTime  int64 // modification time of the file, in Unix nanoseconds
+	Tail     uint32
+	Count    uint32 // number of offsets
+}
+
+// Index is a line-offset index of one file. Lines are numbered from 1.
+type Index struct {
+	path  string
+	every int
+
+	size     int64
+	lines    int64
+	fileSize int64
+	modTime  time.Time
+	tail     uint32
+	offsets  []int64 // offsets[k] is where line k*every+1 starts
+}
+
+// SidecarPath returns the name of the index file for path.
+func SidecarPath(path string) string {
+	return path + ".idx"
+}
+
+// Open loads the index of path, recording every Nth line. A missing, stale
+// or corrupt index is rebuilt and an appended file is indexed
+// incrementally, as Update does.
+func Open(path string, every int) (*Index, Change, error) {
+	if every <= 0 {
+		return nil, 0, fmt.Errorf("lineindex: every %d must be positive", every)
+	}
+	ix := &Index{path: path, every: every}
+	if err := ix.load(); err != nil {
+		ix.reset()
+	}
+	ch, err := ix.Update()
+	if err != nil {
+		return nil, 0, err
+	}
+	return ix, ch, nil
+}
+
+func (ix *Index) reset() {
+	ix.size, ix.lines, ix.fileSize, ix.tail = 0, 0, -1, 0
+	ix.modTime = time.Time{}
+	ix.offsets = []int64{0}
+}
+
+// Update brings the index up to date with the file. If the file is
+// unchanged it does nothing. If it grew and the bytes before the old end
+// are the same, it was appended to and only the new part is scanned. If
+// the size or modification time changed in any other way, the index is
+// rebuilt. The sidecar file is saved after every change.
+func (ix *Index) Update() (Change, error) {
+	st, err := os.Stat(ix.path)
+	if err != nil {
+		return 0, err
+	}
+	if st.Size() == ix.fileSize && st.ModTime().Equal(ix.modTime) {
+		return Unchanged, nil
+	}
+
+	f, err := os.Open(ix.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	ch := Updated
+	if st.Size() <= ix.fileSize || ix.fileSize < 0 || !ix.tailMatches(f) {
+		ix.reset()
+		ch = Rebuilt
+	}
+	if err := ix.scan(f); err != nil {
+		return 0, err
+	}
+	ix.fileSize = st.Size()
+	ix.modTime = st.ModTime()
+	if ix.tail, err = tailSum(f, ix.size); err != nil {
+		return 0, err
+	}
+	return ch, ix.save()
+}
+
+// scan indexes the file from the end of the last complete line.
+func (ix *Index) scan(f *os.File) error {
+	if _, err := f.Seek(ix.size, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReaderSize(f, 64<<10)
+	pos := ix.size
+	for {
+		chunk, err := r.ReadSlice('\n')
+		pos += int64(len(chunk))
+		if len(chunk) > 0 && chunk[len(chunk)-1] == '\n' {
+			ix.lines++
+			ix.size = pos
+			if ix.lines%int64(ix.every) == 0 {
+				ix.offsets = append(ix.offsets, pos)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+}
+
+func (ix *Index) tailMatches(f *os.File) bool {
+	sum, err := tailSum(f, ix.size)
+	return err == nil && sum == ix.tail
+}
+
+func tailSum(f *os.File, end int64) (uint32, error) {
+	start := max(end-tailLen, 0)
+	b := make([]byte, end-start)
+	if _, err := f.ReadAt(b, start); err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(b), nil
+}
+
+func (ix *Index) load() error {
+	b, err := os.ReadFile(SidecarPath(ix.path))
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(b)
+	var h header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return err
+	}
+	if h.Magic != magic || int(h.Every) != ix.every || h.Count == 0 ||
+		int64(h.Count) != h.Lines/int64(h.Every)+1 {
+		return errors.New("lineindex: bad index file")
+	}
+	offsets := make([]int64, h.Count)
+	if err := binary.Read(r, binary.LittleEndian, offsets); err != nil {
+		return err
+	}
+	ix.size, ix.lines, ix.fileSize, ix.tail = h.Size, h.Lines, h.FileSize, h.Tail
+	ix.modTime = time.Unix(0, h.ModTime)
+	ix.offsets = offsets
+	return nil
+}
+
+func (ix *Index) save() error {
+	var buf bytes.Buffer
+	h := header{
+		Magic:    magic,
+		Every:    uint32(ix.every),
+		Size:     ix.size,
+		Lines:    ix.lines,
+		FileSize: ix.fileSize,
+		ModTime:  ix.modTime.UnixNano(),
+		Tail:     ix.tail,
+		Count:    uint32(len(ix.offsets)),
+	}
+	binary.Write(&buf, binary.LittleEndian, h)
+	binary.Write(&buf, binary.LittleEndian, ix.offsets)
+	return safewrite.WriteFile(SidecarPath(ix.path), buf.Bytes(), safewrite.Options{NoSync: true})
+}
+
+// Len returns the number of lines, counting a last line without a line
+// terminator.
+func (ix *Index) Len() int64 {
+	if ix.fileSize > ix.size {
+		return ix.lines + 1
+	}
+	return ix.lines
+}
+
+// ReadLine returns line n without its terminator.
+func (ix *Index) ReadLine(n int64) (string, error) {
+	lines, err := ix.ReadRange(n, n)
+	if err != nil {
+		return "", err
+	}
+	return lines[0], nil
+}
+
+// ReadRange returns lines from through to, both included. A range that
+// runs past the end of the file is cut short.
+func (ix *Index) ReadRange(from, to int64) ([]string, error) {
+	if from < 1 || from > ix.Len() || to < from {
+		return nil, fmt.Errorf("%w: %d-%d of %d", ErrOutOfRange, from, to, ix.Len())
+	}
+	to = min(to, ix.Len())
+
+	f, err := os.Open(ix.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	k := (from - 1) / int64(ix.every)
+	if _, err := f.Seek(ix.offsets[k], io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	lines := make([]string, 0, to-from+1)
+	for n := k*int64(ix.every) + 1; n <= to; n++ {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break // the file was truncated after the last Update
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n >= from {
+			line = strings.TrimSuffix(line, "\n")
+			lines = append(lines, strings.TrimSuffix(line, "\r"))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: %d-%d, the file is shorter than indexed", ErrOutOfRange, from, to)
+	}
+	return lines, nil
+}
@@ -0,0 +1,212 @@
+/*
+Čitanje redova bez iznenađenja
+==============================
+
+"ReadLineByLine" koristi bufio.Scanner sa podrazumevanim podešavanjima. To
+radi za test.txt, ali ima tri slabosti:
+
+- red duži od 64KiB (bufio.MaxScanTokenSize) zaustavlja čitanje greškom
+  "bufio.Scanner: token too long",
+- u datotekama sa Windows-a redovi se završavaju sa "\r\n"; Scanner uklanja
+  "\r", ali stari Mac format sa samim "\r" ne prepoznaje kao kraj reda,
+- ne možemo da pratimo datoteku koja raste, kao što je log.
+
+Paket "learngo/14-files/textio" ima tip LineReader:
+
+	lr := textio.NewLineReader(f, textio.LineOptions{MaxLen: 1 << 20})
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			break
+		}
+		...
+	}
+
+Next vraća vrednost tipa Line sa tekstom reda bez završetka, rednim brojem
+reda i pozicijom (offset) prvog bajta reda u datoteci. Sva tri završetka
+("\n", "\r\n" i "\r") se prepoznaju.
+
+Red može biti proizvoljno dug. Polje MaxLen ograničava koliko bajtova reda
+čuvamo u memoriji. Za duži red Next vraća grešku *textio.LineError koja
+obavija textio.ErrLineTooLong, a čitanje se nastavlja od sledećeg reda. Ako je
+postavljeno i polje Truncate, umesto greške dobijamo prvih MaxLen bajtova i
+Line.Truncated je true.
+*/
+
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"learngo/14-files/textio"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func linesMixed() {
+
+	fmt.Println("\n --- linesMixed ---")
+
+	text := "unix\nwindows\r\nold mac\r" + strings.Repeat("x", 100) + "\nlast"
+
+	for _, opt := range []textio.LineOptions{{MaxLen: 10}, {MaxLen: 10, Truncate: true}} {
+		lr := textio.NewLineReader(strings.NewReader(text), opt)
+		for {
+			l, err := lr.Next()
+			if err == io.EOF {
+				break
+			}
+			if errors.Is(err, textio.ErrLineTooLong) {
+				fmt.Println(err)
+				continue
+			}
+			if err != nil {
+				fmt.Println(err)
+				break
+			}
+			fmt.Printf("%d@%d %q truncated=%v\n", l.Number, l.Offset, l.Text, l.Truncated)
+		}
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> 1@0 "unix" truncated=false
+	>> 2@5 "windows" truncated=false
+	>> 3@14 "old mac" truncated=false
+	>> line 4 (offset 22): textio: line too long
+	>> 5@123 "last" truncated=false
+	>> 1@0 "unix" truncated=false
+	>> 2@5 "windows" truncated=false
+	>> 3@14 "old mac" truncated=false
+	>> 4@22 "xxxxxxxxxx" truncated=true
+	>> 5@123 "last" truncated=false
+
+Poslednji red nema završetak, ali ga Next ipak vraća.
+
+Praćenje datoteke (tail -f)
+---------------------------
+Komanda "tail -f log.txt" ispisuje redove koje drugi program dopisuje u
+datoteku. Funkcija textio.Follow radi isto:
+
+	err := textio.Follow(ctx, path, textio.FollowOptions{Poll: time.Second},
+		func(l textio.Line) error {
+			fmt.Println(l.Text)
+			return nil
+		})
+
+Follow periodično (svakih Poll) proverava da li je datoteka porasla i poziva fn
+za svaki novi red. Red koji još nije završen čeka dok se ne dopiše do kraja.
+Follow se vraća kada se ctx otkaže ili kada fn vrati grešku.
+
+Programi koji pišu logove ih povremeno skraćuju ili rotiraju: log.txt se
+preimenuje u log.txt.1 i pravi se nova, prazna log.txt. Follow prepoznaje oba
+slučaja:
+
+- ako je datoteka kraća od mesta do kog smo pročitali, skraćena je i čita se
+  ponovo od početka,
+- ako ime sada pokazuje na drugu datoteku (os.SameFile vraća false), pročita
+  se ostatak stare datoteke i prati se nova od početka.
+
+U oba slučaja poziva se FollowOptions.OnReset sa greškom textio.ErrTruncated
+ili textio.ErrRotated, a brojanje redova kreće ispočetka.
+
+Bez FromStart postojeći redovi se preskaču, pa Line.Number broji od mesta
+na kome je praćenje počelo, a ne od početka datoteke. Line.Offset je uvek
+mesto u datoteci. Red duži od MaxLen Follow ne preskače, već ga isporuči
+skraćen, sa Line.Truncated postavljenim na true, kao da je postavljeno i
+Truncate.
+*/
+
+func linesFollow() {
+
+	fmt.Println("\n --- linesFollow ---")
+
+	dir, err := os.MkdirTemp("", "follow")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.txt")
+
+	appendLog := func(s string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		f.WriteString(s)
+		f.Close()
+	}
+	appendLog("old line, skipped\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string)
+	done := make(chan error)
+	go func() {
+		done <- textio.Follow(ctx, path, textio.FollowOptions{
+			Poll: 10 * time.Millisecond,
+			OnReset: func(reason error) {
+				lines <- reason.Error()
+			},
+		}, func(l textio.Line) error {
+			lines <- fmt.Sprintf("%d: %s", l.Number, l.Text)
+			return nil
+		})
+	}()
+	time.Sleep(50 * time.Millisecond) // let Follow skip the existing line
+
+	appendLog("first\nsec")
+	fmt.Println(<-lines)
+	appendLog("ond\n")
+	fmt.Println(<-lines)
+
+	os.Truncate(path, 0)
+	appendLog("after truncate\n")
+	fmt.Println(<-lines)
+	fmt.Println(<-lines)
+
+	appendLog("before rotate\n")
+	os.Rename(path, path+".1")
+	appendLog("after rotate\n")
+	fmt.Println(<-lines)
+	fmt.Println(<-lines)
+	fmt.Println(<-lines)
+
+	cancel()
+	fmt.Println(<-done)
+}
+
+/*
+Program ispisuje:
+
+	>> 1: first
+	>> 2: second
+	>> textio: file truncated
+	>> 1: after truncate
+	>> 2: before rotate
+	>> textio: file rotated
+	>> 1: after rotate
+	>> context canceled
+
+Red "sec" je dopisan bez završetka, pa je Follow sačekao "ond\n" i vratio ceo
+red "second".
+
+Provera skraćivanja poredi veličinu, pa ako se datoteka skrati i odmah dopiše
+do veće dužine pre sledeće provere, promena se ne primećuje. Isto ograničenje
+ima i tail.
+*/
+
+func LinesFunc() {
+
+	fmt.Println("\n --- Lines Func ---")
+
+	linesMixed()
+	linesFollow()
+}
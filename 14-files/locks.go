@@ -0,0 +1,259 @@
+/*
+Zaključavanje datoteka između procesa
+=====================================
+
+U lekciji o konkurentnosti smo videli da gorutine koje istovremeno menjaju
+istu promenljivu moraju da koriste sync.Mutex. Isti problem postoji i između
+procesa. Ako dva programa istovremeno pročitaju broj iz datoteke, uvećaju ga i
+upišu nazad, jedno uvećanje se izgubi. "writeAppend" i "writeConcurently" bi
+imali isti problem kada bi ih dva procesa pokrenula u isto vreme.
+
+Mutex ne pomaže, jer postoji samo unutar jednog procesa. Operativni sistem
+zato nudi zaključavanje datoteka. Na Linux-u postoje dva sistemska poziva:
+
+- flock(2) zaključava celu datoteku; brava pripada otvorenoj datoteci,
+- fcntl(2) sa F_SETLK je POSIX brava koja radi i preko NFS-a; brava
+  pripada procesu i oslobađa se kada proces zatvori bilo koji deskriptor te
+  datoteke.
+
+Obe brave su savetodavne (advisory): štite samo od programa koji i sami
+traže bravu. Operativni sistem ne sprečava nikoga da piše u zaključanu
+datoteku.
+
+Brava može biti deljena (shared), koju istovremeno drži više čitalaca, ili
+isključiva (exclusive), koju drži samo jedan pisac. To je isto što i
+sync.RWMutex, samo između procesa.
+
+Paket "learngo/14-files/flock" obavija oba poziva:
+
+	l := flock.New(f, flock.Flock)      // ili flock.Fcntl
+	l.Lock(flock.Exclusive)              // čeka
+	ok, err := l.TryLock(flock.Shared)   // ne čeka
+	l.LockTimeout(flock.Exclusive, time.Second)
+	l.Unlock()
+
+Implementacija je u datoteci flock_linux.go sa direktivom "//go:build linux",
+pa se kompajlira samo za Linux. Datoteka flock_other.go sa direktivom
+"//go:build !linux" sadrži iste funkcije koje vraćaju flock.ErrUnsupported,
+pa se paket kompajlira i na drugim sistemima.
+*/
+
+package files
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"learngo/14-files/flock"
+	"learngo/14-files/safewrite"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func locksInProcess() {
+
+	fmt.Println("\n --- locksInProcess ---")
+
+	path := filepath.Join(os.TempDir(), "learngo-lock-demo")
+	defer os.Remove(path)
+
+	f1, err := os.Create(path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f1.Close()
+	f2, err := os.Open(path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f2.Close()
+
+	l1 := flock.New(f1, flock.Flock)
+	l2 := flock.New(f2, flock.Flock)
+
+	if err := l1.Lock(flock.Shared); err != nil {
+		fmt.Println(err)
+		return
+	}
+	ok, _ := l2.TryLock(flock.Shared)
+	fmt.Println("second shared lock:", ok)
+	l2.Unlock()
+
+	l1.Lock(flock.Exclusive) // upgrade
+	ok, _ = l2.TryLock(flock.Shared)
+	fmt.Println("shared lock while exclusive is held:", ok)
+
+	start := time.Now()
+	err = l2.LockTimeout(flock.Exclusive, 100*time.Millisecond)
+	fmt.Println(errors.Is(err, flock.ErrTimeout), time.Since(start).Round(100*time.Millisecond))
+
+	l1.Unlock()
+	ok, _ = l2.TryLock(flock.Exclusive)
+	fmt.Println("exclusive lock after unlock:", ok)
+}
+
+/*
+Program ispisuje:
+
+	>> second shared lock: true
+	>> shared lock while exclusive is held: false
+	>> true 100ms
+	>> exclusive lock after unlock: true
+
+Brava flock pripada otvorenoj datoteci, pa se f1 i f2 međusobno isključuju čak
+i unutar istog procesa. Sa flock.Fcntl to ne bi bio slučaj: proces ne može da
+blokira sam sebe.
+
+Brojač u datoteci
+-----------------
+Napišimo funkciju koja n puta pročita broj iz datoteke, uveća ga i upiše
+nazad. Za upis koristi safewrite, pa datoteka nikada nije poluupisana.
+
+Datoteku koju safewrite zamenjuje ne možemo zaključati direktno. Preimenovanje
+pravi novu datoteku, a brava je ostala na staroj, pa bi sledeći proces
+zaključao novu datoteku bez čekanja. Zato funkcija flock.WithLock zaključava
+posebnu datoteku sa nastavkom ".lock" i dok drži bravu poziva fn.
+*/
+
+func incrementCounter(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return safewrite.WriteFile(path, []byte(strconv.Itoa(n+1)+"\n"), safewrite.Options{NoSync: true})
+}
+
+// LockCounter increments the number in the file at path n times, taking
+// an exclusive lock for each increment unless noLock is set. The lesson runs
+// it in child processes through the "lock-counter" command.
+func LockCounter(path string, n int, noLock bool) error {
+	for i := 0; i < n; i++ {
+		var err error
+		if noLock {
+			err = incrementCounter(path)
+		} else {
+			err = flock.WithLock(path, flock.Exclusive, 10*time.Second, func() error {
+				return incrementCounter(path)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Sada pokrećemo isti program dva puta kao dva procesa, kao u lekciji o trajnom
+redu poslova, komandom "learngo lock-counter <path> <n> [nolock]". Svaki
+proces uvećava brojač 300 puta.
+*/
+
+func locksChildren(noLock bool) {
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	path := filepath.Join(os.TempDir(), "learngo-counter")
+	os.Remove(path)
+	defer os.Remove(path)
+	defer os.Remove(path + ".lock")
+
+	args := []string{"lock-counter", path, "300"}
+	if noLock {
+		args = append(args, "nolock")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var stderr bytes.Buffer
+			cmd := exec.Command(exe, args...)
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				fmt.Println(err, strings.TrimSpace(stderr.String()))
+			}
+		}()
+	}
+	wg.Wait()
+
+	b, _ := os.ReadFile(path)
+	fmt.Printf("lock=%v counter=%s\n", !noLock, strings.TrimSpace(string(b)))
+}
+
+func locksCounter() {
+
+	fmt.Println("\n --- locksCounter ---")
+
+	locksChildren(true)
+	locksChildren(false)
+}
+
+/*
+Program ispisuje nešto poput:
+
+	>> lock=false counter=331
+	>> lock=true counter=600
+
+Bez brave se više od polovine uvećanja izgubi, a broj je drugačiji pri svakom
+pokretanju. Sa bravom je rezultat uvek 600.
+
+Dodavanje uz bravu
+------------------
+Kod dodavanja u datoteku, kao u "writeAppend", datoteka se ne zamenjuje, pa
+možemo zaključati nju samu. Dok držimo isključivu bravu, nijedan drugi proces
+koji poštuje bravu ne može da doda svoje redove između naših.
+*/
+
+func writeAppendLocked() {
+
+	fmt.Println("\n --- writeAppendLocked ---")
+
+	f, err := os.OpenFile("wlines", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+
+	l := flock.New(f, flock.Fcntl)
+	if err := l.LockTimeout(flock.Exclusive, time.Second); err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer l.Unlock()
+
+	for _, line := range []string{"Locks are advisory.", "Both lines stay together."} {
+		if _, err := fmt.Fprintln(f, line); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	fmt.Println("file appended successfully")
+}
+
+/*
+Ovde smo koristili flock.Fcntl. Datoteka je otvorena samo za pisanje, što je
+dovoljno za isključivu fcntl bravu. Za deljenu bravu bi morala biti otvorena
+i za čitanje.
+*/
+
+func LocksFunc() {
+
+	fmt.Println("\n --- Locks Func ---")
+
+	locksInProcess()
+	locksCounter()
+	writeAppendLocked()
+}
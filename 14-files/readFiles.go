@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"learngo/11-deferAndError/cleanup"
 	fh "learngo/14-files/fh"
 	fh1 "learngo/14-files/fh1"
 	fh2 "learngo/14-files/fh2"
 	fh3 "learngo/14-files/fh3"
-	"log"
-	"os"
+	"learngo/14-files/source"
+	"learngo/14-files/textio"
+	"strings"
 )
 
 /*
@@ -108,6 +110,12 @@ Postoje tri načina za rešavanje ovog problema,
 
 Hajde da razgovaramo o njima jedan po jedan.
 
+Paket fh u ovom repozitorijumu ipak ne čita "test.txt" iz tekućeg
+direktorijuma, da bi program radio odakle god da se pokrene. Čita ga kroz
+source.Module() iz lekcije "14-files/fsFiles.go", koji pronalazi direktorijum
+modula learngo, pod imenom "14-files/fh/test.txt". Kod iznad pokazuje problem,
+a source.Module() je još jedno njegovo rešenje.
+
 1. Korišćenje absolutnog puta datoteke
 Najjednostavniji način da rešite ovaj problem je da prođete sa absolutnim pute
 datoteka u funkciju "Open". Modifikovao sam gornji program i promenio put u
@@ -132,6 +140,10 @@ func ReadAll() {
 
 Sada se program može pokrenuti sa bilo koje lokacije i štampaće sadržaj
 test.txt.
+
+Paket fh1 u ovom repozitorijumu ne piše put ručno, već ga sastavlja od root
+direktorijuma modula koji vraća "source.ModuleRoot()", pa radi na svakom
+računaru na koji je repozitorijum kloniran. Put je i dalje apsolutan.
 */
 
 func ReadAllOfNonLocalFileWithAbsPath() {
@@ -257,6 +269,15 @@ I program će ispisati:
 
 	>> Contents of file: Hello World. Welcome to file handling in Go.
 
+U paketu fh2 podrazumevana vrednost zastave nije "test.txt", već apsolutni put
+do test.txt tog paketa, sastavljen kao u fh1 od source.ModuleRoot(). Zato
+program i bez zastave radi iz bilo kog direktorijuma i ispisuje:
+
+	>> value of fpath is /home/radosav/go/src/learngo/14-files/fh2/test.txt
+	>> Contents of file: Hello World. Welcome to file handling in Go.
+
+Put zavisi od toga gde je repozitorijum kloniran.
+
 3. Pakovanje tekstualne datoteke zajedno sa binarnim kodom
 Gornja opcija dobijanja puta datoteke iz komandne linije je dobra, ali postoji
 još bolji način da se reši ovaj problem. Zar ne bi bilo fenomenalno ako možemo
@@ -340,17 +361,25 @@ func ReadChunkByChunk() {
 	// flag.Parse()
 	// f, err := os.Open(*fptr) // Open file
 
-	filepath := "./14-files/test.txt" // root /home/radosav/go/src/learngo
-	f, err := os.Open(filepath)       // Open file
-	if err != nil {
-		log.Fatal(err)
+	if err := readChunkByChunk("14-files/test.txt"); err != nil {
+		fmt.Println(err)
 	}
+}
 
-	defer func() {
-		if err = f.Close(); err != nil { // Defer Close file
-			log.Fatal(err)
-		}
-	}()
+func readChunkByChunk(name string) (err error) {
+
+	var cleanups cleanup.Group
+	defer cleanups.RunInto(&err) // Close everything opened, errors go to err
+
+	src, err := source.Module() // Files relative to the learngo module root
+	if err != nil {
+		return err
+	}
+	f, err := src.Open(name) // Open file
+	if err != nil {
+		return err
+	}
+	cleanups.Add(f.Close) // Defer Close file
 
 	r := bufio.NewReader(f) // Get newreader for file
 	b := make([]byte, 3)    // Buffer 3 - bytes
@@ -363,17 +392,28 @@ func ReadChunkByChunk() {
 		}
 
 		if err != nil {
-			fmt.Printf("Error %s reading file", err)
-			break
+			return fmt.Errorf("reading file: %w", err)
 		}
 		fmt.Println(string(b[0:n])) // Print all from buff b
 	}
+	return nil
 }
 
 /*
 // U gornjem programu, otvaramo datoteku pomoću puta iz zastave komandne linije.
-U gornjem programu, otvaramo datoteku pomoću relativne putanje u odnosu na root
-direktorijum modula learngo. Zatim odlažemo zatvaranje  datoteke.
+U gornjem programu, otvaramo datoteku pomoću putanje relativne u odnosu na root
+direktorijum modula learngo. Funkcija "source.Module" pronalazi taj direktorijum
+(onaj u kome je go.mod) bez obzira iz kog direktorijuma je program pokrenut.
+Više o paketu "source" u lekciji "fsFiles.go". Zatim odlažemo zatvaranje
+datoteke.
+
+Zatvaranje ne odlažemo sa "defer f.Close()", jer bi greška koju Close vrati
+bila izgubljena, a ni sa log.Fatal u odloženoj funkciji, jer bi to prekinulo
+ceo program. Posao je prebačen u funkciju readChunkByChunk sa imenovanim
+povratnim rezultatom err, a zatvaranje prijavljujemo grupi za čišćenje iz
+paketa "learngo/11-deferAndError/cleanup" (lekcija "defer.go"). Odloženi
+cleanups.RunInto(&err) zatvara datoteku i njenu grešku dodaje u err, pa
+ReadChunkByChunk samo ispisuje grešku, ako je ima.
 
 U gornjem programu kreiramo novi buferisani čitač. U sledećoj liniji kreiramo
 isečak bajtova dužine i kapaciteta 3 u koji će se čitati bajtovi datoteke.
@@ -404,6 +444,136 @@ Ako pokrećemo program iznad, sledeći će biti izlaz
 	>> o.
 	>> finished reading file
 
+Čitanje u delovima bez cepanja znakova
+--------------------------------------
+Datoteka test.txt sadrži samo ASCII znakove, pa je svaki znak jedan bajt. U
+UTF-8 kodiranju naša slova č, ć, š, ž i đ zauzimaju po dva bajta. Ako deo od 3
+bajta završi na sredini takvog slova, string(b[0:n]) sadrži pola znaka, a
+fmt.Println ga štampa kao "\uFFFD" (�). Drugu polovinu dobijamo na početku
+sledećeg dela, takođe kao �.
+
+Paket "learngo/14-files/textio" ima tip ChunkReader koji deli tok na delove od
+najviše Size bajtova, ali uvek na granici znaka (rune). Ako čitanje preseče
+višebajtni niz, ChunkReader ga čuva i dopunjuje sledećim čitanjem.
+
+	cr := textio.NewChunkReader(f, textio.ChunkOptions{Size: 3})
+	for {
+		chunk, err := cr.Next()
+		...
+	}
+
+Pogledajmo oba načina nad datotekom test_sr.txt.
+*/
+
+func ReadChunkByChunkUTF8() {
+
+	fmt.Println("\n --- ReadChunkByChunkUTF8 ---")
+
+	if err := readChunkByChunkUTF8("14-files/test_sr.txt"); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func readChunkByChunkUTF8(name string) error {
+
+	src, err := source.Module()
+	if err != nil {
+		return err
+	}
+	data, err := src.ReadAll(name)
+	if err != nil {
+		return err
+	}
+	_, line, _ := strings.Cut(string(data), "\n") // Second line
+
+	// Naive: 3 bytes at a time
+	r := bufio.NewReader(strings.NewReader(line))
+	b := make([]byte, 3)
+	var naive []string
+	for {
+		n, err := r.Read(b)
+		if err != nil {
+			break
+		}
+		naive = append(naive, string(b[0:n]))
+	}
+	fmt.Println(strings.Join(naive[:min(5, len(naive))], "|"))
+
+	// Rune boundaries: at most 3 bytes, but never half a letter
+	cr := textio.NewChunkReader(strings.NewReader(line), textio.ChunkOptions{Size: 3})
+	var safe []string
+	for {
+		chunk, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		safe = append(safe, string(chunk))
+	}
+	fmt.Println(strings.Join(safe[:min(5, len(safe))], "|"))
+	return nil
+}
+
+/*
+Program ispisuje:
+
+	>> Ča|ša|, �|�up|, �
+	>> Ča|ša|, |ću|p,
+
+U prvom redu slovo ć je podeljeno između dva dela, pa umesto njega vidimo dva
+znaka �. Delovi ChunkReader-a imaju najviše 3 bajta. Deo ", " ima samo 2,
+jer ć ne staje u preostali bajt, pa ide u sledeći deo "ću".
+
+Kombinujući znakovi
+-------------------
+Slovo "š" se u Unicode-u može zapisati na dva načina: kao jedan znak U+0161,
+ili kao "s" iza koga ide kombinujuća kvačica U+030C. Drugi oblik su dve rune,
+pa ga ChunkReader u podrazumevanom režimu (textio.Runes) može podeliti između
+dva dela. Režim textio.Graphemes drži osnovni znak zajedno sa kombinujućim
+znacima koji ga prate.
+
+Neispravan UTF-8
+----------------
+Ako naiđe na bajt koji ne može biti deo UTF-8 niza, Next prvo vrati ispravan
+tekst pre njega, zatim grešku tipa *textio.InvalidUTF8Error sa pozicijom
+(offset) lošeg bajta u toku. Loš bajt se preskače, pa se čitanje može
+nastaviti.
+*/
+
+func ReadChunkByChunkModes() {
+
+	fmt.Println("\n --- ReadChunkByChunkModes ---")
+
+	text := "s\u030Cas" + "\xff" + "ok" // š as s + caron, then a bad byte
+
+	for _, mode := range []textio.ChunkMode{textio.Runes, textio.Graphemes} {
+		cr := textio.NewChunkReader(strings.NewReader(text), textio.ChunkOptions{Size: 1, Mode: mode})
+		for {
+			chunk, err := cr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fmt.Printf("[%v] ", err)
+				continue
+			}
+			fmt.Printf("%q@%d ", chunk, cr.Offset())
+		}
+		fmt.Println()
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> "s"@0 "̌"@1 "a"@3 "s"@4 [invalid UTF-8 byte 0xff at offset 5] "o"@6 "k"@7
+	>> "š"@0 "a"@3 "s"@4 [invalid UTF-8 byte 0xff at offset 5] "o"@6 "k"@7
+
+U režimu Runes kvačica je ostala sama u svom delu. U režimu Graphemes deo
+"š" je duži od Size, jer se osnovni znak i kvačica ne mogu razdvojiti.
+
 Čitanje datoteke datoteke po liniji
 -----------------------------------
 U odeljku ćemo razgovarati o tome kako da pročitam datotečnu liniju linijom koristeći Go.To može učiniti pomoću Bufio paketa.
@@ -429,26 +599,36 @@ func ReadLineByLine() {
 	// flag.Parse()
 	// f, err := os.Open(*fptr)
 
-	filepath := "./14-files/test.txt" // root /home/radosav/go/src/learngo
-	f, err := os.Open(filepath)
-	if err != nil {
-		log.Fatal(err)
+	if err := readLineByLine("14-files/test.txt"); err != nil {
+		fmt.Println(err)
 	}
+}
 
-	defer func() {
-		if err = f.Close(); err != nil {
-			log.Fatal(err)
-		}
-	}()
+func readLineByLine(name string) (err error) {
 
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		fmt.Println(s.Text())
-	}
+	var cleanups cleanup.Group
+	defer cleanups.RunInto(&err)
 
-	err = s.Err()
+	src, err := source.Module()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	f, err := src.Open(name)
+	if err != nil {
+		return err
+	}
+	cleanups.Add(f.Close)
+
+	lr := textio.NewLineReader(f, textio.LineOptions{})
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(l.Text)
 	}
 }
 
@@ -466,6 +646,11 @@ Ako pokrenemo program sadržaj datoteke će biti štampana linija po liniji kao
 	>> Hello World. Welcome to file handling in Go.
 	>> This is the second line of the file.
 	>> We have reached the end of the file.
+
+bufio.Scanner podrazumevano odbija red duži od 64 KiB greškom "token too
+long". Zato readLineByLine ne koristi skener, već textio.LineReader iz
+lekcije "14-files/lines.go", koji nema takvo ograničenje. Next radi isto što
+i Scan i Text zajedno, a kraj datoteke javlja greškom io.EOF.
 */
 
 func ReadFiles() {
@@ -477,5 +662,7 @@ func ReadFiles() {
 	ReadAllOfNonLocalFileWithCmdLine()
 	ReadAllOfFileEmbed()
 	ReadChunkByChunk()
+	ReadChunkByChunkUTF8()
+	ReadChunkByChunkModes()
 	ReadLineByLine()
 }
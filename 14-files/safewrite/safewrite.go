@@ -0,0 +1,224 @@
+// Package safewrite replaces files atomically: the new content is written to
+// a temporary file next to the target, flushed to disk and renamed over the
+// target, so readers see either the old or the new file, never a part of it.
+package safewrite
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File is the part of *os.File that Write uses.
+type File interface {
+	io.Writer
+	Name() string
+	Sync() error
+	Chmod(mode fs.FileMode) error
+	Close() error
+}
+
+// FS is the file system Write works on. OS returns the real one; wrapping it
+// lets tests inject failures.
+type FS interface {
+	CreateTemp(dir, pattern string) (File, error)
+	Open(name string) (File, error)
+	Lstat(name string) (fs.FileInfo, error)
+	Readlink(name string) (string, error)
+	Rename(oldpath, newpath string) error
+	Link(oldpath, newpath string) error
+	Remove(name string) error
+}
+
+type osFS struct{}
+
+// OS returns the FS implemented by the os package.
+func OS() FS {
+	return osFS{}
+}
+
+func (osFS) CreateTemp(dir, pattern string) (File, error) { return os.CreateTemp(dir, pattern) }
+func (osFS) Open(name string) (File, error)               { return os.Open(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (osFS) Readlink(name string) (string, error)         { return os.Readlink(name) }
+func (osFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (osFS) Link(oldpath, newpath string) error           { return os.Link(oldpath, newpath) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+
+// Options configures Write.
+type Options struct {
+	// Perm is used when the target does not exist yet, default 0644. An
+	// existing target keeps its permissions.
+	Perm fs.FileMode
+
+	// Backup keeps the previous content of the target in name+".bak".
+	Backup bool
+
+	// NoSync skips the fsync calls. The rename is still atomic, but after
+	// a power failure the file may be empty.
+	NoSync bool
+
+	FS FS // default OS()
+}
+
+// WriteFile atomically replaces the named file with data.
+func WriteFile(name string, data []byte, opt Options) error {
+	return Write(name, opt, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Write atomically replaces the named file with what fn writes. If fn or
+// any step fails, the target is left untouched and the temporary file is
+// removed. The returned error joins every failure, so a write error does
+// not hide a failed Close or cleanup.
+//
+// If name is a symbolic link, the file it points to is replaced and the
+// link is kept. The temporary file is created next to that file, so that
+// the rename stays on one file system.
+//
+// If name ends in ".gz", what fn writes is gzip compressed.
+func Write(name string, opt Options, fn func(w io.Writer) error) error {
+	fsys := opt.FS
+	if fsys == nil {
+		fsys = OS()
+	}
+	perm := opt.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+
+	link := name // decides about compression even if it is a symlink
+	name, st, err := resolve(fsys, name)
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+
+	exists := false
+	switch {
+	case err == nil:
+		if !st.Mode().IsRegular() {
+			return fmt.Errorf("safewrite: %s is not a regular file", name)
+		}
+		perm = st.Mode().Perm()
+		exists = true
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("safewrite: %w", err)
+	}
+
+	f, err := fsys.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("safewrite: %w", err)
+	}
+	tmp := f.Name()
+
+	var errs []error
+	if err := write(f, link, fn); err != nil {
+		errs = append(errs, fmt.Errorf("safewrite: write %s: %w", name, err))
+	}
+	if len(errs) == 0 && !opt.NoSync {
+		if err := f.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("safewrite: sync %s: %w", tmp, err))
+		}
+	}
+	if len(errs) == 0 {
+		if err := f.Chmod(perm); err != nil {
+			errs = append(errs, fmt.Errorf("safewrite: %w", err))
+		}
+	}
+	if err := f.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("safewrite: close %s: %w", tmp, err))
+	}
+
+	if len(errs) == 0 && exists && opt.Backup {
+		if err := backup(fsys, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		if err := fsys.Rename(tmp, name); err != nil {
+			errs = append(errs, fmt.Errorf("safewrite: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		if err := fsys.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("safewrite: cleanup: %w", err))
+		}
+		return errors.Join(errs...)
+	}
+
+	if !opt.NoSync {
+		if err := syncDir(fsys, dir); err != nil {
+			return fmt.Errorf("safewrite: sync dir %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// maxLinks is how many symbolic links resolve follows, like the limit of
+// the Linux kernel.
+const maxLinks = 40
+
+// resolve follows name while it is a symbolic link and returns the path
+// of the file it points to, with that file's Lstat result. A link to a
+// file that does not exist yet resolves to that path and an error wrapping
+// fs.ErrNotExist.
+func resolve(fsys FS, name string) (string, fs.FileInfo, error) {
+	for range maxLinks {
+		st, err := fsys.Lstat(name)
+		if err != nil || st.Mode()&fs.ModeSymlink == 0 {
+			return name, st, err
+		}
+		target, err := fsys.Readlink(name)
+		if err != nil {
+			return name, nil, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(name), target)
+		}
+		name = target
+	}
+	return name, nil, &fs.PathError{Op: "resolve", Path: name, Err: errors.New("too many links")}
+}
+
+// write calls fn, compressing what it writes if name ends in ".gz".
+func write(f File, name string, fn func(w io.Writer) error) error {
+	if !strings.HasSuffix(strings.ToLower(name), ".gz") {
+		return fn(f)
+	}
+	gz := gzip.NewWriter(f)
+	if err := fn(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// backup makes name+".bak" a hard link to the current content of name, so
+// that the rename that follows leaves it behind.
+func backup(fsys FS, name string) error {
+	bak := name + ".bak"
+	if err := fsys.Remove(bak); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("safewrite: backup: %w", err)
+	}
+	if err := fsys.Link(name, bak); err != nil {
+		return fmt.Errorf("safewrite: backup: %w", err)
+	}
+	return nil
+}
+
+// syncDir flushes the directory entry created by the rename.
+func syncDir(fsys FS, dir string) error {
+	d, err := fsys.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(d.Sync(), d.Close())
+}
@@ -0,0 +1,163 @@
+package safewrite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type faultyFile struct {
+	File
+	failWrite, failSync, failClose bool
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.failWrite {
+		return 0, errors.New("disk full")
+	}
+	return f.File.Write(p)
+}
+
+func (f *faultyFile) Sync() error {
+	if f.failSync {
+		return errors.New("sync failed")
+	}
+	return f.File.Sync()
+}
+
+func (f *faultyFile) Close() error {
+	err := f.File.Close()
+	if f.failClose {
+		return errors.New("I/O error")
+	}
+	return err
+}
+
+type faultyFS struct {
+	FS
+	failWrite, failSync, failClose, failRename bool
+}
+
+func (fsys faultyFS) CreateTemp(dir, pattern string) (File, error) {
+	f, err := fsys.FS.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{File: f, failWrite: fsys.failWrite, failSync: fsys.failSync, failClose: fsys.failClose}, nil
+}
+
+func (fsys faultyFS) Rename(oldpath, newpath string) error {
+	if fsys.failRename {
+		return errors.New("rename failed")
+	}
+	return fsys.FS.Rename(oldpath, newpath)
+}
+
+// entries returns the names in dir.
+func entries(t *testing.T, dir string) []string {
+	t.Helper()
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range list {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestWriteFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys faultyFS
+		want []string // parts of the error
+	}{
+		{"write", faultyFS{failWrite: true}, []string{"disk full"}},
+		{"sync", faultyFS{failSync: true}, []string{"sync failed"}},
+		{"close", faultyFS{failClose: true}, []string{"I/O error"}},
+		{"write and close", faultyFS{failWrite: true, failClose: true}, []string{"disk full", "I/O error"}},
+		{"rename", faultyFS{failRename: true}, []string{"rename failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			name := filepath.Join(dir, "target.txt")
+			if err := os.WriteFile(name, []byte("old"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.fsys.FS = OS()
+			err := WriteFile(name, []byte("new"), Options{FS: tt.fsys})
+			if err == nil {
+				t.Fatal("WriteFile succeeded, want an error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+
+			if b, err := os.ReadFile(name); err != nil || string(b) != "old" {
+				t.Errorf("target = %q, %v; want \"old\"", b, err)
+			}
+			if got := entries(t, dir); len(got) != 1 {
+				t.Errorf("directory has %v, want only target.txt", got)
+			}
+		})
+	}
+}
+
+func TestWriteKeepsPermissions(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "target.txt")
+	if err := os.WriteFile(name, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(name, []byte("new"), Options{Backup: true}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", st.Mode().Perm())
+	}
+	if b, _ := os.ReadFile(name + ".bak"); string(b) != "old" {
+		t.Errorf("backup = %q, want \"old\"", b)
+	}
+}
+
+func TestWriteSymlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "data", "config.txt")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config.txt")
+	if err := os.Symlink(filepath.Join("data", "config.txt"), link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	if err := WriteFile(link, []byte("new"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s was replaced by a regular file", link)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "new" {
+		t.Errorf("link target = %q, want \"new\"", b)
+	}
+	if got := entries(t, filepath.Join(dir, "data")); len(got) != 1 {
+		t.Errorf("data has %v, want only config.txt", got)
+	}
+}
@@ -0,0 +1,197 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"learngo/14-files/textio"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ModulePath is the module whose root ModuleRoot looks for.
+const ModulePath = "learngo"
+
+// RootEnv overrides the module root lookup when set.
+const RootEnv = "LEARNGO_ROOT"
+
+// ErrNoRoot is returned when the module root cannot be found.
+var ErrNoRoot = errors.New("source: learngo module root not found")
+
+// ModuleRoot returns the directory that holds the go.mod of the learngo
+// module. It looks, in order, at $LEARNGO_ROOT, at the directory this file
+// was compiled from, at the directory of the executable and at the current
+// directory, walking up from each until it finds the go.mod.
+func ModuleRoot() (string, error) {
+	if dir := os.Getenv(RootEnv); dir != "" {
+		if isRoot(dir) {
+			return dir, nil
+		}
+		return "", fmt.Errorf("%w: $%s=%s has no go.mod for %s", ErrNoRoot, RootEnv, dir, ModulePath)
+	}
+
+	var starts []string
+	if _, file, _, ok := runtime.Caller(0); ok && filepath.IsAbs(file) {
+		starts = append(starts, filepath.Dir(file))
+	}
+	if exe, err := os.Executable(); err == nil {
+		starts = append(starts, filepath.Dir(exe))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		starts = append(starts, wd)
+	}
+
+	for _, dir := range starts {
+		for {
+			if isRoot(dir) {
+				return dir, nil
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return "", ErrNoRoot
+}
+
+func isRoot(dir string) bool {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return false
+	}
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	return string(bytes.TrimSpace(line)) == "module "+ModulePath
+}
+
+// Source reads files from an fs.FS. Names are slash separated and relative
+// to the root of the file system, as fs.ValidPath requires, so the same
+// code works with os.DirFS, embed.FS and fstest.MapFS. Files whose name
+// ends in ".gz" are decompressed by ReadAll, ReadChunks and ReadLines.
+type Source struct {
+	fsys fs.FS
+}
+
+// New returns a Source reading from fsys.
+func New(fsys fs.FS) *Source {
+	return &Source{fsys: fsys}
+}
+
+// Module returns a Source rooted at the learngo module root, so that
+// "14-files/test.txt" names the same file wherever the program is started.
+func Module() (*Source, error) {
+	root, err := ModuleRoot()
+	if err != nil {
+		return nil, err
+	}
+	return New(os.DirFS(root)), nil
+}
+
+// FS returns the underlying file system.
+func (s *Source) FS() fs.FS {
+	return s.fsys
+}
+
+// Sub returns a Source rooted at dir.
+func (s *Source) Sub(dir string) (*Source, error) {
+	sub, err := fs.Sub(s.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return New(sub), nil
+}
+
+// Open opens the named file for reading.
+func (s *Source) Open(name string) (fs.File, error) {
+	return s.fsys.Open(name)
+}
+
+// ReadAll reads the whole named file into memory.
+func (s *Source) ReadAll(name string) ([]byte, error) {
+	f, r, err := s.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(r)
+}
+
+// open opens the named file for ReadAll, ReadChunks and ReadLines, which
+// read files ending in ".gz" decompressed.
+func (s *Source) open(name string) (fs.File, io.Reader, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.EqualFold(path.Ext(name), ".gz") {
+		return f, f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, zr, nil
+}
+
+// ReadChunks calls fn with consecutive chunks of at most size bytes. The
+// slice passed to fn is reused and is only valid during the call.
+func (s *Source) ReadChunks(name string, size int, fn func(chunk []byte) error) error {
+	if size <= 0 {
+		return fmt.Errorf("source: chunk size %d must be positive", size)
+	}
+	f, zr, err := s.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(zr)
+	b := make([]byte, size)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if ferr := fn(b[:n]); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ReadLines calls fn with every line of the named file, without the line
+// terminator. Lines may be of any length and end in "\n", "\r\n" or "\r".
+func (s *Source) ReadLines(name string, fn func(line string) error) error {
+	f, r, err := s.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lr := textio.NewLineReader(r, textio.LineOptions{})
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(l.Text); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func gz(t *testing.T, text string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(text))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const text = "first\r\nsecond\rthird\n\nlast"
+
+func testSource(t *testing.T) *Source {
+	t.Helper()
+	return New(fstest.MapFS{
+		"a.txt":          {Data: []byte(text)},
+		"dir/a.txt.gz":   {Data: gz(t, text)},
+		"dir/UPPER.GZ":   {Data: gz(t, "upper")},
+		"dir/broken.gz":  {Data: []byte("not gzip")},
+		"dir/long.txt":   {Data: []byte(strings.Repeat("x", 100_000) + "\nend")},
+		"dir/empty.txt":  {Data: nil},
+		"dir/sub/b.txt":  {Data: []byte("b")},
+		"dir/plain.gzip": {Data: []byte("plain")},
+	})
+}
+
+func TestReadAll(t *testing.T) {
+	s := testSource(t)
+	tests := []struct {
+		name, want string
+	}{
+		{"a.txt", text},
+		{"dir/a.txt.gz", text},
+		{"dir/UPPER.GZ", "upper"},
+		{"dir/plain.gzip", "plain"},
+		{"dir/empty.txt", ""},
+	}
+	for _, tt := range tests {
+		b, err := s.ReadAll(tt.name)
+		if err != nil || string(b) != tt.want {
+			t.Errorf("ReadAll(%s) = %q, %v; want %q", tt.name, b, err, tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := testSource(t)
+	for _, name := range []string{"missing.txt", "../a.txt", "/a.txt", "./a.txt", "dir//a.txt.gz", `dir\sub\b.txt`} {
+		if _, err := s.ReadAll(name); err == nil {
+			t.Errorf("ReadAll(%q) succeeded", name)
+		}
+		if err := s.ReadLines(name, func(string) error { return nil }); err == nil {
+			t.Errorf("ReadLines(%q) succeeded", name)
+		}
+	}
+	if _, err := s.ReadAll("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadAll(missing.txt) = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := s.ReadAll("dir/broken.gz"); err == nil || !strings.Contains(err.Error(), "dir/broken.gz") {
+		t.Errorf("ReadAll(dir/broken.gz) = %v, want an error naming the file", err)
+	}
+	if err := s.ReadChunks("a.txt", 0, func([]byte) error { return nil }); err == nil {
+		t.Error("ReadChunks with size 0 succeeded")
+	}
+}
+
+func TestReadChunks(t *testing.T) {
+	s := testSource(t)
+	for _, name := range []string{"a.txt", "dir/a.txt.gz"} {
+		for _, size := range []int{1, 4, 1000} {
+			var got []byte
+			err := s.ReadChunks(name, size, func(chunk []byte) error {
+				if len(chunk) == 0 || len(chunk) > size {
+					t.Errorf("%s: chunk of %d bytes, size %d", name, len(chunk), size)
+				}
+				got = append(got, chunk...)
+				return nil
+			})
+			if err != nil || string(got) != text {
+				t.Errorf("ReadChunks(%s, %d) = %q, %v", name, size, got, err)
+			}
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.ReadChunks("a.txt", 1, func([]byte) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("ReadChunks = %v after %d calls, want the callback error after 1", err, calls)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	s := testSource(t)
+	want := []string{"first", "second", "third", "", "last"}
+	for _, name := range []string{"a.txt", "dir/a.txt.gz"} {
+		var got []string
+		err := s.ReadLines(name, func(line string) error {
+			got = append(got, line)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadLines(%s) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	var lens []int
+	err := s.ReadLines("dir/long.txt", func(line string) error {
+		lens = append(lens, len(line))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(lens, []int{100_000, 3}) {
+		t.Errorf("ReadLines(dir/long.txt) line lengths = %v, %v", lens, err)
+	}
+}
+
+func TestSub(t *testing.T) {
+	sub, err := testSource(t).Sub("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := sub.ReadAll("sub/b.txt"); err != nil || string(b) != "b" {
+		t.Errorf("ReadAll(sub/b.txt) = %q, %v", b, err)
+	}
+	if _, err := sub.ReadAll("../a.txt"); err == nil {
+		t.Error("Sub lets ../a.txt out")
+	}
+}
+
+func TestModuleRoot(t *testing.T) {
+	root, err := ModuleRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "14-files", "source", "source.go")); err != nil {
+		t.Errorf("ModuleRoot = %s: %v", root, err)
+	}
+
+	t.Setenv(RootEnv, root)
+	if got, err := ModuleRoot(); err != nil || got != root {
+		t.Errorf("with $%s: ModuleRoot = %s, %v", RootEnv, got, err)
+	}
+	t.Setenv(RootEnv, t.TempDir())
+	if _, err := ModuleRoot(); !errors.Is(err, ErrNoRoot) {
+		t.Errorf("with $%s set to a directory without go.mod: %v, want ErrNoRoot", RootEnv, err)
+	}
+}
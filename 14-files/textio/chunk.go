@@ -0,0 +1,209 @@
+package textio
+
+import (
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkMode tells ChunkReader where a chunk may end.
+type ChunkMode int
+
+const (
+	// Runes ends chunks on rune boundaries, never inside a UTF-8 sequence.
+	Runes ChunkMode = iota
+	// Graphemes also keeps a base rune together with the combining marks,
+	// variation selectors and zero width joiners that follow it, so "s" +
+	// U+030C (s with a combining caron) is never split.
+	Graphemes
+)
+
+// ChunkOptions configures a ChunkReader.
+type ChunkOptions struct {
+	Size int // maximum chunk size in bytes, <= 0 means 4096
+	Mode ChunkMode
+}
+
+// InvalidUTF8Error reports a byte that does not start a valid UTF-8
+// sequence.
+type InvalidUTF8Error struct {
+	Offset int64 // byte offset of the bad byte in the stream
+	Byte   byte
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	return fmt.Sprintf("invalid UTF-8 byte 0x%02x at offset %d", e.Byte, e.Offset)
+}
+
+// graphemeLookahead is how far past Size we read to see the marks that
+// follow a chunk boundary.
+const graphemeLookahead = 64
+
+// ChunkReader splits a stream into chunks of at most Size bytes that never
+// end in the middle of a character. A multi-byte sequence cut by a read is
+// carried over and completed by the next one.
+//
+// A chunk can be longer than Size only when a single rune (or, in Graphemes
+// mode, a single base rune with its marks) does not fit in Size.
+type ChunkReader struct {
+	r   io.Reader
+	opt ChunkOptions
+
+	data []byte // read buffer, pending bytes are data[rp:wp]
+	rp   int
+	wp   int
+	off  int64 // stream offset of data[rp]
+	last int64 // stream offset of the last chunk
+	eof  bool
+	err  error
+}
+
+// NewChunkReader returns a ChunkReader reading from r.
+func NewChunkReader(r io.Reader, opt ChunkOptions) *ChunkReader {
+	if opt.Size <= 0 {
+		opt.Size = 4096
+	}
+	return &ChunkReader{r: r, opt: opt}
+}
+
+// Offset returns the byte offset of the chunk returned by the last call to
+// Next.
+func (c *ChunkReader) Offset() int64 {
+	return c.last
+}
+
+func (c *ChunkReader) pending() []byte {
+	return c.data[c.rp:c.wp]
+}
+
+// fill reads until at least n bytes are pending or the input ends.
+func (c *ChunkReader) fill(n int) {
+	if c.wp-c.rp >= n || c.eof {
+		return
+	}
+	// Move the pending bytes to the front. This overwrites the chunk
+	// returned by the previous call, which is why it is only valid until
+	// the next one.
+	if len(c.data) < n {
+		data := make([]byte, 2*n)
+		c.wp = copy(data, c.pending())
+		c.data = data
+	} else {
+		c.wp = copy(c.data, c.pending())
+	}
+	c.rp = 0
+
+	for c.wp < n && !c.eof {
+		m, err := c.r.Read(c.data[c.wp:])
+		c.wp += m
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			c.eof = true
+			c.err = err
+		}
+	}
+}
+
+// Next returns the next chunk, or io.EOF after the last one. The returned
+// slice is only valid until the next call.
+//
+// When the stream contains an invalid byte, Next first returns the valid
+// text before it, then a nil chunk with an *InvalidUTF8Error. The bad byte
+// is skipped, so reading can continue with the following call.
+func (c *ChunkReader) Next() ([]byte, error) {
+	want := c.opt.Size + utf8.UTFMax
+	if c.opt.Mode == Graphemes {
+		want += graphemeLookahead
+	}
+	c.fill(want)
+
+	buf := c.pending()
+	if len(buf) == 0 {
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, io.EOF
+	}
+
+	pos := 0
+	for pos < len(buf) {
+		r, n := utf8.DecodeRune(buf[pos:])
+		if r == utf8.RuneError && n <= 1 {
+			if pos > 0 {
+				break
+			}
+			e := &InvalidUTF8Error{Offset: c.off, Byte: buf[0]}
+			c.advance(1)
+			return nil, e
+		}
+		if pos > 0 && pos+n > c.opt.Size {
+			break
+		}
+		pos += n
+		if pos >= c.opt.Size {
+			break
+		}
+	}
+
+	if c.opt.Mode == Graphemes {
+		pos = c.graphemeBoundary(pos)
+	}
+
+	chunk := c.pending()[:pos]
+	c.last = c.off
+	c.advance(pos)
+	return chunk, nil
+}
+
+func (c *ChunkReader) advance(n int) {
+	c.rp += n
+	c.off += int64(n)
+}
+
+// graphemeBoundary moves pos so that it does not separate a base rune from
+// the marks that follow it. It moves back if it can and forward otherwise.
+func (c *ChunkReader) graphemeBoundary(pos int) int {
+	buf := c.pending()
+	p := pos
+	for p > 0 && p < len(buf) && joinsPrevious(buf, p) {
+		_, n := utf8.DecodeLastRune(buf[:p])
+		p -= n
+	}
+	if p > 0 {
+		return p
+	}
+
+	// The whole chunk is one cluster, keep reading until it ends.
+	_, p = utf8.DecodeRune(buf)
+	for {
+		if len(buf)-p < utf8.UTFMax && !c.eof {
+			c.fill(len(buf) + graphemeLookahead)
+			buf = c.pending()
+		}
+		if p >= len(buf) || !joinsPrevious(buf, p) {
+			return p
+		}
+		_, n := utf8.DecodeRune(buf[p:])
+		p += n
+	}
+}
+
+// joinsPrevious reports whether the rune at buf[p:] belongs to the cluster
+// of the rune before it.
+func joinsPrevious(buf []byte, p int) bool {
+	r, _ := utf8.DecodeRune(buf[p:])
+	if isMark(r) {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRune(buf[:p])
+	return prev == zwj
+}
+
+const zwj = '\u200d' // zero width joiner
+
+func isMark(r rune) bool {
+	return r == zwj ||
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector)
+}
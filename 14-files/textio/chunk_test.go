@@ -0,0 +1,102 @@
+package textio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// chunks reads all chunks of s, one byte per Read, and returns them with
+// the errors other than io.EOF written as "!error".
+func chunks(t *testing.T, s string, opt ChunkOptions) []string {
+	t.Helper()
+	cr := NewChunkReader(iotest.OneByteReader(strings.NewReader(s)), opt)
+	var got []string
+	for {
+		chunk, err := cr.Next()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			got = append(got, "!"+err.Error())
+			continue
+		}
+		got = append(got, string(chunk))
+	}
+}
+
+func TestChunkReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opt  ChunkOptions
+		want []string
+	}{
+		{"ascii", "abcdefg", ChunkOptions{Size: 3}, []string{"abc", "def", "g"}},
+		{"runes", "Čaša, ćup", ChunkOptions{Size: 3}, []string{"Ča", "ša", ", ", "ću", "p"}},
+		{"rune longer than size", "čćš", ChunkOptions{Size: 1}, []string{"č", "ć", "š"}},
+		{"four byte rune", "a😀b", ChunkOptions{Size: 2}, []string{"a", "😀", "b"}},
+		{"combining mark split by runes", "s\u030ca", ChunkOptions{Size: 1}, []string{"s", "\u030c", "a"}},
+		{"combining mark kept by graphemes", "as\u030cb", ChunkOptions{Size: 2, Mode: Graphemes}, []string{"a", "s\u030c", "b"}},
+		{"cluster longer than size", "s\u030c\u030cx", ChunkOptions{Size: 1, Mode: Graphemes}, []string{"s\u030c\u030c", "x"}},
+		{"zero width joiner", "\U0001f469\u200d\U0001f4bbx", ChunkOptions{Size: 4, Mode: Graphemes}, []string{"\U0001f469\u200d\U0001f4bb", "x"}},
+		{"variation selector", "x\u2764\ufe0f", ChunkOptions{Size: 4, Mode: Graphemes}, []string{"x", "\u2764\ufe0f"}},
+		{"invalid byte", "ab\xffcd", ChunkOptions{Size: 10}, []string{"ab", "!invalid UTF-8 byte 0xff at offset 2", "cd"}},
+		{"invalid first byte", "\xc4x", ChunkOptions{Size: 10}, []string{"!invalid UTF-8 byte 0xc4 at offset 0", "x"}},
+		{"cut sequence at end", "ok\xc4", ChunkOptions{Size: 10}, []string{"ok", "!invalid UTF-8 byte 0xc4 at offset 2"}},
+		{"empty", "", ChunkOptions{Size: 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunks(t, tt.in, tt.opt); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkReaderOffset(t *testing.T) {
+	cr := NewChunkReader(strings.NewReader("čab\xffd"), ChunkOptions{Size: 3})
+	var offsets []int64
+	var bad *InvalidUTF8Error
+	for {
+		_, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.As(err, &bad) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, cr.Offset())
+	}
+	if want := []int64{0, 3, 5}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets = %v, want %v", offsets, want)
+	}
+	if bad == nil || bad.Offset != 4 || bad.Byte != 0xff {
+		t.Errorf("invalid byte error = %+v", bad)
+	}
+}
+
+func TestChunkReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader("abcd"), iotest.ErrReader(boom))
+	cr := NewChunkReader(r, ChunkOptions{Size: 3})
+	var got []string
+	var err error
+	for {
+		var chunk []byte
+		if chunk, err = cr.Next(); err != nil {
+			break
+		}
+		got = append(got, string(chunk))
+	}
+	if !errors.Is(err, boom) || !reflect.DeepEqual(got, []string{"abc", "d"}) {
+		t.Errorf("chunks %q, err %v; want the text before the error, then boom", got, err)
+	}
+}
@@ -0,0 +1,255 @@
+package textio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrLineTooLong is wrapped by the LineError returned for a line longer than
+// LineOptions.MaxLen.
+var ErrLineTooLong = errors.New("textio: line too long")
+
+// Reasons passed to FollowOptions.OnReset.
+var (
+	ErrTruncated = errors.New("textio: file truncated")
+	ErrRotated   = errors.New("textio: file rotated")
+)
+
+// Line is one line of input without its terminator.
+type Line struct {
+	Text      string
+	Number    int   // 1-based line number
+	Offset    int64 // byte offset of the first byte of the line
+	Truncated bool  // Text holds only the first MaxLen bytes of the line
+}
+
+// LineError reports a problem with a single line. Reading can continue
+// with the next line.
+type LineError struct {
+	Number int
+	Offset int64
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d (offset %d): %v", e.Number, e.Offset, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LineOptions configures a LineReader.
+type LineOptions struct {
+	// MaxLen caps the number of bytes kept per line, 0 means no limit.
+	// Longer lines are returned as a *LineError wrapping ErrLineTooLong,
+	// or, if Truncate is set, cut to MaxLen bytes.
+	MaxLen   int
+	Truncate bool
+}
+
+// LineReader reads lines ending in "\n", "\r\n" or a lone "\r". Unlike
+// bufio.Scanner it has no fixed limit on the line length.
+type LineReader struct {
+	br  *bufio.Reader
+	opt LineOptions
+
+	num    int
+	off    int64 // bytes consumed so far
+	skipLF bool  // the last byte was '\r', so a '\n' now ends nothing
+
+	// The line being read, kept between calls while following a file.
+	buf   []byte
+	start int64
+	long  bool
+}
+
+// NewLineReader returns a LineReader reading from r.
+func NewLineReader(r io.Reader, opt LineOptions) *LineReader {
+	return &LineReader{br: bufio.NewReader(r), opt: opt}
+}
+
+// Offset returns the number of bytes consumed from the input.
+func (lr *LineReader) Offset() int64 {
+	return lr.off
+}
+
+// Next returns the next line, or io.EOF when there are no more. A final
+// line without a terminator is still returned.
+func (lr *LineReader) Next() (Line, error) {
+	return lr.next(true)
+}
+
+// next reads one line. If final is false, a line that is not terminated yet
+// is kept and io.EOF is returned, so that the rest of it can be read once
+// more data arrives.
+func (lr *LineReader) next(final bool) (Line, error) {
+	for {
+		b, err := lr.br.ReadByte()
+		if err != nil {
+			if err == io.EOF && final && lr.off > lr.start {
+				return lr.emit()
+			}
+			return Line{}, err
+		}
+		lr.off++
+
+		switch {
+		case b == '\n' && lr.skipLF:
+			lr.skipLF = false
+			lr.start = lr.off
+			continue
+		case b == '\n' || b == '\r':
+			lr.skipLF = b == '\r'
+			return lr.emit()
+		}
+		lr.skipLF = false
+
+		if lr.opt.MaxLen > 0 && len(lr.buf) >= lr.opt.MaxLen {
+			lr.long = true
+			continue
+		}
+		lr.buf = append(lr.buf, b)
+	}
+}
+
+func (lr *LineReader) emit() (Line, error) {
+	lr.num++
+	l := Line{Text: string(lr.buf), Number: lr.num, Offset: lr.start}
+	long := lr.long
+
+	lr.buf = lr.buf[:0]
+	lr.start = lr.off
+	lr.long = false
+
+	if long {
+		if !lr.opt.Truncate {
+			return Line{}, &LineError{Number: l.Number, Offset: l.Offset, Err: ErrLineTooLong}
+		}
+		l.Truncated = true
+	}
+	return l, nil
+}
+
+// FollowOptions configures Follow.
+type FollowOptions struct {
+	// LineOptions.MaxLen caps the line length as in LineReader, but Follow
+	// always delivers longer lines cut to MaxLen with Line.Truncated set,
+	// as if Truncate were set, so that no line is lost.
+	LineOptions
+	Poll time.Duration // how often to check for new data, default 250ms
+
+	// FromStart reads the existing lines first instead of skipping them.
+	// Without it Line.Number counts from the end of the file at the time
+	// Follow was called, because counting the lines before it would mean
+	// reading the whole file. Line.Offset is always the offset in the file.
+	FromStart bool
+
+	// OnReset is called with ErrTruncated or ErrRotated when Follow starts
+	// over at the beginning of the file. Line numbers start over too.
+	OnReset func(reason error)
+}
+
+// Follow works like "tail -f": it calls fn for every line appended to the
+// file at path until ctx is done or fn returns an error, and returns that
+// error.
+//
+// If the file gets shorter, it was truncated and is read again from the
+// start. If path names a different file than before, the old file was
+// rotated (renamed or removed and created again); the rest of the old file
+// is read and the new one is followed from its start.
+func Follow(ctx context.Context, path string, opt FollowOptions, fn func(Line) error) error {
+	if opt.Poll <= 0 {
+		opt.Poll = 250 * time.Millisecond
+	}
+	opt.Truncate = true
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	var skip int64
+	if !opt.FromStart {
+		if skip, err = f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	lr := NewLineReader(f, opt.LineOptions)
+	lr.off, lr.start = skip, skip
+
+	drain := func(final bool) error {
+		for {
+			l, err := lr.next(final)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+	}
+	reset := func(reason error) {
+		if opt.OnReset != nil {
+			opt.OnReset(reason)
+		}
+	}
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		if err := drain(false); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			t.Reset(opt.Poll)
+		}
+
+		cur, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		st, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // being rotated, the new file is not there yet
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !os.SameFile(cur, st):
+			if err := drain(true); err != nil {
+				return err
+			}
+			nf, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			f.Close()
+			f = nf
+			lr = NewLineReader(f, opt.LineOptions)
+			reset(ErrRotated)
+
+		case cur.Size() < lr.off:
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			lr = NewLineReader(f, opt.LineOptions)
+			reset(ErrTruncated)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package textio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// lines reads all lines of s, one byte per Read, and returns them as
+// "number@offset text", with errors written as "!error".
+func lines(t *testing.T, s string, opt LineOptions) []string {
+	t.Helper()
+	lr := NewLineReader(iotest.OneByteReader(strings.NewReader(s)), opt)
+	var got []string
+	for {
+		l, err := lr.Next()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			got = append(got, "!"+err.Error())
+			continue
+		}
+		text := fmt.Sprintf("%d@%d %s", l.Number, l.Offset, l.Text)
+		if l.Truncated {
+			text += "..."
+		}
+		got = append(got, text)
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opt  LineOptions
+		want []string
+	}{
+		{"lf", "a\nbc\n", LineOptions{}, []string{"1@0 a", "2@2 bc"}},
+		{"crlf", "a\r\nbc\r\n", LineOptions{}, []string{"1@0 a", "2@3 bc"}},
+		{"cr", "a\rbc\r", LineOptions{}, []string{"1@0 a", "2@2 bc"}},
+		{"mixed", "a\r\n\nb\r\rc", LineOptions{}, []string{"1@0 a", "2@3 ", "3@4 b", "4@6 ", "5@7 c"}},
+		{"no final terminator", "a\nb", LineOptions{}, []string{"1@0 a", "2@2 b"}},
+		{"empty", "", LineOptions{}, nil},
+		{"only terminator", "\n", LineOptions{}, []string{"1@0 "}},
+		{"long line", "abcdef\nab\n", LineOptions{MaxLen: 3}, []string{
+			"!line 1 (offset 0): textio: line too long", "2@7 ab",
+		}},
+		{"long line truncated", "abcdef\nabc\n", LineOptions{MaxLen: 3, Truncate: true}, []string{"1@0 abc...", "2@7 abc"}},
+		{"long last line", "ab\nabcd", LineOptions{MaxLen: 3, Truncate: true}, []string{"1@0 ab", "2@3 abc..."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lines(t, tt.in, tt.opt); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineReaderNoLimit(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	lr := NewLineReader(strings.NewReader(long+"\nend"), LineOptions{})
+	l, err := lr.Next()
+	if err != nil || l.Text != long {
+		t.Fatalf("Next = %d bytes, %v; want %d bytes", len(l.Text), err, len(long))
+	}
+	if l, err = lr.Next(); err != nil || l.Text != "end" {
+		t.Errorf("Next = %q, %v; want end", l.Text, err)
+	}
+	if lr.Offset() != int64(len(long)+4) {
+		t.Errorf("Offset = %d", lr.Offset())
+	}
+}
+
+func TestLineErrorIs(t *testing.T) {
+	lr := NewLineReader(strings.NewReader("abcd\n"), LineOptions{MaxLen: 2})
+	_, err := lr.Next()
+	var le *LineError
+	if !errors.Is(err, ErrLineTooLong) || !errors.As(err, &le) || le.Number != 1 {
+		t.Errorf("Next = %v, want a *LineError for line 1 wrapping ErrLineTooLong", err)
+	}
+}
+
+// follower runs Follow on path and sends what it sees on a channel: lines
+// as "number@offset text" and resets as their error text.
+type follower struct {
+	events  chan string
+	done    chan error
+	cancel  context.CancelFunc
+	stopped bool
+	err     error
+}
+
+// stop cancels Follow and returns its error.
+func (fl *follower) stop() error {
+	if !fl.stopped {
+		fl.cancel()
+		fl.err = <-fl.done
+		fl.stopped = true
+	}
+	return fl.err
+}
+
+func follow(t *testing.T, path string, opt FollowOptions) *follower {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	fl := &follower{events: make(chan string, 100), done: make(chan error, 1), cancel: cancel}
+	opt.Poll = time.Millisecond
+	opt.OnReset = func(reason error) { fl.events <- reason.Error() }
+	go func() {
+		fl.done <- Follow(ctx, path, opt, func(l Line) error {
+			text := fmt.Sprintf("%d@%d %s", l.Number, l.Offset, l.Text)
+			if l.Truncated {
+				text += "..."
+			}
+			fl.events <- text
+			return nil
+		})
+	}()
+	t.Cleanup(func() { fl.stop() })
+	return fl
+}
+
+// expect waits for the given events in order.
+func (fl *follower) expect(t *testing.T, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-fl.events:
+			if got != w {
+				t.Fatalf("got %q, want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", w)
+		}
+	}
+}
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	appendFile(t, path, "old\n")
+	fl := follow(t, path, FollowOptions{LineOptions: LineOptions{MaxLen: 5}})
+
+	// The existing line is skipped, but a line started later is completed
+	// before it is delivered. Offsets are in the file, numbers count from
+	// where following started.
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, "first\nsec")
+	fl.expect(t, "1@4 first")
+	appendFile(t, path, "ond\n")
+	fl.expect(t, "2@10 secon...")
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "a\n")
+	fl.expect(t, "textio: file truncated", "1@0 a")
+
+	// The rest of the rotated file is read before the new one.
+	appendFile(t, path, "b\n")
+	fl.expect(t, "2@2 b")
+	appendFile(t, path, "c\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "new\n")
+	fl.expect(t, "3@4 c", "textio: file rotated", "1@0 new")
+
+	if err := fl.stop(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Follow = %v, want context.Canceled", err)
+	}
+}
+
+func TestFollowFromStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	appendFile(t, path, "one\ntwo\n")
+	fl := follow(t, path, FollowOptions{FromStart: true})
+	fl.expect(t, "1@0 one", "2@4 two")
+	appendFile(t, path, "three\n")
+	fl.expect(t, "3@8 three")
+}
+
+func TestFollowStopsOnCallbackError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	appendFile(t, path, "one\n")
+	stop := errors.New("stop")
+	err := Follow(context.Background(), path, FollowOptions{FromStart: true, Poll: time.Millisecond}, func(Line) error {
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Follow = %v, want the callback error", err)
+	}
+	if err := Follow(context.Background(), path+".missing", FollowOptions{}, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Follow of a missing file = %v, want os.ErrNotExist", err)
+	}
+}
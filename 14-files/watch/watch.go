@@ -0,0 +1,298 @@
+// Package watch reports changes to the files under a directory. It compares
+// periodic snapshots made with os.Stat, so it needs no support from the
+// operating system, such as inotify, and works on any file system.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"learngo/09-conc/clock"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Op is the kind of change.
+type Op int
+
+const (
+	Create Op = iota
+	Modify
+	Remove
+	Rename
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Modify:
+		return "modify"
+	case Remove:
+		return "remove"
+	}
+	return "rename"
+}
+
+// Event is a change to one file.
+type Event struct {
+	Op      Op
+	Path    string // slash separated, relative to the watched directory
+	OldPath string // for Rename, the path before it
+}
+
+func (e Event) String() string {
+	if e.Op == Rename {
+		return e.Op.String() + " " + e.OldPath + " -> " + e.Path
+	}
+	return e.Op.String() + " " + e.Path
+}
+
+// Options configures Watch.
+type Options struct {
+	Interval time.Duration // time between snapshots, default 500ms
+
+	// Debounce is how long the files must stay unchanged before the
+	// collected events are sent. An editor that saves a file in several
+	// steps then produces a single batch. Default 0, every snapshot that
+	// finds changes sends them.
+	Debounce time.Duration
+
+	// Include and Exclude are path.Match patterns. A pattern without a "/"
+	// is matched against the file name, otherwise against the whole path.
+	// With Include set, only files matching one of its patterns are
+	// watched. Files and directories matching Exclude are never watched.
+	Include []string
+	Exclude []string
+
+	// OnError is called when a snapshot fails, for example because the
+	// directory was removed. The watcher keeps trying.
+	OnError func(error)
+
+	Clock clock.Clock // default clock.Real()
+}
+
+// Watch takes a snapshot of root and returns a channel on which it sends
+// the changes found by the following snapshots, sorted by path. Changes to
+// the same file between two sends are coalesced into one event: a file
+// that was created and modified is reported as created, one that was
+// created and removed is not reported at all. A removed file that shows up
+// under another path is reported as renamed.
+//
+// Only regular files are watched. A change that keeps both the size and
+// the modification time of a file cannot be seen.
+//
+// The channel is closed when ctx is cancelled.
+func Watch(ctx context.Context, root string, opt Options) (<-chan []Event, error) {
+	if opt.Interval <= 0 {
+		opt.Interval = 500 * time.Millisecond
+	}
+	if opt.Clock == nil {
+		opt.Clock = clock.Real()
+	}
+	for _, p := range append(opt.Include, opt.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &watcher{root: root, opt: opt, pending: map[string]Event{}}
+	snap, err := w.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	w.snap = snap
+
+	events := make(chan []Event)
+	go w.run(ctx, events)
+	return events, nil
+}
+
+type watcher struct {
+	root       string
+	opt        Options
+	snap       map[string]fs.FileInfo
+	pending    map[string]Event
+	lastChange time.Time
+}
+
+func (w *watcher) run(ctx context.Context, events chan<- []Event) {
+	defer close(events)
+	for {
+		wait := w.opt.Interval
+		if len(w.pending) > 0 {
+			if d := w.lastChange.Add(w.opt.Debounce).Sub(w.opt.Clock.Now()); d < wait {
+				wait = d
+			}
+		}
+		t := w.opt.Clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C():
+		}
+
+		snap, err := w.snapshot()
+		if err != nil {
+			if w.opt.OnError != nil {
+				w.opt.OnError(err)
+			}
+			continue
+		}
+		if changes := diff(w.snap, snap); len(changes) > 0 {
+			for _, e := range changes {
+				w.add(e)
+			}
+			w.lastChange = w.opt.Clock.Now()
+		}
+		w.snap = snap
+
+		if len(w.pending) == 0 || w.opt.Clock.Now().Sub(w.lastChange) < w.opt.Debounce {
+			continue
+		}
+		batch := make([]Event, 0, len(w.pending))
+		for _, e := range w.pending {
+			batch = append(batch, e)
+		}
+		sort.Slice(batch, func(i, j int) bool { return batch[i].Path < batch[j].Path })
+		select {
+		case events <- batch:
+			clear(w.pending)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// add coalesces e with the pending event for the same file.
+func (w *watcher) add(e Event) {
+	switch e.Op {
+	case Create:
+		if p, ok := w.pending[e.Path]; ok && p.Op == Remove {
+			e.Op = Modify
+		}
+	case Modify:
+		if p, ok := w.pending[e.Path]; ok {
+			e = p // created, renamed or modified stays that
+		}
+	case Remove:
+		switch p, ok := w.pending[e.Path]; {
+		case ok && p.Op == Create:
+			delete(w.pending, e.Path)
+			return
+		case ok && p.Op == Rename:
+			delete(w.pending, e.Path)
+			e.Path = p.OldPath
+		}
+	case Rename:
+		if p, ok := w.pending[e.OldPath]; ok {
+			delete(w.pending, e.OldPath)
+			switch p.Op {
+			case Create:
+				e = Event{Op: Create, Path: e.Path}
+			case Rename:
+				e.OldPath = p.OldPath
+			}
+		}
+		if e.Op == Rename && e.OldPath == e.Path {
+			e = Event{Op: Modify, Path: e.Path} // renamed back
+		}
+	}
+	w.pending[e.Path] = e
+}
+
+// diff returns the changes from old to cur.
+func diff(old, cur map[string]fs.FileInfo) []Event {
+	var changes, created []Event
+	var removed []string
+	for p, st := range cur {
+		prev, ok := old[p]
+		switch {
+		case !ok:
+			created = append(created, Event{Op: Create, Path: p})
+		case prev.Size() != st.Size() || !prev.ModTime().Equal(st.ModTime()) || !os.SameFile(prev, st):
+			changes = append(changes, Event{Op: Modify, Path: p})
+		}
+	}
+	for p := range old {
+		if _, ok := cur[p]; !ok {
+			removed = append(removed, p)
+		}
+	}
+
+	// A created file that is the same file as a removed one was renamed.
+	sort.Strings(removed)
+	for _, c := range created {
+		for i, r := range removed {
+			if os.SameFile(old[r], cur[c.Path]) {
+				c = Event{Op: Rename, Path: c.Path, OldPath: r}
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+		changes = append(changes, c)
+	}
+	for _, r := range removed {
+		changes = append(changes, Event{Op: Remove, Path: r})
+	}
+	return changes
+}
+
+// snapshot returns the watched files under root by path.
+func (w *watcher) snapshot() (map[string]fs.FileInfo, error) {
+	snap := map[string]fs.FileInfo{}
+	err := filepath.WalkDir(w.root, func(full string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && full != w.root {
+			return nil // removed while walking
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(w.root, full)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+
+		if match(w.opt.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || len(w.opt.Include) > 0 && !match(w.opt.Include, rel) {
+			return nil
+		}
+		st, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		snap[rel] = st
+		return nil
+	})
+	return snap, err
+}
+
+func match(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		name := rel
+		if !strings.Contains(p, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"learngo/09-conc/clock"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestAdd(t *testing.T) {
+	create := func(p string) Event { return Event{Op: Create, Path: p} }
+	modify := func(p string) Event { return Event{Op: Modify, Path: p} }
+	remove := func(p string) Event { return Event{Op: Remove, Path: p} }
+	rename := func(from, to string) Event { return Event{Op: Rename, Path: to, OldPath: from} }
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   []Event
+	}{
+		{"create+modify", []Event{create("a"), modify("a")}, []Event{create("a")}},
+		{"create+remove", []Event{create("a"), remove("a")}, nil},
+		{"remove+create", []Event{remove("a"), create("a")}, []Event{modify("a")}},
+		{"modify+remove", []Event{modify("a"), remove("a")}, []Event{remove("a")}},
+		{"rename+modify", []Event{rename("a", "b"), modify("b")}, []Event{rename("a", "b")}},
+		{"create+rename", []Event{create("a"), rename("a", "b")}, []Event{create("b")}},
+		{"rename chain", []Event{rename("a", "b"), rename("b", "c"), rename("c", "d")}, []Event{rename("a", "d")}},
+		{"rename back", []Event{rename("a", "b"), rename("b", "a")}, []Event{modify("a")}},
+		{"rename+remove", []Event{rename("a", "b"), remove("b")}, []Event{remove("a")}},
+		{"two files", []Event{create("b"), remove("a")}, []Event{remove("a"), create("b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &watcher{pending: map[string]Event{}}
+			for _, e := range tt.events {
+				w.add(e)
+			}
+			var got []Event
+			for _, e := range w.pending {
+				got = append(got, e)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("pending = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func write(t *testing.T, name, text string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// step moves f forward by d once the watcher waits on its timer, and waits
+// until the snapshot is done and the next timer is armed.
+func step(f *clock.Fake, d time.Duration) {
+	f.BlockUntil(1)
+	f.Advance(d)
+	f.BlockUntil(1)
+}
+
+func TestWatchDebounce(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "old.txt"), "old")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := clock.NewFake(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	events, err := Watch(ctx, dir, Options{Interval: 100 * time.Millisecond, Debounce: 250 * time.Millisecond, Clock: f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := func(when string) {
+		t.Helper()
+		select {
+		case batch := <-events:
+			t.Fatalf("%s: got %v before the debounce time", when, batch)
+		default:
+		}
+	}
+
+	// An editor saving a file in several steps.
+	write(t, filepath.Join(dir, "new.txt"), "1")
+	step(f, 100*time.Millisecond)
+	none("after create")
+	write(t, filepath.Join(dir, "new.txt"), "12")
+	if err := os.Rename(filepath.Join(dir, "old.txt"), filepath.Join(dir, "renamed.txt")); err != nil {
+		t.Fatal(err)
+	}
+	step(f, 100*time.Millisecond)
+	none("after modify")
+	step(f, 100*time.Millisecond)
+	none("100ms after the last change")
+	step(f, 100*time.Millisecond)
+	none("200ms after the last change")
+
+	f.Advance(50 * time.Millisecond)
+	batch := <-events
+	want := "[create new.txt rename old.txt -> renamed.txt]"
+	if fmt.Sprint(batch) != want {
+		t.Errorf("batch = %v, want %s", batch, want)
+	}
+}
+
+func TestWatchClosesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := clock.NewFake(time.Now())
+	events, err := Watch(ctx, t.TempDir(), Options{Clock: f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.BlockUntil(1)
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("got a batch from an unchanged directory")
+	}
+}
@@ -0,0 +1,255 @@
+/*
+Praćenje promena
+================
+
+Često želimo da program reaguje kada se neka datoteka promeni: da ponovo
+učita konfiguraciju, da ponovo prevede i pokrene program ili da sinhronizuje
+direktorijum. Operativni sistemi za to imaju posebne mehanizme (inotify na
+Linux-u, kqueue na BSD-u i macOS-u, ReadDirectoryChangesW na Windows-u), ali
+svaki radi drugačije i nijedan nije u standardnoj biblioteci.
+
+Najjednostavniji način koji radi svuda je prozivanje (polling): na svakih
+pola sekunde obiđemo direktorijum, za svaku datoteku zapamtimo rezultat
+os.Stat (snapshot) i uporedimo ga sa prethodnim:
+
+- datoteka koje ranije nije bilo je napravljena (create),
+- datoteka kojoj su se promenili veličina ili vreme izmene je izmenjena
+  (modify),
+- datoteka koje više nema je obrisana (remove),
+- ako je nova datoteka ista kao obrisana (os.SameFile poredi broj inode-a),
+  datoteka je preimenovana (rename).
+
+Cena je jedan os.Stat po datoteci na svakih pola sekunde, što je za
+direktorijum sa nekoliko stotina datoteka zanemarljivo.
+
+Paket "learngo/14-files/watch" šalje promene na kanal:
+
+	events, err := watch.Watch(ctx, "14-files", watch.Options{
+		Include: []string{"*.go", "*.txt"},
+		Exclude: []string{".git"},
+	})
+	for batch := range events {
+		...
+	}
+
+Debounce i spajanje događaja
+----------------------------
+Editor pri čuvanju datoteke često napravi više promena zaredom: upiše
+privremenu datoteku, preimenuje je, promeni prava pristupa. Da ne bismo
+program pokretali tri puta, Watch događaje skuplja dok se datoteke ne
+smire, koliko kaže Options.Debounce, i onda ih šalje zajedno, kao jedan
+niz ([]Event).
+
+Događaji za istu datoteku se pri tom spajaju: napravljena pa izmenjena
+datoteka je napravljena, napravljena pa obrisana se ne prijavljuje, a
+obrisana pa ponovo napravljena je izmenjena.
+
+Lažni sat
+---------
+Watch vreme čita iz Options.Clock, tipa clock.Clock iz poglavlja o
+konkurentnosti. U primeru koristimo clock.Fake, pa vreme pomeramo ručno,
+metodom Advance, i primer ne mora da čeka. BlockUntil(1) čeka da Watch
+postavi tajmer za sledeći snapshot, tj. da završi sa prethodnim.
+*/
+
+package files
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"learngo/09-conc/clock"
+	"learngo/14-files/watch"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func watchEvents() {
+
+	fmt.Println("\n --- watchEvents ---")
+
+	dir, err := os.MkdirTemp("", "watch")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	file := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	os.Mkdir(file("tmp"), 0755)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	events, err := watch.Watch(ctx, dir, watch.Options{
+		Interval: time.Second,
+		Debounce: 2 * time.Second,
+		Include:  []string{"*.go", "*.txt"},
+		Exclude:  []string{"tmp"},
+		Clock:    clk,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// tick sačeka da Watch postavi tajmer i pomeri vreme za jedan interval.
+	tick := func() {
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+	}
+	receive := func() {
+		for _, e := range <-events {
+			fmt.Println(" ", e)
+		}
+	}
+
+	clk.BlockUntil(1)
+	os.WriteFile(file("test.txt"), []byte("Hello\n"), 0644)
+	os.WriteFile(file("main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(file("notes.md"), []byte("not included\n"), 0644)
+	os.WriteFile(file("tmp/out.txt"), []byte("excluded\n"), 0644)
+	tick()
+	fmt.Println("1s: created test.txt and main.go")
+
+	clk.BlockUntil(1)
+	os.WriteFile(file("test.txt"), []byte("Hello World\n"), 0644)
+	tick()
+	fmt.Println("2s: modified test.txt")
+
+	tick()
+	fmt.Println("3s: nothing changed")
+	tick()
+	fmt.Println("4s: nothing changed for 2s, batch:")
+	receive()
+
+	clk.BlockUntil(1)
+	os.Rename(file("main.go"), file("lesson.go"))
+	os.Remove(file("test.txt"))
+	os.WriteFile(file("draft.txt"), []byte("draft\n"), 0644)
+	tick()
+	fmt.Println("5s: renamed main.go, removed test.txt, created draft.txt")
+
+	clk.BlockUntil(1)
+	os.Remove(file("draft.txt"))
+	os.WriteFile(file("test.txt"), []byte("Hello again\n"), 0644)
+	tick()
+	fmt.Println("6s: removed draft.txt, created test.txt")
+
+	tick()
+	tick()
+	fmt.Println("8s: batch:")
+	receive()
+}
+
+/*
+Program ispisuje:
+
+	>> 1s: created test.txt and main.go
+	>> 2s: modified test.txt
+	>> 3s: nothing changed
+	>> 4s: nothing changed for 2s, batch:
+	>>   create main.go
+	>>   create test.txt
+	>> 5s: renamed main.go, removed test.txt, created draft.txt
+	>> 6s: removed draft.txt, created test.txt
+	>> 8s: batch:
+	>>   rename main.go -> lesson.go
+	>>   modify test.txt
+
+notes.md ne odgovara nijednom obrascu iz Include, a direktorijum tmp je
+isključen, pa promene u njima nisu prijavljene. draft.txt je napravljen i
+obrisan pre slanja, pa se ne pojavljuje, a test.txt je obrisan i ponovo
+napravljen, što je izmena.
+
+Automatsko pokretanje lekcije
+-----------------------------
+Lekcije biramo tako što u main.go uklonimo komentar ispred poziva, na
+primer fl.ReadFiles(). Komanda watch pokreće zadatu komandu i pokreće je
+ponovo posle svake promene, pa dok menjamo lekciju ili test.txt, u
+terminalu uvek vidimo ispis najnovije verzije:
+
+	$ go build -o learngo . && ./learngo watch . go run .
+	watch: running go run .
+	...
+	watch: modify 14-files/readFiles.go
+	watch: running go run .
+	...
+
+Program se prekida sa Ctrl+C.
+*/
+
+// Watch runs the "watch" command: it runs a command and runs it again
+// every time a watched file under dir changes, until interrupted.
+func Watch(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	include := flags.String("include", "*.go,*.txt", "comma separated patterns of watched files")
+	exclude := flags.String("exclude", ".git", "comma separated patterns of ignored files and directories")
+	interval := flags.Duration("interval", 500*time.Millisecond, "time between checks")
+	debounce := flags.Duration("debounce", 200*time.Millisecond, "quiet time before running")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: learngo watch [flags] dir command [args...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return errors.New("watch: missing dir or command")
+	}
+	dir, command := flags.Arg(0), flags.Args()[1:]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	events, err := watch.Watch(ctx, dir, watch.Options{
+		Interval: *interval,
+		Debounce: *debounce,
+		Include:  splitPatterns(*include),
+		Exclude:  splitPatterns(*exclude),
+		OnError:  func(err error) { fmt.Fprintln(os.Stderr, "watch:", err) },
+	})
+	if err != nil {
+		return err
+	}
+
+	run := func() {
+		fmt.Fprintln(os.Stderr, "watch: running", strings.Join(command, " "))
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil && ctx.Err() == nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+		}
+	}
+	run()
+	for batch := range events {
+		for _, e := range batch {
+			fmt.Fprintln(os.Stderr, "watch:", e)
+		}
+		run()
+	}
+	return nil
+}
+
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func WatchFunc() {
+
+	fmt.Println("\n --- Watch Func ---")
+
+	watchEvents()
+}
@@ -17,10 +17,18 @@ Hajde odmah da pređemo na kod.
 package files
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"learngo/09-conc/bounded"
+	"learngo/09-conc/clock"
+	"learngo/14-files/appender"
+	"learngo/14-files/safewrite"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
 func writeString() {
@@ -170,6 +178,149 @@ lines je dat u nastavku:
 	>> Go is a compiled language.
 	>> It is easy to learn Go.
 
+Bezbedno upisivanje
+-------------------
+Sva tri programa iznad imaju isti problem. os.Create odmah skraćuje postojeću
+datoteku na nultu dužinu. Ako program pukne ili nestane struje dok pišemo,
+ostaje poluprazna datoteka, a stari sadržaj je izgubljen. Osim toga, Write
+uspešno upisuje podatke samo u keš operativnog sistema. Tek Sync (fsync)
+garantuje da su na disku, a greška iz Close može biti jedini znak da upis nije
+uspeo.
+
+Uobičajeno rešenje je:
+
+	1. Upišite novi sadržaj u privremenu datoteku u istom direktorijumu.
+	2. Pozovite Sync nad datotekom i zatvorite je, proveravajući obe greške.
+	3. Preimenujte privremenu datoteku u ciljnu. Na istom sistemu datoteka
+	   os.Rename je atomska operacija: drugi programi vide ili staru ili novu
+	   datoteku, nikada deo nove.
+	4. Pozovite Sync nad direktorijumom, da bi i samo preimenovanje bilo
+	   trajno zapisano.
+
+Paket "learngo/14-files/safewrite" radi upravo to:
+
+	func WriteFile(name string, data []byte, opt Options) error
+	func Write(name string, opt Options, fn func(w io.Writer) error) error
+
+Postojeća datoteka zadržava svoje dozvole (permissions). Sa Options.Backup
+prethodni sadržaj ostaje u datoteci name+".bak". Ako bilo koji korak ne uspe,
+privremena datoteka se briše, ciljna ostaje netaknuta, a vraćena greška
+sadrži sve greške spojene funkcijom errors.Join, pa greška iz Write ne
+sakriva grešku iz Close.
+
+Evo istih programa napisanih bezbedno.
+*/
+
+func writeStringSafe() {
+
+	fmt.Println("\n --- Write string to file safely ---")
+
+	s := "Hello World"
+	err := safewrite.WriteFile("wtest.txt", []byte(s), safewrite.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(len(s), "bytes written successfully")
+}
+
+func writeBytesSafe() {
+
+	fmt.Println("\n --- Write bytes to file safely ---")
+
+	// Rezervna kopija bi u radnom direktorijumu ostala posle svakog
+	// pokretanja, pa ovaj primer piše u privremeni direktorijum.
+	dir, err := os.MkdirTemp("", "safewrite")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "wbytes")
+	if err := os.WriteFile(name, []byte("old bytes"), 0644); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	d2 := []byte{104, 101, 108, 108, 111, 32, 98, 121, 116, 101, 115}
+	err = safewrite.WriteFile(name, d2, safewrite.Options{Backup: true})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(len(d2), "bytes written successfully")
+
+	bak, err := os.ReadFile(name + ".bak")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("wbytes.bak: %s\n", bak)
+}
+
+func writeSliceOfStringsSafe() {
+
+	fmt.Println("\n --- Write slice strings to file safely ---")
+
+	d := []string{
+		"Welcome to the world of Go1.",
+		"Go is a compiled language.",
+		"It is easy to learn Go.",
+	}
+	err := safewrite.Write("wlines", safewrite.Options{}, func(w io.Writer) error {
+		for _, v := range d {
+			if _, err := fmt.Fprintln(w, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("file written successfully")
+}
+
+/*
+Programi ispisuju isto što i ranije. Funkcija Write dobija fn koja piše u
+io.Writer, pa "Fprintln" radi kao i sa *os.File. writeBytesSafe prvo napravi
+datoteku wbytes sa starim sadržajem, pa posle upisa sa Options.Backup pored
+nje postoji i wbytes.bak:
+
+	>> 11 bytes written successfully
+	>> wbytes.bak: old bytes
+
+Ubacivanje grešaka
+------------------
+Kako proveriti da se ciljna datoteka ne menja kada upis ne uspe? Greške pri
+pisanju na disk je teško izazvati. Zato safewrite ne poziva direktno paket os,
+već radi preko interfejsa safewrite.FS i safewrite.File. safewrite.OS() vraća
+pravu implementaciju, a test je obmotava tipom koji vraća greške kada mi to
+želimo:
+
+	type faultyFS struct {
+		safewrite.FS
+		failWrite, failClose bool
+	}
+
+	func (fsys faultyFS) CreateTemp(dir, pattern string) (safewrite.File, error) {
+		f, err := fsys.FS.CreateTemp(dir, pattern)
+		...
+		return &faultyFile{File: f, failWrite: fsys.failWrite, failClose: fsys.failClose}, nil
+	}
+
+Takav kod pripada testovima, ne programu, pa je u datoteci
+"14-files/safewrite/safewrite_test.go". Test za svaku ubačenu grešku
+proverava da ciljna datoteka i dalje ima stari sadržaj i da nije ostala
+nijedna privremena datoteka. Pokreće se komandom:
+
+	go test ./14-files/safewrite
+
+Ako je ciljna datoteka simbolička veza (symlink), Write zamenjuje datoteku na
+koju veza pokazuje, a veza ostaje. Da je preimenovao privremenu datoteku
+preko same veze, veza bi postala obična datoteka.
+
 Dodavanje u datoteku
 --------------------
 U ovom odeljku, dodaćemo još jedan red datoteci "wlines" koju smo kreirali u
@@ -340,6 +491,170 @@ se deblokira i ispisuje
 
 Sada možete konkuretntno otvoriti datoteku u bilo kom uređivaču teksta i videti
 100 generisanih slučajnih brojeva :)
+
+Ograničen broj producer-a
+-------------------------
+Gornji program pokreće svih 100 "produce" gorutina odjednom. Pomoću funkcije
+"ParallelFor" iz paketa "learngo/09-conc/bounded" možemo ograničiti koliko njih
+radi u isto vreme. Ostatak programa, "consume" gorutina i done kanal, ostaju
+isti.
+*/
+
+func writeConcurentlyBounded() {
+
+	fmt.Println("\n --- Write concurently to file (bounded) ---")
+
+	data := make(chan int)
+	done := make(chan bool)
+
+	go consume(data, done)
+
+	err := bounded.ParallelFor(context.Background(), 100, bounded.Options{Limit: 10},
+		func(ctx context.Context, i int) error {
+			data <- rand.Intn(999)
+			return nil
+		})
+	close(data)
+
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	if d := <-done; d {
+		fmt.Println("File concurently written successfully")
+	} else {
+		fmt.Println("File concurently writing failed")
+	}
+}
+
+/*
+ParallelFor se vraća tek kada se svih 100 stavki završi, pa nam WaitGroup više
+nije potrebna i kanal data možemo zatvoriti odmah nakon poziva. U svakom
+trenutku najviše 10 gorutina šalje brojeve u kanal.
+*/
+
+/*
+Appender
+--------
+U programu "writeConcurently" jedna "consume" gorutina upisuje svaki broj
+posebnim pozivom "Fprintln" direktno u datoteku, dakle jedan sistemski poziv
+po broju, a uspeh javlja kroz kanal done. Za log datoteke obično nam treba i
+više od toga: da se upisi skupljaju u bafer, da datoteka ne raste beskonačno i
+da se stare datoteke brišu.
+
+Paket "learngo/14-files/appender" ima tip Appender koji:
+
+- implementira io.Writer i bezbedan je za istovremenu upotrebu iz više
+  gorutina, pa nam "consume" gorutina nije potrebna,
+- skuplja upise u bafer i upisuje ga kada naraste do Options.BufferSize ili
+  najkasnije svakih Options.FlushInterval,
+- rotira datoteku kada bi prešla Options.MaxSize bajtova ili kada je starija
+  od Options.MaxAge,
+- imenuje rotirane datoteke brojevima (wlog.1, wlog.2, ...) ili vremenom
+  (wlog.20240301-101500.000) i čuva najviše Options.Keep starih datoteka.
+
+Jedan poziv Write nikada se ne deli između dve datoteke, pa je svaki red iz
+"Fprintln" ceo u jednoj datoteci. Close upisuje ostatak bafera i vraća prvu
+grešku koja se dogodila tokom rada, pa zamenjuje kanal done.
+*/
+
+func writeConcurentlyAppender() {
+
+	fmt.Println("\n --- Write concurently to file (appender) ---")
+
+	a, err := appender.Open("wconcurrent", appender.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = bounded.ParallelFor(context.Background(), 100, bounded.Options{Limit: 10},
+		func(ctx context.Context, i int) error {
+			_, err := fmt.Fprintln(a, rand.Intn(999))
+			return err
+		})
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	if err := a.Close(); err != nil {
+		fmt.Println("File concurently writing failed:", err)
+		return
+	}
+	st := a.Stats()
+	fmt.Printf("File concurently written successfully: %d writes, %d flushes\n", st.Writes, st.Flushes)
+}
+
+/*
+Program ispisuje:
+
+	>> File concurently written successfully: 100 writes, 1 flushes
+
+Svih 100 brojeva je upisano u datoteku jednim sistemskim pozivom, prilikom
+zatvaranja.
+
+Rotacija
+--------
+U sledećem programu svaka datoteka može imati najviše 20 bajtova, a čuvamo
+dve stare. Sat je lažni sat iz paketa "learngo/09-conc/clock", pa možemo
+pokazati i rotaciju po starosti bez čekanja.
+*/
+
+func writeAppenderRotation() {
+
+	fmt.Println("\n --- Write with rotation ---")
+
+	dir, err := os.MkdirTemp("", "appender")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wlog")
+
+	fake := clock.NewFake(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	a, err := appender.Open(path, appender.Options{
+		MaxSize: 20,
+		MaxAge:  time.Hour,
+		Keep:    2,
+		Clock:   fake,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for i := 1; i <= 8; i++ {
+		fmt.Fprintf(a, "line %d\n", i) // 7 bytes, two lines per file
+	}
+	fake.Advance(time.Hour)
+	fmt.Fprintln(a, "an hour later")
+
+	if err := a.Close(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("rotations:", a.Stats().Rotations)
+
+	names, _ := a.Rotated()
+	for _, name := range append(names, path) {
+		b, _ := os.ReadFile(name)
+		fmt.Printf("%-7s %q\n", filepath.Base(name), b)
+	}
+}
+
+/*
+Program ispisuje:
+
+	>> rotations: 4
+	>> wlog.2  "line 5\nline 6\n"
+	>> wlog.1  "line 7\nline 8\n"
+	>> wlog    "an hour later\n"
+
+Treći, peti i sedmi red ne staju u datoteku od 20 bajtova, pa pre njih dolazi
+do rotacije. Četvrta rotacija se dogodila posle sat vremena, jer je datoteka
+sa redovima 7 i 8 postala starija od MaxAge. Od četiri rotirane datoteke
+ostale su samo dve najnovije.
 */
 
 func WriteFiles() {
@@ -349,6 +664,12 @@ func WriteFiles() {
 	writeString()
 	writeBytes()
 	writeSliceOfStrings()
+	writeStringSafe()
+	writeBytesSafe()
+	writeSliceOfStringsSafe()
 	writeAppend()
 	writeConcurently()
+	writeConcurentlyBounded()
+	writeConcurentlyAppender()
+	writeAppenderRotation()
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	conc "learngo/09-conc"
+	de "learngo/11-deferAndError"
+	fl "learngo/14-files"
+)
+
+// commands are the subcommands of learngo. Some lessons start the program
+// again as a child process with one of them, others are small utilities.
+var commands = map[string]func(args []string) error{
+	"error-codes": func(args []string) error {
+		return de.ErrorCodes(args, os.Stdout)
+	},
+	"error-report": func(args []string) error {
+		return de.ErrorReport(args, os.Stdout)
+	},
+	"grep": func(args []string) error {
+		return fl.Grep(args, os.Stdout)
+	},
+	"jobqueue-worker": func(args []string) error {
+		if len(args) != 1 {
+			return errors.New("usage: learngo jobqueue-worker <log path>")
+		}
+		return conc.DurableWorkerPool(args[0])
+	},
+	"lock-counter": func(args []string) error {
+		if len(args) < 2 || len(args) > 3 || len(args) == 3 && args[2] != "nolock" {
+			return errors.New("usage: learngo lock-counter <path> <n> [nolock]")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return err
+		}
+		return fl.LockCounter(args[0], n, len(args) == 3)
+	},
+	"watch": fl.Watch,
+}
+
+func runCommand(name string, args []string) {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q, available commands:\n", name)
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(os.Stderr, "\t%s\n", n)
+		}
+		os.Exit(2)
+	}
+	if err := cmd(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"strings"
+
 	// intro "learngo/01-intro"
 	// vars "learngo/02-variables"
 	// funcs "learngo/03-funcAndPack"
@@ -18,6 +21,11 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "" && !strings.HasPrefix(os.Args[1], "-") {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	// intro.IAndI()
 	// intro.HelloWorld()
 	// vars.Variables()
@@ -36,18 +44,36 @@ func main() {
 	// psm.StructFuncs()
 	// psm.MethodFuncs()
 	// ifaces.InterfaceFuncs()
+	// ifaces.InterfaceJSONFunc()
 	// conc.ConcFunc()
 	// conc.Conc2Func()
 	// conc.SelectFunc()
 	// conc.MutFunc()
+	// conc.BoundedFunc()
+	// conc.DurableFunc()
+	// conc.SchedFunc()
+	// conc.LeakFunc()
 	// oop.OOPFunc()
+	// oop.BillingFunc()
 	// de.DeferFunc()
 	// de.ErrorFunc()
 	// de.CustomError()
 	// de.WrappError()
 	// de.PanicRecoverFunc()
+	// de.RetryFunc()
+	// de.ErrorCodesFunc()
+	// de.SuperviseFunc()
+	// de.ErrorReportFunc()
 	// fcf.FcfFunc()
 	// ref.RefFunc()
+	// fl.FSFunc()
+	// fl.LinesFunc()
+	// fl.LocksFunc()
+	// fl.IndexFunc()
+	// fl.ArchiveFunc()
+	// fl.GrepFunc()
+	// fl.ChecksumFunc()
+	// fl.WatchFunc()
 	fl.ReadFiles()
 	fl.WriteFiles()
 }